@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/xackery/talkeq/discord"
 	"github.com/xackery/talkeq/registerdb"
 	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/telnet"
 	"github.com/xackery/talkeq/tlog"
 )
 
@@ -30,15 +32,20 @@ type API struct {
 	subscribers    []func(interface{}) error
 	isInitialState bool
 	discord        *discord.Discord
+	telnet         *telnet.Telnet
+	server         *http.Server
+	rootConfig     *config.Config
 }
 
 const (
 	//ActionReply is used when replying to a discord message
 	ActionReply = "reply"
+	// shutdownTimeout is how long Disconnect waits for in flight requests to finish
+	shutdownTimeout = 10 * time.Second
 )
 
 // New creates a new api endpoint
-func New(ctx context.Context, config config.API, discord *discord.Discord) (*API, error) {
+func New(ctx context.Context, config config.API, discord *discord.Discord, telnet *telnet.Telnet) (*API, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	t := &API{
 		ctx:            ctx,
@@ -46,6 +53,7 @@ func New(ctx context.Context, config config.API, discord *discord.Discord) (*API
 		cancel:         cancel,
 		isInitialState: true,
 		discord:        discord,
+		telnet:         telnet,
 	}
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
@@ -66,6 +74,13 @@ func New(ctx context.Context, config config.API, discord *discord.Discord) (*API
 	return t, nil
 }
 
+// SetRootConfig gives the api access to the full config, used to report route stats
+func (t *API) SetRootConfig(cfg *config.Config) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.rootConfig = cfg
+}
+
 // Subscribe starts a subscription listening on specified data
 func (t *API) Subscribe(ctx context.Context, onMessage func(interface{}) error) error {
 	t.mutex.Lock()
@@ -153,21 +168,25 @@ func (t *API) Command(req request.APICommand) error {
 		reply := request.DiscordSend{
 			Ctx:       ctx,
 			ChannelID: req.FromDiscordChannelID,
-			Message:   fmt.Sprintf("I sent a /tell to %s, you have 2 minutes to go in game and [ accept ] it. Status: In Queue", character),
+			Message:   registerMessage(character, "In Queue"),
 		}
-		for _, s := range t.subscribers {
-			err = s(reply)
-			if err != nil {
-				tlog.Warnf("[api->discord] reply to !register: %s", err)
-				continue
-			}
-			tlog.Infof("[api->discord] !register message: %s", reply.Message)
+		// the sent message is edited once the character is confirmed, so it is sent directly to learn its ID
+		result, err := t.discord.SendWithResult(reply)
+		if err != nil {
+			return fmt.Errorf("reply to !register: %w", err)
 		}
-		channelID, messageID, err := t.discord.LastSentMessage()
+		if result.IsQueued {
+			tlog.Warnf("[api->discord] !register message queued, it will not be updated when %s is confirmed", character)
+		}
+		tlog.Infof("[api->discord] !register message: %s", reply.Message)
+		code, err := registerdb.Set(req.FromDiscordNameID, req.FromDiscordName, character, result.ChannelID, result.MessageID, "In Queue", time.Now().Add(registerTimeout).Unix())
+		if err != nil {
+			return fmt.Errorf("registerdb set: %w", err)
+		}
+		err = t.discord.DirectMessage(req.FromDiscordNameID, fmt.Sprintf("Your talkeq code is %s. Log in as %s and say it in ooc within 2 minutes to confirm you own the character.", code, character))
 		if err != nil {
-			return fmt.Errorf("lastSentMessage: %w", err)
+			tlog.Warnf("[api->discord] register code DM to %s failed: %s", req.FromDiscordName, err)
 		}
-		registerdb.Set(req.FromDiscordNameID, req.FromDiscordName, character, channelID, messageID, "In Queue", time.Now().Add(30*time.Second).Unix())
 	}
 	return nil
 }
@@ -190,18 +209,31 @@ func (t *API) Connect(ctx context.Context) error {
 		t.conn = nil
 		t.cancel()
 	}
+	if t.server != nil {
+		t.server.Close()
+	}
 
 	t.ctx, t.cancel = context.WithCancel(ctx)
 	r := mux.NewRouter()
+	r.Use(logMiddleware)
+	r.Use(t.allowMiddleware)
 
 	r.HandleFunc("/api", t.index).Methods("GET")
 	r.HandleFunc("/api/relays", t.relays).Methods("GET")
 	r.HandleFunc("/api/register/confirm", t.registerConfirm).Methods("GET")
+	r.HandleFunc("/api/who", t.who).Methods("POST")
+	r.HandleFunc("/api/routes", t.routes).Methods("GET")
+	if t.config.IsDebug {
+		tlog.Warnf("[api] debug endpoints are enabled")
+		r.HandleFunc("/api/debug/telnet-line", t.debugTelnetLine).Methods("POST")
+	}
 
 	// Start server
+	server := &http.Server{Addr: t.config.Host, Handler: r}
+	t.server = server
 	go func() {
-		err = http.ListenAndServe(t.config.Host, r)
-		if err != nil {
+		err = server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			tlog.Errorf("[api] listenandserve failed: %s", err)
 		}
 		t.mutex.Lock()
@@ -224,7 +256,7 @@ func (t *API) IsConnected() bool {
 	return isConnected
 }
 
-// Disconnect stops a previously started connection with Discord.
+// Disconnect stops accepting new API requests, and waits for in flight requests to finish.
 // If called while a connection is not active, returns nil
 func (t *API) Disconnect(ctx context.Context) error {
 	if !t.config.IsEnabled {
@@ -235,19 +267,38 @@ func (t *API) Disconnect(ctx context.Context) error {
 		tlog.Debugf("[api] is already disconnected, skipping disconnect")
 		return nil
 	}
-	err := t.conn.Close()
-	if err != nil {
-		tlog.Warnf("[api] disconect failed: %s", err)
+	if t.server != nil {
+		// ctx may already be cancelled during shutdown, so in flight requests get their own deadline
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		err := t.server.Shutdown(shutdownCtx)
+		cancel()
+		if err != nil {
+			tlog.Warnf("[api] shutdown did not finish in flight requests: %s", err)
+		}
+		t.server = nil
+	}
+	if t.conn != nil {
+		err := t.conn.Close()
+		if err != nil {
+			tlog.Warnf("[api] disconect failed: %s", err)
+		}
+		t.conn = nil
 	}
-	t.conn = nil
 	t.isConnected = false
 	return nil
 }
 
 func (t *API) index(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	type Resp struct{}
-	err := json.NewEncoder(w).Encode(&Resp{})
+	type Resp struct {
+		DiscordSendQueued      int      `json:"discord_send_queued"`
+		DiscordSendDropped     int64    `json:"discord_send_dropped"`
+		DiscordChannelProblems []string `json:"discord_channel_problems"`
+	}
+	resp := &Resp{}
+	resp.DiscordSendQueued, resp.DiscordSendDropped = t.discord.SendStats()
+	resp.DiscordChannelProblems = t.discord.ChannelProblems()
+	err := json.NewEncoder(w).Encode(resp)
 	if err != nil {
 		tlog.Warnf("[api] encode response failed: %s", err)
 	}
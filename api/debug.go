@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/xackery/talkeq/tlog"
+)
+
+// debugTelnetLine runs a posted line through the telnet processing path, and returns which routes matched
+func (t *API) debugTelnetLine(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	type Req struct {
+		Line string `json:"line"`
+	}
+	type Resp struct {
+		Message  string `json:"message,omitempty"`
+		IsRouted bool   `json:"routed"`
+		Routes   []int  `json:"routes"`
+	}
+	resp := Resp{Routes: []int{}}
+
+	ip := remoteIP(r)
+	if ip == nil || !ip.IsLoopback() {
+		w.WriteHeader(http.StatusForbidden)
+		resp.Message = "debug endpoints are only available from localhost"
+		t.writeJSON(w, resp)
+		return
+	}
+	if !t.isAuthorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		resp.Message = "unauthorized"
+		t.writeJSON(w, resp)
+		return
+	}
+	if t.telnet == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		resp.Message = "telnet is not available"
+		t.writeJSON(w, resp)
+		return
+	}
+
+	req := Req{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || strings.TrimSpace(req.Line) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Message = "body must be json with a line, e.g. {\"line\": \"Shin says ooc, 'hello'\"}"
+		t.writeJSON(w, resp)
+		return
+	}
+
+	resp.Routes, resp.IsRouted = t.telnet.InjectLine(req.Line)
+	if !resp.IsRouted {
+		resp.Message = "line was handled before routes, e.g. as a who entry, tell or death"
+	}
+	tlog.Debugf("[api] debug telnet line matched routes %v", resp.Routes)
+	t.writeJSON(w, resp)
+}
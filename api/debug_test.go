@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPI_debugTelnetLine(t *testing.T) {
+	a := &API{}
+	r := httptest.NewRequest("POST", "/api/debug/telnet-line", strings.NewReader(`{"line": "Shin says ooc, 'hello'"}`))
+	r.RemoteAddr = "10.0.0.5:1234"
+	w := httptest.NewRecorder()
+	a.debugTelnetLine(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("remote request wanted %d, got %d", http.StatusForbidden, w.Code)
+	}
+
+	r = httptest.NewRequest("POST", "/api/debug/telnet-line", strings.NewReader(`{}`))
+	r.RemoteAddr = "127.0.0.1:1234"
+	w = httptest.NewRecorder()
+	a.debugTelnetLine(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("request without telnet wanted %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
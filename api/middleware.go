@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/xackery/talkeq/tlog"
+)
+
+// statusRecorder remembers the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records status before writing it
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// logMiddleware logs the method, path, status, duration and source IP of each request at debug level
+func logMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		tlog.Debugf("[api] %s %s %d %s from %s", r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Microsecond), remoteIP(r))
+	})
+}
+
+// allowMiddleware rejects requests from IPs not in allowed_ips with 403
+func (t *API) allowMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := remoteIP(r)
+		if !t.config.IsAllowedIP(ip) {
+			tlog.Debugf("[api] rejected %s %s from %s, not in allowed_ips", r.Method, r.URL.Path, r.RemoteAddr)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// remoteIP returns the IP of the connection a request came from
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
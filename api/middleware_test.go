@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+)
+
+func TestAPI_allowMiddleware(t *testing.T) {
+	cfg := config.API{IsEnabled: true, Host: ":9933", AllowedIPs: []string{"127.0.0.1", "10.0.0.0/8"}}
+	err := cfg.Verify()
+	if err != nil {
+		t.Fatalf("verify: %s", err)
+	}
+	a := &API{config: cfg}
+	handler := a.allowMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		remoteAddr string
+		want       int
+	}{
+		{"127.0.0.1:5000", http.StatusOK},
+		{"10.1.2.3:5000", http.StatusOK},
+		{"192.168.1.5:5000", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api", nil)
+		r.RemoteAddr = tt.remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s got status %d, want %d", tt.remoteAddr, w.Code, tt.want)
+		}
+	}
+
+	cfg.AllowedIPs = []string{"not an ip"}
+	if cfg.Verify() == nil {
+		t.Fatalf("verify wanted error for invalid allowed_ips")
+	}
+}
+
+func Test_logMiddleware(t *testing.T) {
+	handler := logMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	r := httptest.NewRequest("GET", "/api", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTeapot)
+	}
+}
@@ -8,10 +8,56 @@ import (
 	"time"
 
 	"github.com/xackery/talkeq/registerdb"
+	"github.com/xackery/talkeq/request"
 	"github.com/xackery/talkeq/tlog"
 	"github.com/xackery/talkeq/userdb"
 )
 
+// registerTimeout is how long a player has to confirm a !register request
+const registerTimeout = 2 * time.Minute
+
+// registerMessage returns the reply to a !register request, with its current status
+func registerMessage(character string, status string) string {
+	return fmt.Sprintf("I sent a /tell to %s and a code to your DMs, you have 2 minutes to go in game and [ accept ] it, or say the code in ooc. Status: %s", character, status)
+}
+
+// ConfirmCode confirms a pending registration when its character says the registration code in game
+func (t *API) ConfirmCode(req request.RegisterCode) error {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	if !t.config.IsEnabled || !t.config.APIRegister.IsEnabled {
+		return nil
+	}
+	entry, err := registerdb.FindByCode(req.Code)
+	if err != nil {
+		// most messages that look like a code are just chat
+		return nil
+	}
+	if entry.Status == "Confirmed" || entry.Status == "Denied" {
+		return nil
+	}
+	if !strings.EqualFold(entry.CharacterName, req.CharacterName) {
+		tlog.Warnf("[api] register code for %s was said by %s, ignoring", entry.CharacterName, req.CharacterName)
+		return nil
+	}
+
+	userdb.Set(entry.DiscordID, entry.CharacterName)
+	err = registerdb.Update(entry.DiscordID, "Confirmed", time.Now().Add(24*time.Hour).Unix())
+	if err != nil {
+		return fmt.Errorf("registerdb update: %w", err)
+	}
+	tlog.Infof("[api] %s confirmed registration of %s in game", entry.DiscordName, entry.CharacterName)
+	if entry.MessageID == "" {
+		// the !register reply was queued, so there is no message to edit
+		return nil
+	}
+	err = t.discord.EditMessage(entry.ChannelID, entry.MessageID, registerMessage(entry.CharacterName, "Confirmed"))
+	if err != nil {
+		return fmt.Errorf("edit message: %w", err)
+	}
+	return nil
+}
+
 func (t *API) registerConfirm(w http.ResponseWriter, r *http.Request) {
 	type Resp struct {
 		Message string `json:"message"`
@@ -98,14 +144,16 @@ func (t *API) registerConfirm(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		tlog.Warnf("[api] registerdb update failed: %s", err)
 	}
-	err = t.discord.EditMessage(entry.ChannelID, entry.MessageID, fmt.Sprintf("I sent a /tell to %s, you have 2 minutes to go in game and [ accept ] it. Status: Confirmed", entry.CharacterName))
-	if err != nil {
-		resp.Message = err.Error()
-		err := json.NewEncoder(w).Encode(resp)
+	if entry.MessageID != "" {
+		err = t.discord.EditMessage(entry.ChannelID, entry.MessageID, registerMessage(entry.CharacterName, "Confirmed"))
 		if err != nil {
-			tlog.Warnf("[api] encode response failed: %s", err)
+			resp.Message = err.Error()
+			err := json.NewEncoder(w).Encode(resp)
+			if err != nil {
+				tlog.Warnf("[api] encode response failed: %s", err)
+			}
+			return
 		}
-		return
 	}
 	resp.Message = "confirmed successfully"
 	err = json.NewEncoder(w).Encode(&Resp{})
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/xackery/talkeq/config"
+)
+
+// routeStatus is a route's settings and match stats
+type routeStatus struct {
+	Source       string     `json:"source"`
+	Index        int        `json:"index"`
+	IsEnabled    bool       `json:"enabled"`
+	Trigger      string     `json:"trigger"`
+	Target       string     `json:"target"`
+	Destinations []string   `json:"destinations"`
+	Matches      int64      `json:"matches"`
+	LastMatch    *time.Time `json:"last_match,omitempty"`
+}
+
+// routes lists every route with how often and when its trigger last matched
+func (t *API) routes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	type Resp struct {
+		Message string        `json:"message,omitempty"`
+		Routes  []routeStatus `json:"routes"`
+	}
+	resp := Resp{Routes: []routeStatus{}}
+	if !t.isAuthorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		resp.Message = "unauthorized"
+		t.writeJSON(w, resp)
+		return
+	}
+	t.mutex.RLock()
+	cfg := t.rootConfig
+	t.mutex.RUnlock()
+	if cfg == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		resp.Message = "routes are not available yet"
+		t.writeJSON(w, resp)
+		return
+	}
+	resp.Routes = routeStatuses(cfg)
+	t.writeJSON(w, resp)
+}
+
+// routeStatuses returns the status of the discord routes, then the routes of every other source
+func routeStatuses(cfg *config.Config) []routeStatus {
+	statuses := []routeStatus{}
+	for i := range cfg.Discord.Routes {
+		route := &cfg.Discord.Routes[i]
+		status := routeStatus{
+			Source:       "discord",
+			Index:        i,
+			IsEnabled:    route.IsEnabled,
+			Trigger:      route.Trigger.ChannelID,
+			Target:       route.Target,
+			Destinations: []string{route.ChannelID},
+			Matches:      route.Stats().Matches(),
+		}
+		if last := route.Stats().LastMatch(); !last.IsZero() {
+			status.LastMatch = &last
+		}
+		statuses = append(statuses, status)
+	}
+	for _, source := range cfg.SourceRoutes() {
+		for i := range source.Routes {
+			route := &source.Routes[i]
+			trigger := route.Trigger.Regex
+			if route.Trigger.Custom != "" {
+				trigger = route.Trigger.Custom
+			}
+			status := routeStatus{
+				Source:       source.Source,
+				Index:        i,
+				IsEnabled:    route.IsEnabled,
+				Trigger:      trigger,
+				Target:       route.Target,
+				Destinations: route.Destinations(),
+				Matches:      route.Stats().Matches(),
+			}
+			if last := route.Stats().LastMatch(); !last.IsZero() {
+				status.LastMatch = &last
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
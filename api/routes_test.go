@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+)
+
+func TestAPI_routes(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Telnet.Routes = []config.Route{
+		{IsEnabled: true, Trigger: config.Trigger{Regex: `(\w+) says ooc, '(.*)'`}, Target: "discord", ChannelID: "123"},
+		{IsEnabled: true, Trigger: config.Trigger{Regex: `(\w+) auctions, '(.*)'`}, Target: "discord", ChannelID: "456"},
+	}
+	for i := range cfg.Telnet.Routes {
+		err := cfg.Telnet.Routes[i].LoadMessagePattern()
+		if err != nil {
+			t.Fatalf("load route %d: %s", i, err)
+		}
+	}
+	route := cfg.Telnet.Routes[0]
+	route.Stats().Matched()
+
+	a := &API{rootConfig: cfg}
+	w := httptest.NewRecorder()
+	a.routes(w, httptest.NewRequest("GET", "/api/routes", nil))
+	resp := struct {
+		Routes []routeStatus `json:"routes"`
+	}{}
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	if len(resp.Routes) != 2 {
+		t.Fatalf("routes wanted 2, got %d", len(resp.Routes))
+	}
+	if resp.Routes[0].Matches != 1 || resp.Routes[0].LastMatch == nil {
+		t.Fatalf("route 0 = %+v", resp.Routes[0])
+	}
+	if resp.Routes[1].Matches != 0 || resp.Routes[1].LastMatch != nil {
+		t.Fatalf("route 1 wanted no matches, got %+v", resp.Routes[1])
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/xackery/talkeq/characterdb"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// whoTimeout is how long /api/who waits for telnet to return a who list
+const whoTimeout = 5 * time.Second
+
+// isAuthorized returns true if no token is configured, or the request has a matching bearer token
+func (t *API) isAuthorized(r *http.Request) bool {
+	if t.config.Token == "" {
+		return true
+	}
+	want := "Bearer " + t.config.Token
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(want)) == 1
+}
+
+func (t *API) who(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	type Player struct {
+		Name  string `json:"name"`
+		Level int    `json:"level"`
+		Class string `json:"class"`
+		Race  string `json:"race"`
+		Zone  string `json:"zone"`
+	}
+	type Resp struct {
+		Message string   `json:"message,omitempty"`
+		Online  int      `json:"online"`
+		Players []Player `json:"players"`
+	}
+	resp := Resp{Players: []Player{}}
+
+	if !t.isAuthorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		resp.Message = "unauthorized"
+		t.writeJSON(w, resp)
+		return
+	}
+	if t.telnet == nil || !t.telnet.IsConnected() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		resp.Message = "telnet is not connected"
+		t.writeJSON(w, resp)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), whoTimeout)
+	defer cancel()
+	online, err := t.telnet.Who(ctx)
+	if err != nil {
+		tlog.Warnf("[api] who failed: %s", err)
+		w.WriteHeader(http.StatusGatewayTimeout)
+		resp.Message = "telnet did not return a who list in time"
+		t.writeJSON(w, resp)
+		return
+	}
+
+	resp.Online = online
+	for _, character := range characterdb.Roster() {
+		resp.Players = append(resp.Players, Player{
+			Name:  character.Name,
+			Level: character.Level,
+			Class: character.Class,
+			Race:  character.Race,
+			Zone:  character.Zone,
+		})
+	}
+	tlog.Debugf("[api] who returned %d online", online)
+	t.writeJSON(w, resp)
+}
+
+func (t *API) writeJSON(w http.ResponseWriter, resp interface{}) {
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		tlog.Warnf("[api] encode response failed: %s", err)
+	}
+}
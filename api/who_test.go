@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+)
+
+func TestAPI_isAuthorized(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   bool
+	}{
+		{"no token", "", "", true},
+		{"match", "secret", "Bearer secret", true},
+		{"missing", "secret", "", false},
+		{"wrong", "secret", "Bearer nope", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &API{config: config.API{Token: tt.token}}
+			r := httptest.NewRequest("POST", "/api/who", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if got := a.isAuthorized(r); got != tt.want {
+				t.Fatalf("isAuthorized() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package auction
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/tlog"
+)
+
+var (
+	isStarted   bool
+	aliases     = make(map[string]string)
+	mu          sync.RWMutex
+	aliasesPath string
+)
+
+// New loads the auction item alias database, and reloads it when the file changes
+func New(config *config.Config) error {
+	if isStarted {
+		return fmt.Errorf("already started")
+	}
+	aliasesPath = config.AuctionAliasesDatabasePath
+
+	tlog.Debugf("[auction] initializing")
+	err := setPatterns(config.AuctionParsing)
+	if err != nil {
+		return fmt.Errorf("setPatterns: %w", err)
+	}
+
+	_, err = os.Stat(aliasesPath)
+	if os.IsNotExist(err) {
+		err = os.WriteFile(aliasesPath, []byte("# alias:full item name #comment\nfbss:Flowing Black Silk Sash\ncof:Cloak of Flames\n"), 0644)
+		if err != nil {
+			return fmt.Errorf("auction aliases database create %w", err)
+		}
+	}
+
+	err = reload()
+	if err != nil {
+		return fmt.Errorf("reload: %w", err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("newWatcher: %w", err)
+	}
+
+	err = watcher.Add(aliasesPath)
+	if err != nil {
+		return fmt.Errorf("watcherAdd: %w", err)
+	}
+
+	err = loadHistory(config.AuctionHistory)
+	if err != nil {
+		return fmt.Errorf("loadHistory: %w", err)
+	}
+	setDigest(config.AuctionDigest)
+
+	go loop(watcher)
+	return nil
+}
+
+func loop(watcher *fsnotify.Watcher) {
+	if isStarted {
+		return
+	}
+	mu.Lock()
+	isStarted = true
+	mu.Unlock()
+
+	defer watcher.Close()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				tlog.Warn("[auction] failed to read file")
+				return
+			}
+			if event.Op&fsnotify.Write != fsnotify.Write {
+				continue
+			}
+			tlog.Debugf("[auction] aliases modified, reloading")
+			err := reload()
+			if err != nil {
+				tlog.Warnf("[auction] failed to reload aliases: %s", err)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			tlog.Warnf("[auction] failed to read file: %s", err)
+		}
+	}
+}
+
+func reload() error {
+	data, err := os.ReadFile(aliasesPath)
+	if err != nil {
+		return fmt.Errorf("readFile: %w", err)
+	}
+	na := parseAliases(string(data))
+
+	mu.Lock()
+	aliases = na
+	mu.Unlock()
+	tlog.Debugf("[auction] loaded %d item aliases", len(na))
+	return nil
+}
+
+// parseAliases reads alias:full item name lines, keyed by lowercase alias
+func parseAliases(data string) map[string]string {
+	na := make(map[string]string)
+	for lineNumber, line := range strings.Split(data, "\n") {
+		lineNumber++
+
+		line = strings.TrimSpace(line)
+		if len(line) < 1 || line[0] == '#' {
+			continue
+		}
+		alias, name, ok := strings.Cut(line, ":")
+		if !ok {
+			tlog.Debugf("[auction] aliases line %d skipped, no : found", lineNumber)
+			continue
+		}
+		p := strings.Index(name, "#")
+		if p >= 0 {
+			name = name[0:p]
+		}
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		name = strings.TrimSpace(name)
+		if alias == "" || name == "" {
+			tlog.Warnf("[auction] aliases line %d failed, alias or item name is empty", lineNumber)
+			continue
+		}
+		_, ok = na[alias]
+		if ok {
+			tlog.Debugf("[auction] aliases line %d, %s is a duplicate entry", lineNumber, alias)
+		}
+		na[alias] = name
+	}
+	return na
+}
+
+// CanonicalName returns the full item name of an alias, or name if it is not an alias
+func CanonicalName(name string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	full, ok := aliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return name
+	}
+	return full
+}
@@ -0,0 +1,247 @@
+// Package auction parses buy and sell messages from EQ chat into structured listings
+package auction
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+)
+
+const (
+	// KindSell is an item offered for sale
+	KindSell = "WTS"
+	// KindBuy is an item wanted
+	KindBuy = "WTB"
+	// KindTrade is an item offered for trade
+	KindTrade = "WTT"
+	// KindPriceCheck is an item someone wants to know the price of
+	KindPriceCheck = "PC"
+	// KindSearch is an item someone is in search of
+	KindSearch = "ISO"
+)
+
+// listingStyle is the title and color of a listing's embed
+type listingStyle struct {
+	title string
+	color int
+}
+
+// kindStyles are the embed styles of listings where every item is the same kind, other listings use auctionStyle
+var kindStyles = map[string]listingStyle{
+	KindPriceCheck: {title: "%s's price check", color: 0x3498DB},
+	KindSearch:     {title: "%s is in search of", color: 0x2ECC71},
+}
+
+var auctionStyle = listingStyle{title: "%s's auction", color: 0xF1C40F}
+
+var (
+	kindPattern      = regexp.MustCompile(`(?i)\b(WTS|WTB|WTT|selling|buying|PC\s+on|price\s*check(?:\s+on)?|ISO|in\s+search\s+of)\b`)
+	pricePattern     = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(k|pp|p|plat)\b`)
+	separatorPattern = regexp.MustCompile(`\s*(?:,|/|\||;|\s-\s)\s*`)
+)
+
+// setPatterns replaces the separator and price patterns with the configured ones
+func setPatterns(cfg config.AuctionParsing) error {
+	separator, err := regexp.Compile(cfg.SeparatorPattern)
+	if err != nil {
+		return fmt.Errorf("separator_pattern: %w", err)
+	}
+	price, err := regexp.Compile(cfg.PricePattern)
+	if err != nil {
+		return fmt.Errorf("price_pattern: %w", err)
+	}
+	if price.NumSubexp() < 2 {
+		return fmt.Errorf("price_pattern needs an amount and a unit group")
+	}
+	separatorPattern = separator
+	pricePattern = price
+	return nil
+}
+
+// Item is an item found in an auction message
+type Item struct {
+	Name string
+	Kind string
+	// PricePlat is the asking price in platinum, 0 if no price was given
+	PricePlat int
+}
+
+// Listing is a parsed auction message
+type Listing struct {
+	Name    string
+	Message string
+	Items   []Item
+}
+
+// IsAuctionMessage returns true if message is buying, selling, trading, a price check or in search of an item
+func IsAuctionMessage(message string) bool {
+	return kindPattern.MatchString(message)
+}
+
+// Parse returns the listing of an auction message sent by name
+func Parse(name string, message string) *Listing {
+	return &Listing{
+		Name:    name,
+		Message: message,
+		Items:   extractItems(message),
+	}
+}
+
+// extractItems splits an auction message into items, with the kind and price that applies to each
+func extractItems(message string) []Item {
+	items := []Item{}
+	kind := KindSell
+	for _, segment := range separatorPattern.Split(message, -1) {
+		for _, match := range kindPattern.FindAllString(segment, -1) {
+			kind = normalizeKind(match)
+		}
+		segment = kindPattern.ReplaceAllString(segment, "")
+
+		price := 0
+		priceMatch := pricePattern.FindStringSubmatch(segment)
+		if len(priceMatch) > 0 {
+			price = parsePrice(priceMatch[1], priceMatch[2])
+			segment = pricePattern.ReplaceAllString(segment, "")
+		}
+
+		name := strings.Trim(strings.TrimSpace(segment), ":.!-")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		items = append(items, Item{Name: CanonicalName(name), Kind: kind, PricePlat: price})
+	}
+	return items
+}
+
+// normalizeKind converts a kind keyword to one of the Kind constants
+func normalizeKind(value string) string {
+	value = strings.Join(strings.Fields(strings.ToLower(value)), " ")
+	switch value {
+	case "wtb", "buying":
+		return KindBuy
+	case "wtt":
+		return KindTrade
+	case "iso", "in search of":
+		return KindSearch
+	}
+	if strings.HasPrefix(value, "pc") || strings.HasPrefix(value, "price") {
+		return KindPriceCheck
+	}
+	return KindSell
+}
+
+// parsePrice converts a price like 1.5 k to platinum
+func parsePrice(amount string, unit string) int {
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0
+	}
+	if strings.EqualFold(unit, "k") {
+		value *= 1000
+	}
+	return int(value)
+}
+
+const (
+	// embedFieldMax is the most fields discord allows on an embed
+	embedFieldMax = 25
+	// messageEmbedMax is the most embeds discord allows on a message
+	messageEmbedMax = 10
+	// fieldCharMax is the characters of item fields allowed across a message's embeds,
+	// leaving room for the description, titles and footers under discord's 6000 limit
+	fieldCharMax = 4000
+)
+
+// ToEmbeds returns embeds listing each item with its price, split over as many embeds as needed, and a summary field when there is more than one item.
+// The embeds fit in one discord message, items past discord's limits are counted in an "and N more" field
+func (l *Listing) ToEmbeds() []*request.DiscordEmbed {
+	style := l.style()
+	embeds := []*request.DiscordEmbed{}
+	add := func(field request.DiscordEmbedField) {
+		if len(embeds) == 0 || len(embeds[len(embeds)-1].Fields) >= embedFieldMax {
+			title := fmt.Sprintf(style.title, l.Name)
+			if len(embeds) > 0 {
+				title += " (continued)"
+			}
+			embeds = append(embeds, &request.DiscordEmbed{Title: title, Color: style.color})
+		}
+		embeds[len(embeds)-1].Fields = append(embeds[len(embeds)-1].Fields, field)
+	}
+
+	chars := 0
+	for i, item := range l.Items {
+		price := "offer"
+		if item.PricePlat > 0 {
+			price = fmt.Sprintf("%dpp", item.PricePlat)
+		}
+		field := request.DiscordEmbedField{
+			Name:   fmt.Sprintf("%s %s", item.Kind, item.Name),
+			Value:  price,
+			Inline: true,
+		}
+		// keep room for the more and summary fields in the last embed
+		isLastEmbedFull := len(embeds) == messageEmbedMax && len(embeds[len(embeds)-1].Fields) >= embedFieldMax-2
+		if isLastEmbedFull || chars+len(field.Name)+len(field.Value) > fieldCharMax {
+			add(request.DiscordEmbedField{
+				Name:   "...",
+				Value:  fmt.Sprintf("and %d more", len(l.Items)-i),
+				Inline: true,
+			})
+			break
+		}
+		chars += len(field.Name) + len(field.Value)
+		add(field)
+	}
+	if len(l.Items) > 1 {
+		add(request.DiscordEmbedField{
+			Name:  "Summary",
+			Value: l.summary(),
+		})
+	}
+	if len(embeds) == 0 {
+		embeds = append(embeds, &request.DiscordEmbed{Title: fmt.Sprintf(style.title, l.Name), Color: style.color})
+	}
+	return embeds
+}
+
+// style returns the embed style of the listing's kind, or the auction style when items are of mixed kinds
+func (l *Listing) style() listingStyle {
+	if len(l.Items) == 0 {
+		return auctionStyle
+	}
+	kind := l.Items[0].Kind
+	for _, item := range l.Items {
+		if item.Kind != kind {
+			return auctionStyle
+		}
+	}
+	style, ok := kindStyles[kind]
+	if !ok {
+		return auctionStyle
+	}
+	return style
+}
+
+// summary returns the item count, and the total asking price of WTS items if every one has a price
+func (l *Listing) summary() string {
+	summary := fmt.Sprintf("%d items", len(l.Items))
+	total := 0
+	for _, item := range l.Items {
+		if item.Kind != KindSell {
+			continue
+		}
+		if item.PricePlat < 1 {
+			return summary
+		}
+		total += item.PricePlat
+	}
+	if total > 0 {
+		summary += fmt.Sprintf(", %dpp total for WTS", total)
+	}
+	return summary
+}
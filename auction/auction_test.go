@@ -0,0 +1,176 @@
+package auction
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+)
+
+func TestIsAuctionMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"WTS Flowing Black Silk Sash 500p", true},
+		{"buying a cloak of flames", true},
+		{"anyone seen Shin?", false},
+		{"lfg 52 war", false},
+	}
+	for _, tt := range tests {
+		if got := IsAuctionMessage(tt.message); got != tt.want {
+			t.Errorf("IsAuctionMessage(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func Test_extractItems(t *testing.T) {
+	tests := []struct {
+		message string
+		want    []Item
+	}{
+		{"WTS Flowing Black Silk Sash 500p, Cloak of Flames 1.5k", []Item{
+			{Name: "Flowing Black Silk Sash", Kind: KindSell, PricePlat: 500},
+			{Name: "Cloak of Flames", Kind: KindSell, PricePlat: 1500},
+		}},
+		{"WTB Fungi Tunic / WTS Bone Chips 10pp", []Item{
+			{Name: "Fungi Tunic", Kind: KindBuy},
+			{Name: "Bone Chips", Kind: KindSell, PricePlat: 10},
+		}},
+	}
+	for _, tt := range tests {
+		if got := extractItems(tt.message); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractItems(%q) = %+v, want %+v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func Test_parseAliases(t *testing.T) {
+	got := parseAliases("# alias:full item name\nFBSS:Flowing Black Silk Sash #popular\ncof: Cloak of Flames\nbroken line\n:empty\n")
+	want := map[string]string{
+		"fbss": "Flowing Black Silk Sash",
+		"cof":  "Cloak of Flames",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseAliases() = %v, want %v", got, want)
+	}
+}
+
+func TestCanonicalName(t *testing.T) {
+	mu.Lock()
+	aliases = map[string]string{"fbss": "Flowing Black Silk Sash"}
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		aliases = make(map[string]string)
+		mu.Unlock()
+	}()
+
+	items := extractItems("WTS FBSS 500p, cof 2k")
+	if items[0].Name != "Flowing Black Silk Sash" {
+		t.Fatalf("alias name = %q, want Flowing Black Silk Sash", items[0].Name)
+	}
+	if items[1].Name != "cof" {
+		t.Fatalf("unknown alias name = %q, want cof", items[1].Name)
+	}
+}
+
+func TestListing_ToEmbeds(t *testing.T) {
+	embeds := Parse("Shin", "WTS Flowing Black Silk Sash 500p, Cloak of Flames 1.5k").ToEmbeds()
+	if len(embeds) != 1 || len(embeds[0].Fields) != 3 {
+		t.Fatalf("embeds wanted 1 with 3 fields, got %d", len(embeds))
+	}
+	if got := embeds[0].Fields[2].Value; got != "2 items, 2000pp total for WTS" {
+		t.Fatalf("summary = %q", got)
+	}
+
+	embeds = Parse("Shin", "WTS Flowing Black Silk Sash 500p, Cloak of Flames").ToEmbeds()
+	if got := embeds[0].Fields[2].Value; got != "2 items" {
+		t.Fatalf("summary with unpriced item = %q", got)
+	}
+
+	message := "WTS"
+	for i := 0; i < 30; i++ {
+		message += fmt.Sprintf(" Item %d,", i)
+	}
+	embeds = Parse("Shin", message).ToEmbeds()
+	if len(embeds) != 2 || len(embeds[0].Fields) != embedFieldMax || len(embeds[1].Fields) != 6 {
+		t.Fatalf("30 items wanted 2 embeds of 25 and 6 fields, got %d", len(embeds))
+	}
+	if got := embeds[1].Fields[5].Value; got != "30 items" {
+		t.Fatalf("summary of long listing = %q", got)
+	}
+
+	message = "WTS"
+	for i := 0; i < 400; i++ {
+		message += fmt.Sprintf(" Item %d,", i)
+	}
+	embeds = Parse("Shin", message).ToEmbeds()
+	chars := 0
+	for _, embed := range embeds {
+		if len(embed.Fields) > embedFieldMax {
+			t.Fatalf("embed has %d fields", len(embed.Fields))
+		}
+		for _, field := range embed.Fields {
+			chars += len(field.Name) + len(field.Value)
+		}
+	}
+	if len(embeds) > messageEmbedMax || chars > 6000 {
+		t.Fatalf("400 items wanted to fit a message, got %d embeds and %d characters", len(embeds), chars)
+	}
+}
+
+func Test_setPatterns(t *testing.T) {
+	defer func() {
+		err := setPatterns(config.AuctionParsing{SeparatorPattern: `\s*(?:,|/|\||;|\s-\s)\s*`, PricePattern: `(?i)(\d+(?:\.\d+)?)\s*(k|pp|p|plat)\b`})
+		if err != nil {
+			t.Fatalf("restore patterns: %s", err)
+		}
+	}()
+	err := setPatterns(config.AuctionParsing{SeparatorPattern: `\s*\+\s*`, PricePattern: `(?i)(\d+)\s*(k|pp|p|plat|pl)\b`})
+	if err != nil {
+		t.Fatalf("setPatterns: %s", err)
+	}
+	items := extractItems("WTS Cloak of Flames 2k + Bone Chips 5pl")
+	want := []Item{
+		{Name: "Cloak of Flames", Kind: KindSell, PricePlat: 2000},
+		{Name: "Bone Chips", Kind: KindSell, PricePlat: 5},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("extractItems = %+v", items)
+	}
+	err = setPatterns(config.AuctionParsing{SeparatorPattern: `,`, PricePattern: `(\d+)k`})
+	if err == nil {
+		t.Fatalf("price_pattern with one group wanted error")
+	}
+}
+
+func TestParse_intents(t *testing.T) {
+	tests := []struct {
+		message   string
+		wantKind  string
+		wantTitle string
+	}{
+		{"PC on Cloak of Flames", KindPriceCheck, "Shin's price check"},
+		{"price check Flowing Black Silk Sash", KindPriceCheck, "Shin's price check"},
+		{"ISO Fungi Tunic", KindSearch, "Shin is in search of"},
+		{"in search of Cloak of Flames", KindSearch, "Shin is in search of"},
+		{"WTS Cloak of Flames 1k, ISO Fungi Tunic", KindSell, "Shin's auction"},
+	}
+	for _, tt := range tests {
+		if !IsAuctionMessage(tt.message) {
+			t.Fatalf("IsAuctionMessage(%q) = false", tt.message)
+		}
+		listing := Parse("Shin", tt.message)
+		if len(listing.Items) == 0 || listing.Items[0].Kind != tt.wantKind {
+			t.Fatalf("Parse(%q) items = %+v, want kind %s", tt.message, listing.Items, tt.wantKind)
+		}
+		if got := listing.ToEmbeds()[0].Title; got != tt.wantTitle {
+			t.Fatalf("Parse(%q) title = %q, want %q", tt.message, got, tt.wantTitle)
+		}
+	}
+	if IsAuctionMessage("my pc crashed again") {
+		t.Fatalf("IsAuctionMessage wanted false for pc without on")
+	}
+}
@@ -0,0 +1,131 @@
+package auction
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+)
+
+var (
+	isDigestEnabled bool
+	// digestListings counts listings since the last digest by the kind of their first item
+	digestListings = make(map[string]int)
+	// digestItems are items auctioned since the last digest
+	digestItems []Item
+	topItems    int
+)
+
+// digestItem is an item's auction count and WTS price range in a digest
+type digestItem struct {
+	name     string
+	listings int
+	min      int
+	max      int
+}
+
+// setDigest enables buffering listings for the auction digest
+func setDigest(cfg config.AuctionDigest) {
+	mu.Lock()
+	defer mu.Unlock()
+	isDigestEnabled = cfg.IsEnabled
+	topItems = cfg.TopItems
+}
+
+// addDigest buffers a listing for the next digest, mu is expected to be locked
+func addDigest(l *Listing) {
+	if !isDigestEnabled || len(l.Items) == 0 {
+		return
+	}
+	digestListings[l.Items[0].Kind]++
+	digestItems = append(digestItems, l.Items...)
+}
+
+// Digest returns an embed summarizing listings since the previous digest and clears them, or nil if there were none
+func Digest() *request.DiscordEmbed {
+	mu.Lock()
+	listings := digestListings
+	items := digestItems
+	top := topItems
+	digestListings = make(map[string]int)
+	digestItems = nil
+	mu.Unlock()
+
+	if len(listings) == 0 {
+		return nil
+	}
+	return digestEmbed(listings, items, top)
+}
+
+// digestEmbed formats listing counts by kind and the top most auctioned items
+func digestEmbed(listings map[string]int, items []Item, top int) *request.DiscordEmbed {
+	counts := []string{}
+	for _, kind := range []string{KindSell, KindBuy, KindTrade, KindPriceCheck, KindSearch} {
+		if listings[kind] == 0 {
+			continue
+		}
+		counts = append(counts, fmt.Sprintf("%s %d", kind, listings[kind]))
+	}
+
+	byName := map[string]*digestItem{}
+	for _, item := range items {
+		entry, ok := byName[item.Name]
+		if !ok {
+			entry = &digestItem{name: item.Name}
+			byName[item.Name] = entry
+		}
+		entry.listings++
+		if item.Kind != KindSell || item.PricePlat < 1 {
+			continue
+		}
+		if entry.min == 0 || item.PricePlat < entry.min {
+			entry.min = item.PricePlat
+		}
+		if item.PricePlat > entry.max {
+			entry.max = item.PricePlat
+		}
+	}
+	ranked := []*digestItem{}
+	for _, entry := range byName {
+		ranked = append(ranked, entry)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].listings != ranked[j].listings {
+			return ranked[i].listings > ranked[j].listings
+		}
+		return ranked[i].name < ranked[j].name
+	})
+	if len(ranked) > top {
+		ranked = ranked[:top]
+	}
+
+	lines := []string{}
+	size := 0
+	for _, entry := range ranked {
+		line := fmt.Sprintf("%s x%d", entry.name, entry.listings)
+		switch {
+		case entry.max == 0:
+		case entry.min == entry.max:
+			line += fmt.Sprintf(", %dpp", entry.min)
+		default:
+			line += fmt.Sprintf(", %d-%dpp", entry.min, entry.max)
+		}
+		// discord allows 1024 characters in a field value
+		size += len(line) + 1
+		if size > 1024 {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	return &request.DiscordEmbed{
+		Title: "Auction digest",
+		Color: auctionStyle.color,
+		Fields: []request.DiscordEmbedField{
+			{Name: "Listings", Value: strings.Join(counts, ", ")},
+			{Name: "Most auctioned", Value: strings.Join(lines, "\n")},
+		},
+	}
+}
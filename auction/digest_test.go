@@ -0,0 +1,40 @@
+package auction
+
+import (
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+)
+
+func TestDigest(t *testing.T) {
+	setDigest(config.AuctionDigest{IsEnabled: true, TopItems: 2})
+	defer setDigest(config.AuctionDigest{})
+
+	if Digest() != nil {
+		t.Fatalf("digest with no listings wanted nil")
+	}
+	for _, message := range []string{
+		"WTS Cloak of Flames 1k, Bone Chips 10p",
+		"WTS Cloak of Flames 1.5k",
+		"WTB Cloak of Flames",
+		"WTB Fungi Tunic",
+	} {
+		err := Save(Parse("Shin", message))
+		if err != nil {
+			t.Fatalf("save: %s", err)
+		}
+	}
+	embed := Digest()
+	if embed == nil || len(embed.Fields) != 2 {
+		t.Fatalf("digest wanted 2 fields, got %+v", embed)
+	}
+	if got := embed.Fields[0].Value; got != "WTS 2, WTB 2" {
+		t.Fatalf("listings = %q", got)
+	}
+	if got := embed.Fields[1].Value; got != "Cloak of Flames x3, 1000-1500pp\nBone Chips x1, 10pp" {
+		t.Fatalf("most auctioned = %q", got)
+	}
+	if Digest() != nil {
+		t.Fatalf("digest wanted nil after being posted")
+	}
+}
@@ -0,0 +1,203 @@
+package auction
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/tlog"
+)
+
+var (
+	// history is every saved listing item within lookback, oldest first
+	history     []Record
+	historyPath string
+	lookback    time.Duration
+	// historyDay is the day the history file was last rewritten without records older than lookback
+	historyDay string
+)
+
+// Record is an auctioned item saved to the auction history
+type Record struct {
+	Time      time.Time `json:"time"`
+	Seller    string    `json:"seller"`
+	Item      string    `json:"item"`
+	Kind      string    `json:"kind"`
+	PricePlat int       `json:"price_plat"`
+}
+
+// Stats summarizes the prices an item was auctioned for
+type Stats struct {
+	Item string
+	// Listings is how many times the item was auctioned, buying or selling
+	Listings int
+	// Priced is how many WTS listings had a price, used for Min, Avg and Max
+	Priced int
+	Min    int
+	Avg    int
+	Max    int
+}
+
+// loadHistory reads the saved auction history, dropping listings older than the lookback window
+func loadHistory(cfg config.AuctionHistory) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if !cfg.IsEnabled {
+		return nil
+	}
+	historyPath = cfg.Path
+	lookback = time.Duration(cfg.LookbackDays) * 24 * time.Hour
+
+	f, err := os.Open(historyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	cutoff := time.Now().Add(-lookback)
+	history = nil
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		record := Record{}
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			continue
+		}
+		if record.Time.Before(cutoff) {
+			continue
+		}
+		history = append(history, record)
+	}
+	err = scanner.Err()
+	if err != nil {
+		return fmt.Errorf("scan: %w", err)
+	}
+	err = saveHistory()
+	if err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+	historyDay = time.Now().Format("2006-01-02")
+	tlog.Debugf("[auction] loaded %d auction history records", len(history))
+	return nil
+}
+
+// saveHistory rewrites the history file with the records in lookback, mu is expected to be locked
+func saveHistory() error {
+	f, err := os.Create(historyPath)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, record := range history {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+		w.Write(data)
+		w.WriteString("\n")
+	}
+	return w.Flush()
+}
+
+// SaveMessage parses an auction message said in game and saves it, if it is one. It should be called once per line read,
+// not once per channel the line is relayed to, so a listing is not counted twice
+func SaveMessage(name string, text string) {
+	if !IsAuctionMessage(text) {
+		return
+	}
+	err := Save(Parse(name, text))
+	if err != nil {
+		tlog.Warnf("[auction] save failed: %s", err)
+	}
+}
+
+// Save adds each item of a listing to the auction history and the next digest. Disabled features are skipped
+func Save(l *Listing) error {
+	mu.Lock()
+	defer mu.Unlock()
+	addDigest(l)
+	if historyPath == "" {
+		return nil
+	}
+	now := time.Now()
+	cutoff := now.Add(-lookback)
+	for len(history) > 0 && history[0].Time.Before(cutoff) {
+		history = history[1:]
+	}
+	// like the chat log, the file is pruned once a day instead of growing until the next restart
+	day := now.Format("2006-01-02")
+	if day != historyDay {
+		historyDay = day
+		err := saveHistory()
+		if err != nil {
+			tlog.Warnf("[auction] prune history failed: %s", err)
+		}
+	}
+	f, err := os.OpenFile(historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+	for _, item := range l.Items {
+		record := Record{Time: now, Seller: l.Name, Item: item.Name, Kind: item.Kind, PricePlat: item.PricePlat}
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+		_, err = f.Write(append(data, '\n'))
+		if err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+		history = append(history, record)
+	}
+	return nil
+}
+
+// Market returns price stats of items in the auction history matching name
+func Market(name string) Stats {
+	name = CanonicalName(name)
+	mu.RLock()
+	defer mu.RUnlock()
+	stats := Stats{Item: name}
+	cutoff := time.Now().Add(-lookback)
+	total := 0
+	for _, record := range history {
+		if record.Time.Before(cutoff) {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(record.Item), strings.ToLower(name)) {
+			continue
+		}
+		stats.Listings++
+		if record.Kind != KindSell || record.PricePlat < 1 {
+			continue
+		}
+		if stats.Priced == 0 || record.PricePlat < stats.Min {
+			stats.Min = record.PricePlat
+		}
+		if record.PricePlat > stats.Max {
+			stats.Max = record.PricePlat
+		}
+		stats.Priced++
+		total += record.PricePlat
+	}
+	if stats.Priced > 0 {
+		stats.Avg = total / stats.Priced
+	}
+	return stats
+}
+
+// IsHistoryEnabled returns true if auction history is being saved
+func IsHistoryEnabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return historyPath != ""
+}
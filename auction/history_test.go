@@ -0,0 +1,92 @@
+package auction
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xackery/talkeq/config"
+)
+
+func TestMarket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	err := loadHistory(config.AuctionHistory{IsEnabled: true, Path: path, LookbackDays: 1})
+	if err != nil {
+		t.Fatalf("loadHistory: %s", err)
+	}
+	defer func() {
+		mu.Lock()
+		history = nil
+		historyPath = ""
+		mu.Unlock()
+	}()
+
+	for _, message := range []string{
+		"WTS Cloak of Flames 1k",
+		"WTS Cloak of Flames 2k, Fungi Tunic 500p",
+		"WTB Cloak of Flames 800p",
+		"WTS Cloak of Flames",
+	} {
+		err = Save(Parse("Shin", message))
+		if err != nil {
+			t.Fatalf("save: %s", err)
+		}
+	}
+	mu.Lock()
+	history = append([]Record{{Time: time.Now().Add(-48 * time.Hour), Item: "Cloak of Flames", Kind: KindSell, PricePlat: 1}}, history...)
+	mu.Unlock()
+
+	got := Market("cloak of flames")
+	want := Stats{Item: "cloak of flames", Listings: 4, Priced: 2, Min: 1000, Avg: 1500, Max: 2000}
+	if got != want {
+		t.Fatalf("Market() = %+v, want %+v", got, want)
+	}
+
+	err = loadHistory(config.AuctionHistory{IsEnabled: true, Path: path, LookbackDays: 1})
+	if err != nil {
+		t.Fatalf("reload: %s", err)
+	}
+	if got = Market("cloak of flames"); got != want {
+		t.Fatalf("Market() after reload = %+v, want %+v", got, want)
+	}
+}
+
+func TestSave_prune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	err := loadHistory(config.AuctionHistory{IsEnabled: true, Path: path, LookbackDays: 1})
+	if err != nil {
+		t.Fatalf("loadHistory: %s", err)
+	}
+	defer func() {
+		mu.Lock()
+		history = nil
+		historyPath = ""
+		mu.Unlock()
+	}()
+
+	mu.Lock()
+	history = []Record{{Time: time.Now().Add(-48 * time.Hour), Item: "Rusty Dagger", Kind: KindSell, PricePlat: 1}}
+	err = saveHistory()
+	// a new day has started since the file was last pruned
+	historyDay = "2000-01-01"
+	mu.Unlock()
+	if err != nil {
+		t.Fatalf("saveHistory: %s", err)
+	}
+
+	SaveMessage("Shin", "hello there")
+	SaveMessage("Shin", "WTS Cloak of Flames 1k")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	if strings.Contains(string(data), "Rusty Dagger") {
+		t.Fatalf("wanted records older than lookback pruned from the file, got %s", data)
+	}
+	if strings.Count(string(data), "\n") != 1 || !strings.Contains(string(data), "Cloak of Flames") {
+		t.Fatalf("wanted only the auction saved, got %s", data)
+	}
+}
@@ -2,8 +2,10 @@ package characterdb
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/xackery/talkeq/tlog"
 )
@@ -12,6 +14,16 @@ var (
 	characters  = make(map[string]*Character)
 	mu          sync.RWMutex
 	onlineCount int
+	// isLoaded is true once a who list was set, so the first list after starting is not reported as everyone logging in
+	isLoaded bool
+	// lastUpdate is when a who list was last set
+	lastUpdate time.Time
+	// classIcons are emoji shown before names, keyed by lowercase class
+	classIcons = make(map[string]string)
+	// maxCharacters caps a who list and the last seen data, 0 is unlimited
+	maxCharacters int
+	// isMaxWarned is true once reaching maxCharacters was warned about, so it is not repeated every who list
+	isMaxWarned bool
 )
 
 // Character represents a character inside EverQuest
@@ -30,11 +42,24 @@ type Character struct {
 	Status   int
 }
 
+// PlayerChange is a difference found for a character between two who lists
+type PlayerChange struct {
+	Character    *Character
+	IsLogin      bool
+	IsZoneChange bool
+	OldZone      string
+	IsLevelUp    bool
+	OldLevel     int
+	// LastSeen is when a logging in character was previously seen, zero if never
+	LastSeen time.Time
+}
+
 // Characters is an list of character
 type Characters []*Character
 
-// CharactersOnline returns a string of online characters
-func CharactersOnline(filter string) string {
+// CharactersOnline returns a string of online characters.
+// ANON and RolePlay characters are hidden unless isHiddenShown, e.g. for admins, then they are marked instead
+func CharactersOnline(filter string, isHiddenShown bool) string {
 	mu.RLock()
 	defer mu.RUnlock()
 	content := ""
@@ -47,11 +72,13 @@ func CharactersOnline(filter string) string {
 		if totalCount >= 20 {
 			isTruncated = true
 		}
+		marker := ""
 		if strings.Contains(user.State, "ANON") {
-			hiddenCount++
-			continue
+			marker = " (anon)"
+		} else if strings.Contains(user.State, "RolePlay") {
+			marker = " (roleplay)"
 		}
-		if strings.Contains(user.State, "RolePlay") {
+		if marker != "" && !isHiddenShown {
 			hiddenCount++
 			continue
 		}
@@ -61,7 +88,7 @@ func CharactersOnline(filter string) string {
 		}*/
 
 		if filter == "" {
-			content += fmt.Sprintf("%s\n", user.Name)
+			content += fmt.Sprintf("%s%s%s\n", classPrefix(user.Class), user.Name, marker)
 			totalCount++
 			continue
 		}
@@ -71,13 +98,13 @@ func CharactersOnline(filter string) string {
 			continue
 		}
 
-		content += fmt.Sprintf("%s\n", user.Name)
+		content += fmt.Sprintf("%s%s%s\n", classPrefix(user.Class), user.Name, marker)
 		totalCount++
 	}
 
 	hiddenText := ""
 	if hiddenCount > 0 {
-		hiddenText = "(%d hidden) "
+		hiddenText = fmt.Sprintf("(%d hidden) ", hiddenCount)
 	}
 
 	truncatedText := ""
@@ -98,15 +125,195 @@ func CharactersOnline(filter string) string {
 	return content
 }
 
-// SetCharacters sets the character db to provided argument
-func SetCharacters(req map[string]*Character) error {
+// SetClassIcons sets the emoji shown before names of each class
+func SetClassIcons(icons map[string]string) {
 	mu.Lock()
 	defer mu.Unlock()
+	classIcons = make(map[string]string)
+	for class, icon := range icons {
+		classIcons[strings.ToLower(class)] = icon
+	}
+}
+
+// ClassIcon returns the emoji of a class, falling back to the class name in brackets.
+// If no class icons are set, an empty string is returned
+func ClassIcon(class string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return classIcon(class)
+}
+
+// classIcon is ClassIcon, mu is expected to be locked
+func classIcon(class string) string {
+	if len(classIcons) == 0 {
+		return ""
+	}
+	icon, ok := classIcons[strings.ToLower(class)]
+	if ok {
+		return icon
+	}
+	if class == "" {
+		return ""
+	}
+	return fmt.Sprintf("[%s]", class)
+}
+
+// classPrefix returns the class icon followed by a space, or an empty string, mu is expected to be locked
+func classPrefix(class string) string {
+	icon := classIcon(class)
+	if icon == "" {
+		return ""
+	}
+	return icon + " "
+}
+
+// Suggestions returns up to limit sorted names and zones of visible online characters that contain filter, for command autocomplete
+func Suggestions(filter string, limit int) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	filter = strings.ToLower(filter)
+	unique := map[string]bool{}
+	for _, user := range characters {
+		if strings.Contains(user.State, "ANON") || strings.Contains(user.State, "RolePlay") {
+			continue
+		}
+		for _, value := range []string{user.Name, user.Zone} {
+			if value == "" || !strings.Contains(strings.ToLower(value), filter) {
+				continue
+			}
+			unique[value] = true
+		}
+	}
+	suggestions := []string{}
+	for value := range unique {
+		suggestions = append(suggestions, value)
+	}
+	sort.Strings(suggestions)
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// SetCharacters sets the character db to provided argument, and returns what changed since the previous list
+func SetCharacters(req map[string]*Character) ([]PlayerChange, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if maxCharacters > 0 && len(req) > maxCharacters {
+		tlog.Warnf("[characterdb] who list has %d characters, more than character_database_max %d, keeping the %d most recently seen. This may mean who lines are being misread", len(req), maxCharacters, maxCharacters)
+		req = capCharacters(req, maxCharacters)
+	}
+
+	changes := []PlayerChange{}
+	if isLoaded {
+		for name, character := range req {
+			old, ok := characters[name]
+			if !ok {
+				changes = append(changes, PlayerChange{Character: character, IsLogin: true, LastSeen: lastSeen[strings.ToLower(name)]})
+				continue
+			}
+			change := PlayerChange{
+				Character:    character,
+				IsZoneChange: old.Zone != character.Zone,
+				OldZone:      old.Zone,
+				IsLevelUp:    character.Level > old.Level && old.Level > 0,
+				OldLevel:     old.Level,
+			}
+			if change.IsZoneChange || change.IsLevelUp {
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	now := time.Now()
+	isLastSeenChanged := false
+	for name := range req {
+		_, ok := lastSeen[strings.ToLower(name)]
+		if !ok {
+			isLastSeenChanged = true
+		}
+		lastSeen[strings.ToLower(name)] = now
+	}
+	evicted := evictLastSeen(maxCharacters)
+	if evicted > 0 && !isMaxWarned {
+		tlog.Warnf("[characterdb] last seen data reached character_database_max %d, removed the %d least recently seen characters", maxCharacters, evicted)
+		isMaxWarned = true
+	}
+	if isLastSeenChanged || evicted > 0 || (len(req) > 0 && now.Sub(lastSeenSaved) >= lastSeenSaveInterval) {
+		err := saveLastSeen()
+		if err != nil {
+			tlog.Warnf("[characterdb] save last seen: %s", err)
+		}
+	}
 
 	characters = req
+	isLoaded = true
+	lastUpdate = now
 	onlineCount = len(characters)
 	tlog.Debugf("[characterdb] onlineCount is %d", onlineCount)
-	return nil
+	return changes, nil
+}
+
+// ClearCharacters empties the who list, used when the server reports no players online
+func ClearCharacters() {
+	mu.Lock()
+	defer mu.Unlock()
+	characters = make(map[string]*Character)
+	onlineCount = 0
+	isLoaded = true
+	lastUpdate = time.Now()
+}
+
+// SetMaxCharacters sets the most characters kept in a who list and the last seen data, 0 is unlimited
+func SetMaxCharacters(value int) {
+	mu.Lock()
+	defer mu.Unlock()
+	maxCharacters = value
+}
+
+// capCharacters returns the max most recently seen characters of req, characters seen at the same time are kept by name.
+// mu is expected to be locked
+func capCharacters(req map[string]*Character, max int) map[string]*Character {
+	names := []string{}
+	for name := range req {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		seenI := lastSeen[strings.ToLower(names[i])]
+		seenJ := lastSeen[strings.ToLower(names[j])]
+		if !seenI.Equal(seenJ) {
+			return seenI.After(seenJ)
+		}
+		return names[i] < names[j]
+	})
+	capped := make(map[string]*Character, max)
+	for _, name := range names[:max] {
+		capped[name] = req[name]
+	}
+	return capped
+}
+
+// LastUpdate returns when a who list was last set, or a zero time if never
+func LastUpdate() time.Time {
+	mu.RLock()
+	defer mu.RUnlock()
+	return lastUpdate
+}
+
+// Roster returns a copy of visible online characters sorted by name, ANON and RolePlay characters are left out
+func Roster() []Character {
+	mu.RLock()
+	defer mu.RUnlock()
+	roster := []Character{}
+	for _, user := range characters {
+		if strings.Contains(user.State, "ANON") || strings.Contains(user.State, "RolePlay") {
+			continue
+		}
+		roster = append(roster, *user)
+	}
+	sort.Slice(roster, func(i, j int) bool { return roster[i].Name < roster[j].Name })
+	return roster
 }
 
 // CharactersOnlineCount returns how many characters are reported online
@@ -122,3 +329,15 @@ func SetCharactersOnlineCount(value int) {
 	defer mu.Unlock()
 	onlineCount = value
 }
+
+// IsOnline returns true if a character with provided name was in the last who list
+func IsOnline(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, character := range characters {
+		if strings.EqualFold(character.Name, name) {
+			return true
+		}
+	}
+	return false
+}
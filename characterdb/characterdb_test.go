@@ -0,0 +1,102 @@
+package characterdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetCharacters(t *testing.T) {
+	changes, err := SetCharacters(map[string]*Character{
+		"Xackery": {Name: "Xackery", Zone: "arena", Level: 59},
+	})
+	if err != nil {
+		t.Fatalf("setCharacters: %s", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("first list wanted 0 changes, got %d", len(changes))
+	}
+
+	changes, err = SetCharacters(map[string]*Character{
+		"Xackery": {Name: "Xackery", Zone: "fearplane", Level: 60},
+		"Shin":    {Name: "Shin", Zone: "qeynos", Level: 1},
+	})
+	if err != nil {
+		t.Fatalf("setCharacters: %s", err)
+	}
+	isZoneChange := false
+	isLevelUp := false
+	isLogin := false
+	for _, change := range changes {
+		if change.IsZoneChange && change.Character.Name == "Xackery" && change.OldZone == "arena" {
+			isZoneChange = true
+		}
+		if change.IsLevelUp && change.Character.Name == "Xackery" && change.OldLevel == 59 {
+			isLevelUp = true
+		}
+		if change.IsLogin && change.Character.Name == "Shin" {
+			isLogin = true
+		}
+	}
+	if !isZoneChange || !isLevelUp || !isLogin {
+		t.Fatalf("wanted zone change, level up and login, got %+v", changes)
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	_, err := SetCharacters(map[string]*Character{
+		"Xackery": {Name: "Xackery", Zone: "arena"},
+		"Shin":    {Name: "Shin", Zone: "qeynos"},
+		"Hidden":  {Name: "Hidden", Zone: "arena", State: "ANON"},
+	})
+	if err != nil {
+		t.Fatalf("setCharacters: %s", err)
+	}
+	suggestions := Suggestions("A", 25)
+	if strings.Join(suggestions, ",") != "Xackery,arena" {
+		t.Fatalf("wanted Xackery,arena, got %v", suggestions)
+	}
+	suggestions = Suggestions("", 1)
+	if len(suggestions) != 1 {
+		t.Fatalf("wanted limit of 1, got %v", suggestions)
+	}
+}
+
+func TestClassIcon(t *testing.T) {
+	if got := ClassIcon("Enchanter"); got != "" {
+		t.Fatalf("ClassIcon() with no icons = %q, want empty", got)
+	}
+	SetClassIcons(map[string]string{"Enchanter": ":crystal_ball:"})
+	defer SetClassIcons(nil)
+
+	tests := []struct {
+		class string
+		want  string
+	}{
+		{"enchanter", ":crystal_ball:"},
+		{"Warrior", "[Warrior]"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ClassIcon(tt.class); got != tt.want {
+			t.Errorf("ClassIcon(%q) = %q, want %q", tt.class, got, tt.want)
+		}
+	}
+
+	_, err := SetCharacters(map[string]*Character{
+		"Shin":    {Name: "Shin", Class: "Enchanter"},
+		"Xackery": {Name: "Xackery", Class: "Warrior", State: "ANON"},
+	})
+	if err != nil {
+		t.Fatalf("setCharacters: %s", err)
+	}
+	content := CharactersOnline("", false)
+	if !strings.Contains(content, ":crystal_ball: Shin") {
+		t.Fatalf("CharactersOnline() = %q, want class icon before name", content)
+	}
+	if strings.Contains(content, "Xackery") || !strings.Contains(content, "(1 hidden)") {
+		t.Fatalf("CharactersOnline() = %q, want anon hidden", content)
+	}
+	if content := CharactersOnline("", true); !strings.Contains(content, "Xackery (anon)") {
+		t.Fatalf("CharactersOnline() shown hidden = %q, want anon marked", content)
+	}
+}
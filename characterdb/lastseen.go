@@ -0,0 +1,98 @@
+package characterdb
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jbsmith7741/toml"
+	"github.com/xackery/talkeq/tlog"
+)
+
+var (
+	// lastSeen is when each character was last in a who list, keyed by lowercase name
+	lastSeen     = make(map[string]time.Time)
+	lastSeenPath string
+	// lastSeenSaved is when last seen data was last written to lastSeenPath
+	lastSeenSaved time.Time
+)
+
+// lastSeenSaveInterval is how often last seen data is written while the same characters stay online.
+// A new or removed character is written right away
+const lastSeenSaveInterval = time.Hour
+
+type lastSeenFile struct {
+	Characters map[string]time.Time `toml:"characters"`
+}
+
+// LoadLastSeen loads when characters were last seen from path, and saves future who lists to it
+func LoadLastSeen(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	lastSeenPath = path
+	if path == "" {
+		return nil
+	}
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		tlog.Debugf("[characterdb] %s not found, starting with no last seen data", path)
+		return nil
+	}
+	data := lastSeenFile{}
+	_, err = toml.DecodeFile(path, &data)
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	lastSeen = make(map[string]time.Time)
+	for name, seen := range data.Characters {
+		lastSeen[strings.ToLower(name)] = seen
+	}
+	evictLastSeen(maxCharacters)
+	tlog.Debugf("[characterdb] loaded last seen data for %d characters", len(lastSeen))
+	return nil
+}
+
+// LastSeen returns when a character was last in a who list, or a zero time if never seen
+func LastSeen(name string) time.Time {
+	mu.RLock()
+	defer mu.RUnlock()
+	return lastSeen[strings.ToLower(name)]
+}
+
+// saveLastSeen writes last seen data to lastSeenPath, mu is expected to be locked
+func saveLastSeen() error {
+	if lastSeenPath == "" {
+		return nil
+	}
+	f, err := os.Create(lastSeenPath)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer f.Close()
+	err = toml.NewEncoder(f).Encode(lastSeenFile{Characters: lastSeen})
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	lastSeenSaved = time.Now()
+	return nil
+}
+
+// evictLastSeen removes the least recently seen characters until at most max remain, and returns how many were removed.
+// mu is expected to be locked
+func evictLastSeen(max int) int {
+	if max < 1 || len(lastSeen) <= max {
+		return 0
+	}
+	names := []string{}
+	for name := range lastSeen {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return lastSeen[names[i]].Before(lastSeen[names[j]]) })
+	evicted := len(names) - max
+	for _, name := range names[:evicted] {
+		delete(lastSeen, name)
+	}
+	return evicted
+}
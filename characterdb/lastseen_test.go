@@ -0,0 +1,126 @@
+package characterdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLastSeen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "last_seen.toml")
+	err := LoadLastSeen(path)
+	if err != nil {
+		t.Fatalf("loadLastSeen: %s", err)
+	}
+	defer LoadLastSeen("")
+
+	_, err = SetCharacters(map[string]*Character{
+		"Xackery": {Name: "Xackery", Zone: "arena", Level: 60},
+	})
+	if err != nil {
+		t.Fatalf("setCharacters: %s", err)
+	}
+	seen := LastSeen("xackery")
+	if seen.IsZero() {
+		t.Fatalf("wanted xackery to be seen")
+	}
+
+	mu.Lock()
+	lastSeen = make(map[string]time.Time)
+	mu.Unlock()
+	err = LoadLastSeen(path)
+	if err != nil {
+		t.Fatalf("reload: %s", err)
+	}
+	if LastSeen("Xackery").Unix() != seen.Unix() {
+		t.Fatalf("reload wanted %s, got %s", seen, LastSeen("Xackery"))
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatalf("remove: %s", err)
+	}
+	_, err = SetCharacters(map[string]*Character{
+		"Xackery": {Name: "Xackery", Zone: "arena", Level: 60},
+	})
+	if err != nil {
+		t.Fatalf("setCharacters: %s", err)
+	}
+	_, err = os.Stat(path)
+	if !os.IsNotExist(err) {
+		t.Fatalf("who list with no new characters wanted no save, got %v", err)
+	}
+
+	_, err = SetCharacters(map[string]*Character{
+		"Shin": {Name: "Shin", Zone: "qeynos", Level: 1},
+	})
+	if err != nil {
+		t.Fatalf("setCharacters: %s", err)
+	}
+	changes, err := SetCharacters(map[string]*Character{
+		"Shin":    {Name: "Shin", Zone: "qeynos", Level: 1},
+		"Xackery": {Name: "Xackery", Zone: "arena", Level: 60},
+	})
+	if err != nil {
+		t.Fatalf("setCharacters: %s", err)
+	}
+	if len(changes) != 1 || !changes[0].IsLogin || changes[0].LastSeen.Unix() != seen.Unix() {
+		t.Fatalf("wanted xackery login last seen %s, got %+v", seen, changes)
+	}
+}
+
+func TestMaxCharacters(t *testing.T) {
+	SetMaxCharacters(2)
+	defer SetMaxCharacters(0)
+	mu.Lock()
+	lastSeen = map[string]time.Time{
+		"old":    time.Now().Add(-48 * time.Hour),
+		"recent": time.Now().Add(-time.Hour),
+	}
+	mu.Unlock()
+
+	_, err := SetCharacters(map[string]*Character{
+		"Shin":    {Name: "Shin"},
+		"Xackery": {Name: "Xackery"},
+		"Zed":     {Name: "Zed"},
+	})
+	if err != nil {
+		t.Fatalf("setCharacters: %s", err)
+	}
+	if CharactersOnlineCount() != 2 {
+		t.Fatalf("online wanted 2 after cap, got %d", CharactersOnlineCount())
+	}
+	if !LastSeen("old").IsZero() || !LastSeen("recent").IsZero() {
+		t.Fatalf("least recently seen wanted evicted")
+	}
+	if LastSeen("shin").IsZero() || LastSeen("xackery").IsZero() {
+		t.Fatalf("characters in who list wanted kept")
+	}
+}
+
+func TestMaxCharacters_recency(t *testing.T) {
+	SetMaxCharacters(2)
+	defer SetMaxCharacters(0)
+	mu.Lock()
+	lastSeen = map[string]time.Time{
+		"zed":     time.Now().Add(-time.Hour),
+		"xackery": time.Now().Add(-48 * time.Hour),
+	}
+	mu.Unlock()
+
+	_, err := SetCharacters(map[string]*Character{
+		"Shin":    {Name: "Shin"},
+		"Xackery": {Name: "Xackery"},
+		"Zed":     {Name: "Zed"},
+	})
+	if err != nil {
+		t.Fatalf("setCharacters: %s", err)
+	}
+	if !LastSeen("shin").IsZero() {
+		t.Fatalf("never seen shin wanted dropped before recently seen characters")
+	}
+	if LastSeen("xackery").IsZero() || LastSeen("zed").IsZero() {
+		t.Fatalf("most recently seen characters wanted kept")
+	}
+}
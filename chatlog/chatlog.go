@@ -0,0 +1,194 @@
+package chatlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/tlog"
+)
+
+var (
+	isStarted     bool
+	mu            sync.Mutex
+	logPath       string
+	retentionDays int
+	// currentDay is the day of the last appended entry, used to prune old files when a new day starts
+	currentDay string
+)
+
+// Entry is a relayed message saved to the chat log
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	ChannelID string    `json:"channel_id"`
+	Author    string    `json:"author"`
+	Message   string    `json:"message"`
+}
+
+// New initializes the chat log
+func New(config *config.Config) error {
+	if isStarted {
+		return fmt.Errorf("already started")
+	}
+	if !config.ChatLog.IsEnabled {
+		return nil
+	}
+	tlog.Debugf("[chatlog] initializing")
+	err := os.MkdirAll(config.ChatLog.Path, 0755)
+	if err != nil {
+		return fmt.Errorf("mkdir %s: %w", config.ChatLog.Path, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	logPath = config.ChatLog.Path
+	retentionDays = config.ChatLog.RetentionDays
+	currentDay = time.Now().Format("2006-01-02")
+	err = prune(time.Now())
+	if err != nil {
+		return fmt.Errorf("prune: %w", err)
+	}
+	isStarted = true
+	return nil
+}
+
+// fileName returns the chat log file for provided day
+func fileName(day time.Time) string {
+	return fmt.Sprintf("chatlog-%s.jsonl", day.Format("2006-01-02"))
+}
+
+// Append saves an entry to the chat log file of the entry's day. If the chat log is disabled, it is ignored
+func Append(entry Entry) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if logPath == "" {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	day := time.Now().Format("2006-01-02")
+	if day != currentDay {
+		currentDay = day
+		err = prune(time.Now())
+		if err != nil {
+			tlog.Warnf("[chatlog] prune failed: %s", err)
+		}
+	}
+	f, err := os.OpenFile(filepath.Join(logPath, fileName(entry.Time)), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
+
+// prune deletes chat log files older than retentionDays before now, mu is expected to be locked
+func prune(now time.Time) error {
+	if retentionDays < 1 {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(logPath, "chatlog-*.jsonl"))
+	if err != nil {
+		return fmt.Errorf("glob: %w", err)
+	}
+	oldest := fileName(now.AddDate(0, 0, -retentionDays))
+	for _, path := range files {
+		if filepath.Base(path) >= oldest {
+			continue
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return fmt.Errorf("remove %s: %w", filepath.Base(path), err)
+		}
+		tlog.Infof("[chatlog] removed %s, older than %d days", filepath.Base(path), retentionDays)
+	}
+	return nil
+}
+
+// Search returns entries with an author or message containing term, newest first, skipping offset entries and returning at most limit.
+// If isAllowed is set, entries it returns false for are left out, e.g. chat from channels the searcher cannot read.
+// The total number of matching entries is also returned
+func Search(term string, offset int, limit int, isAllowed func(Entry) bool) ([]Entry, int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if logPath == "" {
+		return nil, 0, fmt.Errorf("chat log is not enabled")
+	}
+	files, err := filepath.Glob(filepath.Join(logPath, "chatlog-*.jsonl"))
+	if err != nil {
+		return nil, 0, fmt.Errorf("glob: %w", err)
+	}
+	// file names sort by date, so reverse for newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+
+	term = strings.ToLower(term)
+	entries := []Entry{}
+	total := 0
+	for _, path := range files {
+		matches, err := searchFile(path, term)
+		if err != nil {
+			return nil, 0, fmt.Errorf("search %s: %w", filepath.Base(path), err)
+		}
+		for i := len(matches) - 1; i >= 0; i-- {
+			if isAllowed != nil && !isAllowed(matches[i]) {
+				continue
+			}
+			total++
+			if total <= offset || len(entries) >= limit {
+				continue
+			}
+			entries = append(entries, matches[i])
+		}
+	}
+	return entries, total, nil
+}
+
+// searchFile returns entries in path matching a lowercase term, oldest first
+func searchFile(path string, term string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !strings.Contains(strings.ToLower(string(line)), term) {
+			continue
+		}
+		entry := Entry{}
+		err = json.Unmarshal(line, &entry)
+		if err != nil {
+			tlog.Debugf("[chatlog] skipping invalid line in %s: %s", path, err)
+			continue
+		}
+		if !strings.Contains(strings.ToLower(entry.Author), term) && !strings.Contains(strings.ToLower(entry.Message), term) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,89 @@
+package chatlog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xackery/talkeq/config"
+)
+
+func TestSearch(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.ChatLog.IsEnabled = true
+	cfg.ChatLog.Path = t.TempDir()
+	err := New(cfg)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	entries := []Entry{
+		{Time: yesterday, Source: "telnet", Author: "Xackery", Message: "anyone selling a cloak?"},
+		{Time: yesterday.Add(time.Minute), Source: "telnet", Author: "Shin", Message: "lfg fear"},
+		{Time: time.Now(), Source: "discord", Author: "Xackery", Message: "still want that Cloak"},
+	}
+	for _, entry := range entries {
+		err = Append(entry)
+		if err != nil {
+			t.Fatalf("append: %s", err)
+		}
+	}
+
+	results, total, err := Search("cloak", 0, 10, nil)
+	if err != nil {
+		t.Fatalf("search: %s", err)
+	}
+	if total != 2 || len(results) != 2 {
+		t.Fatalf("wanted 2 results, got %d of %d", len(results), total)
+	}
+	if results[0].Message != "still want that Cloak" {
+		t.Fatalf("wanted newest first, got %s", results[0].Message)
+	}
+
+	results, total, err = Search("xackery", 1, 10, nil)
+	if err != nil {
+		t.Fatalf("search: %s", err)
+	}
+	if total != 2 || len(results) != 1 || results[0].Message != "anyone selling a cloak?" {
+		t.Fatalf("wanted second page to be oldest xackery entry, got %+v of %d", results, total)
+	}
+
+	results, total, err = Search("cloak", 0, 10, func(entry Entry) bool { return entry.Source == "telnet" })
+	if err != nil {
+		t.Fatalf("search: %s", err)
+	}
+	if total != 1 || len(results) != 1 || results[0].Message != "anyone selling a cloak?" {
+		t.Fatalf("wanted only allowed entries, got %+v of %d", results, total)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	mu.Lock()
+	defer mu.Unlock()
+	oldPath, oldRetention := logPath, retentionDays
+	defer func() { logPath, retentionDays = oldPath, oldRetention }()
+	logPath = dir
+	retentionDays = 2
+
+	now := time.Now()
+	for days := 0; days < 5; days++ {
+		err := os.WriteFile(filepath.Join(dir, fileName(now.AddDate(0, 0, -days))), []byte("{}\n"), 0644)
+		if err != nil {
+			t.Fatalf("write: %s", err)
+		}
+	}
+	err := prune(now)
+	if err != nil {
+		t.Fatalf("prune: %s", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "chatlog-*.jsonl"))
+	if err != nil {
+		t.Fatalf("glob: %s", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("wanted today and 2 previous days kept, got %v", files)
+	}
+}
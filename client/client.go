@@ -2,16 +2,21 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/xackery/talkeq/api"
+	"github.com/xackery/talkeq/auction"
+	"github.com/xackery/talkeq/characterdb"
+	"github.com/xackery/talkeq/chatlog"
 	"github.com/xackery/talkeq/config"
 	"github.com/xackery/talkeq/discord"
 	"github.com/xackery/talkeq/eqlog"
 	"github.com/xackery/talkeq/guilddb"
 	"github.com/xackery/talkeq/peqeditorsql"
-	"github.com/xackery/talkeq/request"
 	"github.com/xackery/talkeq/sqlreport"
 	"github.com/xackery/talkeq/telnet"
 	"github.com/xackery/talkeq/tlog"
@@ -28,7 +33,12 @@ type Client struct {
 	eqlog        *eqlog.EQLog
 	sqlreport    *sqlreport.SQLReport
 	peqeditorsql *peqeditorsql.PEQEditorSQL
+	echo         *echoGuard
 	api          *api.API
+	handlers     map[reflect.Type]requestHandler
+	endpoints    []endpoint
+	reconnect    chan string
+	keepAlive    *keepAliveTracker
 }
 
 // New creates a new client
@@ -36,9 +46,13 @@ func New(ctx context.Context) (*Client, error) {
 	var err error
 	ctx, cancel := context.WithCancel(ctx)
 	c := Client{
-		ctx:    ctx,
-		cancel: cancel,
+		ctx:       ctx,
+		cancel:    cancel,
+		echo:      newEchoGuard(),
+		reconnect: make(chan string, 1),
+		keepAlive: newKeepAliveTracker(),
 	}
+	c.registerHandlers()
 	tlog.Debugf("[talkeq] initializing talkeq client")
 	c.config, err = config.NewConfig(ctx)
 	if err != nil {
@@ -56,16 +70,31 @@ func New(ctx context.Context) (*Client, error) {
 		return nil, fmt.Errorf("guilddb.New: %w", err)
 	}
 
+	err = auction.New(c.config)
+	if err != nil {
+		return nil, fmt.Errorf("auction.New: %w", err)
+	}
+
+	characterdb.SetClassIcons(c.config.Discord.ClassIcons)
+	characterdb.SetMaxCharacters(c.config.CharacterDatabaseMax)
+
+	err = characterdb.LoadLastSeen(c.config.LastSeenDatabasePath)
+	if err != nil {
+		return nil, fmt.Errorf("characterdb.LoadLastSeen: %w", err)
+	}
+
+	err = chatlog.New(c.config)
+	if err != nil {
+		return nil, fmt.Errorf("chatlog.New: %w", err)
+	}
+
 	tlog.Debugf("[talkeq] initializing 3rd party connections")
 	c.discord, err = discord.New(ctx, c.config.Discord)
 	if err != nil {
 		return nil, fmt.Errorf("discord: %w", err)
 	}
 
-	err = c.discord.Subscribe(ctx, c.onMessage)
-	if err != nil {
-		return nil, fmt.Errorf("discord subscribe: %w", err)
-	}
+	c.discord.SetRootConfig(c.config)
 
 	c.telnet, err = telnet.New(ctx, c.config.Telnet)
 	if err != nil {
@@ -77,40 +106,38 @@ func New(ctx context.Context) (*Client, error) {
 		return nil, fmt.Errorf("sqlreport: %w", err)
 	}
 
-	err = c.telnet.Subscribe(ctx, c.onMessage)
-	if err != nil {
-		return nil, fmt.Errorf("telnet subscribe: %w", err)
-	}
-
 	c.eqlog, err = eqlog.New(ctx, c.config.EQLog)
 	if err != nil {
 		return nil, fmt.Errorf("eqlog: %w", err)
 	}
 
-	err = c.eqlog.Subscribe(ctx, c.onMessage)
-	if err != nil {
-		return nil, fmt.Errorf("eqlog subscribe: %w", err)
-	}
-
 	c.peqeditorsql, err = peqeditorsql.New(ctx, c.config.PEQEditor.SQL)
 	if err != nil {
 		return nil, fmt.Errorf("peqeditorsql: %w", err)
 	}
 
-	err = c.peqeditorsql.Subscribe(ctx, c.onMessage)
-	if err != nil {
-		return nil, fmt.Errorf("peqeditorsql subscribe: %w", err)
-	}
-
 	tlog.Debugf("[talkeq] initializing API")
-	c.api, err = api.New(ctx, c.config.API, c.discord)
+	c.api, err = api.New(ctx, c.config.API, c.discord, c.telnet)
 	if err != nil {
-		return nil, fmt.Errorf("api subscribe: %w", err)
+		return nil, fmt.Errorf("api: %w", err)
 	}
+	c.api.SetRootConfig(c.config)
 
-	err = c.api.Subscribe(ctx, c.onMessage)
-	if err != nil {
-		return nil, fmt.Errorf("api subscribe: %w", err)
+	c.addEndpoint("discord", c.discord, c.config.Discord.IsEnabled, true, true)
+	c.addEndpoint("telnet", c.telnet, c.config.Telnet.IsEnabled, true, true)
+	c.addEndpoint("sqlreport", c.sqlreport, c.config.SQLReport.IsEnabled, false, true)
+	c.addEndpoint("eqlog", c.eqlog, c.config.EQLog.IsEnabled, true, false)
+	c.addEndpoint("peqeditorsql", c.peqeditorsql, c.config.PEQEditor.SQL.IsEnabled, true, false)
+	c.addEndpoint("api", c.api, c.config.API.IsEnabled, true, false)
+
+	for _, e := range c.endpoints {
+		if !e.isSubscriber {
+			continue
+		}
+		err = e.Subscribe(ctx, c.onMessage)
+		if err != nil {
+			return nil, fmt.Errorf("%s subscribe: %w", e.name, err)
+		}
 	}
 
 	return &c, nil
@@ -120,60 +147,24 @@ func New(ctx context.Context) (*Client, error) {
 func (c *Client) Connect(ctx context.Context) error {
 	tlog.Debugf("[talkeq] connecting")
 
-	err := c.discord.Connect(ctx)
-	if err != nil {
-		if !c.config.IsKeepAliveEnabled {
-			return fmt.Errorf("discord connect: %w", err)
-		}
-		tlog.Warnf("[discord] connect failed: %s", err)
-	}
-
-	err = c.telnet.Connect(ctx)
-	if err != nil {
-		if !c.config.IsKeepAliveEnabled {
-			return fmt.Errorf("telnet connect: %w", err)
-		}
-		tlog.Warnf("[telnet] connect failed: %s", err)
-	}
-
-	err = c.sqlreport.Connect(ctx)
-	if err != nil {
-		if !c.config.IsKeepAliveEnabled {
-			return fmt.Errorf("sqlreport connect: %w", err)
-		}
-		tlog.Warnf("[sqlreport] connect failed: %s", err)
-	}
-
-	err = c.eqlog.Connect(ctx)
-	if err != nil {
-		if !c.config.IsKeepAliveEnabled {
-			return fmt.Errorf("eqlog connect: %w", err)
-		}
-		tlog.Warnf("[eqlog] connect failed: %s", err)
-	}
-
-	err = c.peqeditorsql.Connect(ctx)
-	if err != nil {
-		if !c.config.IsKeepAliveEnabled {
-			return fmt.Errorf("peqeditorsql connect: %w", err)
-		}
-		tlog.Warnf("[peqeditorsql] connect failed: %s", err)
-	}
-
-	err = c.api.Connect(ctx)
-	if err != nil {
-		if !c.config.IsKeepAliveEnabled {
-			return fmt.Errorf("api connect: %w", err)
+	for _, e := range c.endpoints {
+		err := e.Connect(ctx)
+		if err != nil {
+			if !c.config.IsKeepAliveEnabled {
+				return fmt.Errorf("%s connect: %w", e.name, err)
+			}
+			tlog.Warnf("[%s] connect failed: %s", e.name, err)
 		}
-		tlog.Warnf("[api] connect failed: %s", err)
 	}
 
 	go c.loop(ctx)
+	if c.config.AuctionDigest.IsEnabled && c.config.Discord.IsEnabled {
+		go c.digestLoop(ctx)
+	}
 	return nil
 }
 
 func (c *Client) loop(ctx context.Context) {
-	var err error
 	go func() {
 		var err error
 		var online int
@@ -185,14 +176,22 @@ func (c *Client) loop(ctx context.Context) {
 			default:
 			}
 			if c.config.Telnet.IsEnabled && c.config.Discord.IsEnabled {
-				online, err = c.telnet.Who(ctx)
-				if err != nil {
-					tlog.Warnf("[telnet] who failed: %s", err)
+				isServerOnline := c.telnet.IsConnected()
+				online = 0
+				if isServerOnline {
+					online, err = c.telnet.Who(ctx)
+					if err != nil {
+						tlog.Warnf("[telnet] who failed: %s", err)
+					}
 				}
-				err = c.discord.StatusUpdate(ctx, online, "")
+				err = c.discord.StatusUpdate(ctx, isServerOnline, online, "")
 				if err != nil {
 					tlog.Warnf("[discord] status update failed: %s", err)
 				}
+				err = c.discord.OnlineCountUpdate(online)
+				if err != nil {
+					tlog.Warnf("[discord] online count update failed: %s", err)
+				}
 			}
 
 			time.Sleep(60 * time.Second)
@@ -207,58 +206,95 @@ func (c *Client) loop(ctx context.Context) {
 		case <-ctx.Done():
 			tlog.Debugf("[talkeq] client loop exit, context done")
 			return
-		default:
+		case name := <-c.reconnect:
+			tlog.Debugf("[talkeq] %s lost connection, reconnecting now", name)
+		case <-time.After(c.config.KeepAliveRetryDuration()):
 		}
-		time.Sleep(c.config.KeepAliveRetryDuration())
-		if c.config.Discord.IsEnabled && !c.discord.IsConnected() {
-			tlog.Infof("[discord] attempting to reconnect")
-			err = c.discord.Connect(ctx)
-			if err != nil {
-				tlog.Warnf("[discord] reconnect failed: %s", err)
+		for _, e := range c.endpoints {
+			if !e.isKeepAlive || !e.isEnabled {
+				continue
 			}
+			c.keepAliveEndpoint(ctx, e)
 		}
-		if c.config.Telnet.IsEnabled && !c.telnet.IsConnected() {
-			tlog.Infof("[telnet] attempting to reconnect")
-			err = c.telnet.Connect(ctx)
-			if err != nil {
-				tlog.Warnf("[telnet] reconnect failed: %s", err)
-			}
-		}
-		if c.config.SQLReport.IsEnabled && !c.sqlreport.IsConnected() {
-			tlog.Infof("[sqlreport] attempting to reconnect")
-			err = c.sqlreport.Connect(ctx)
-			if err != nil {
-				tlog.Warnf("[sqlreport] connect failed: %s", err)
-			}
+	}
+}
+
+// keepAliveEndpoint reconnects an endpoint if it lost connection
+func (c *Client) keepAliveEndpoint(ctx context.Context, e endpoint) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.IsConnected() || c.keepAlive.isGivenUp(e.name) {
+		return
+	}
+	tlog.Infof("[%s] attempting to reconnect", e.name)
+	err := e.Connect(ctx)
+	if err != nil {
+		tlog.Warnf("[%s] reconnect failed: %s", e.name, err)
+		if c.keepAlive.failed(e.name, c.config.KeepAliveMaxRetries) {
+			c.giveUp(ctx, e.name)
 		}
+		return
 	}
+	c.keepAlive.succeeded(e.name)
 }
 
 func (c *Client) onMessage(rawReq interface{}) error {
-	var err error
+	_, err := c.handle(rawReq)
+	return err
+}
 
-	switch req := rawReq.(type) {
-	case request.APICommand:
-		err = c.api.Command(req)
-	case request.DiscordSend:
-		err = c.discord.Send(req)
-	case request.TelnetSend:
-		err = c.telnet.Send(req)
-	default:
-		return fmt.Errorf("unknown request type")
-	}
+// logChat saves a relayed message to the chat log
+func (c *Client) logChat(source string, channelID string, author string, message string) {
+	err := chatlog.Append(chatlog.Entry{
+		Source:    source,
+		ChannelID: channelID,
+		Author:    author,
+		Message:   message,
+	})
 	if err != nil {
-		return fmt.Errorf("send: %w", err)
+		tlog.Warnf("[chatlog] append failed: %s", err)
 	}
-	return nil
 }
 
 // Disconnect attempts to gracefully disconnect all enabled endpoints
+// The client context is cancelled first, so endpoints know talkeq is shutting down and not losing connection
+// Every endpoint is disconnected even if one fails, and the failures are returned together
 func (c *Client) Disconnect(ctx context.Context) error {
-	err := c.discord.Disconnect(ctx)
-	if err != nil {
-		return fmt.Errorf("discord: %w", err)
-	}
 	c.cancel()
-	return nil
+	errs := disconnectErrors{}
+	for i := len(c.endpoints) - 1; i >= 0; i-- {
+		e := c.endpoints[i]
+		e.mu.Lock()
+		err := e.Disconnect(c.ctx)
+		e.mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.name, err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// disconnectErrors are the failures of each endpoint on disconnect
+type disconnectErrors []error
+
+// Error returns each failure on its own line
+func (errs disconnectErrors) Error() string {
+	msgs := []string{}
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Is returns true if any failure is target, so errors.Is checks every endpoint
+func (errs disconnectErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestClient_Disconnect(t *testing.T) {
+	errClosed := errors.New("already closed")
+	telnet := &fakeEndpoint{t: t, disconnectErr: errClosed}
+	eqlog := &fakeEndpoint{t: t}
+	discord := &fakeEndpoint{t: t, disconnectErr: errors.New("timeout")}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{ctx: ctx, cancel: cancel}
+	c.addEndpoint("telnet", telnet, true, true, true)
+	c.addEndpoint("eqlog", eqlog, true, true, false)
+	c.addEndpoint("discord", discord, true, true, true)
+
+	err := c.Disconnect(context.Background())
+	if err == nil {
+		t.Fatalf("disconnect wanted error")
+	}
+	for _, f := range []*fakeEndpoint{telnet, eqlog, discord} {
+		if f.disconnects != 1 {
+			t.Fatalf("every endpoint wanted disconnected once, got %d", f.disconnects)
+		}
+	}
+	if !strings.Contains(err.Error(), "telnet: already closed") || !strings.Contains(err.Error(), "discord: timeout") {
+		t.Fatalf("error wanted both failures, got %q", err)
+	}
+	if !errors.Is(err, errClosed) {
+		t.Fatalf("errors.Is wanted to find the telnet failure")
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xackery/talkeq/auction"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// digestLoop posts the auction digest to discord every interval
+func (c *Client) digestLoop(ctx context.Context) {
+	cfg := c.config.AuctionDigest
+	ticker := time.NewTicker(cfg.IntervalDuration())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			tlog.Debugf("[auction] digest loop exit, context done")
+			return
+		case <-ticker.C:
+		}
+		embed := auction.Digest()
+		if embed == nil {
+			continue
+		}
+		err := c.discord.Send(request.DiscordSend{
+			Ctx:       ctx,
+			ChannelID: cfg.ChannelID,
+			Message:   fmt.Sprintf("auctions in the last %d minutes", cfg.Interval),
+			Embed:     embed,
+		})
+		if err != nil {
+			tlog.Warnf("[auction] digest send failed: %s", err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// echoWindow is how long a relayed message is remembered to detect it echoing back
+const echoWindow = 30 * time.Second
+
+// echoGuard remembers recently relayed chat in each direction, keyed by author and text, so a message
+// relayed one way and read back by telnet or eqlog is not relayed back the other way.
+// The author is part of the key so two people saying the same short thing, like gz, are both relayed
+type echoGuard struct {
+	mu        sync.Mutex
+	toTelnet  map[string]time.Time
+	toDiscord map[string]time.Time
+}
+
+func newEchoGuard() *echoGuard {
+	return &echoGuard{
+		toTelnet:  make(map[string]time.Time),
+		toDiscord: make(map[string]time.Time),
+	}
+}
+
+// fingerprint normalizes text so formatting added by routes, like case and punctuation, does not matter
+func fingerprint(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, text)
+}
+
+// toDiscordIsEcho returns true if text by author about to be relayed to discord was recently relayed to telnet, otherwise it is remembered
+func (e *echoGuard) toDiscordIsEcho(author string, text string) bool {
+	return e.check(e.toTelnet, e.toDiscord, author, text)
+}
+
+// toTelnetIsEcho returns true if text by author about to be relayed to telnet was recently relayed to discord, otherwise it is remembered
+func (e *echoGuard) toTelnetIsEcho(author string, text string) bool {
+	return e.check(e.toDiscord, e.toTelnet, author, text)
+}
+
+func (e *echoGuard) check(opposite map[string]time.Time, same map[string]time.Time, author string, text string) bool {
+	author = fingerprint(author)
+	text = fingerprint(text)
+	if author == "" || text == "" {
+		return false
+	}
+	key := author + ":" + text
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	for _, entries := range []map[string]time.Time{opposite, same} {
+		for fp, sent := range entries {
+			if now.Sub(sent) > echoWindow {
+				delete(entries, fp)
+			}
+		}
+	}
+	if _, ok := opposite[key]; ok {
+		return true
+	}
+	same[key] = now
+	return false
+}
@@ -0,0 +1,32 @@
+package client
+
+import "testing"
+
+func TestEchoGuard(t *testing.T) {
+	e := newEchoGuard()
+	if e.toTelnetIsEcho("Xackery", "Hello there!") {
+		t.Fatalf("first relay to telnet wanted not an echo")
+	}
+	if !e.toDiscordIsEcho("xackery", "hello there") {
+		t.Fatalf("same text back to discord wanted an echo")
+	}
+	if e.toDiscordIsEcho("Xackery", "something else") {
+		t.Fatalf("different text wanted not an echo")
+	}
+	if e.toDiscordIsEcho("Xackery", "") {
+		t.Fatalf("empty text wanted not an echo")
+	}
+}
+
+func TestEchoGuard_differentAuthors(t *testing.T) {
+	e := newEchoGuard()
+	if e.toDiscordIsEcho("Shin", "gz") {
+		t.Fatalf("gz from Shin to discord wanted not an echo")
+	}
+	if e.toTelnetIsEcho("Xackery", "gz") {
+		t.Fatalf("gz from Xackery to telnet wanted not an echo, it was said by someone else")
+	}
+	if e.toDiscordIsEcho("Bob", "gz") {
+		t.Fatalf("gz from Bob to discord wanted not an echo")
+	}
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xackery/talkeq/api"
+	"github.com/xackery/talkeq/discord"
+	"github.com/xackery/talkeq/eqlog"
+	"github.com/xackery/talkeq/peqeditorsql"
+	"github.com/xackery/talkeq/sqlreport"
+	"github.com/xackery/talkeq/telnet"
+)
+
+// Endpoint is a service talkeq connects to, to relay messages to and from
+type Endpoint interface {
+	Connect(ctx context.Context) error
+	IsConnected() bool
+	Disconnect(ctx context.Context) error
+	Subscribe(ctx context.Context, onMessage func(interface{}) error) error
+}
+
+var (
+	_ Endpoint = &discord.Discord{}
+	_ Endpoint = &telnet.Telnet{}
+	_ Endpoint = &sqlreport.SQLReport{}
+	_ Endpoint = &eqlog.EQLog{}
+	_ Endpoint = &peqeditorsql.PEQEditorSQL{}
+	_ Endpoint = &api.API{}
+)
+
+// endpoint is a registered Endpoint, with how the client manages it
+type endpoint struct {
+	Endpoint
+	name      string
+	isEnabled bool
+	// isSubscriber is true if the endpoint publishes requests to the client
+	isSubscriber bool
+	// isKeepAlive is true if the endpoint is reconnected by the keep alive loop
+	isKeepAlive bool
+	// mu serializes connecting and disconnecting, so keep alive and /reconnect never reconnect the endpoint at once
+	mu *sync.Mutex
+}
+
+// addEndpoint registers an endpoint. To bridge a new service, implement Endpoint and add it in New
+func (c *Client) addEndpoint(name string, e Endpoint, isEnabled bool, isSubscriber bool, isKeepAlive bool) {
+	c.endpoints = append(c.endpoints, endpoint{
+		Endpoint:     e,
+		name:         name,
+		isEnabled:    isEnabled,
+		isSubscriber: isSubscriber,
+		isKeepAlive:  isKeepAlive,
+		mu:           &sync.Mutex{},
+	})
+}
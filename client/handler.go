@@ -0,0 +1,107 @@
+package client
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/xackery/talkeq/auction"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// requestHandler relays one type of request published by an endpoint
+type requestHandler func(rawReq interface{}) (request.SendResult, error)
+
+// registerHandlers sets the handler of each request type. To relay a new request type, add it here
+func (c *Client) registerHandlers() {
+	c.handlers = map[reflect.Type]requestHandler{
+		reflect.TypeOf(request.APICommand{}): func(rawReq interface{}) (request.SendResult, error) {
+			return request.SendResult{}, c.api.Command(rawReq.(request.APICommand))
+		},
+		reflect.TypeOf(request.DiscordSend{}): func(rawReq interface{}) (request.SendResult, error) {
+			return c.handleDiscordSend(rawReq.(request.DiscordSend))
+		},
+		reflect.TypeOf(request.DiscordTyping{}): func(rawReq interface{}) (request.SendResult, error) {
+			return request.SendResult{}, c.discord.Typing(rawReq.(request.DiscordTyping))
+		},
+		reflect.TypeOf(request.DiscordTell{}): func(rawReq interface{}) (request.SendResult, error) {
+			return request.SendResult{}, c.discord.Tell(rawReq.(request.DiscordTell))
+		},
+		reflect.TypeOf(request.DiscordLFG{}): func(rawReq interface{}) (request.SendResult, error) {
+			return request.SendResult{}, c.discord.LFG(rawReq.(request.DiscordLFG))
+		},
+		reflect.TypeOf(request.RegisterCode{}): func(rawReq interface{}) (request.SendResult, error) {
+			return request.SendResult{}, c.api.ConfirmCode(rawReq.(request.RegisterCode))
+		},
+		reflect.TypeOf(request.RouteToggle{}): func(rawReq interface{}) (request.SendResult, error) {
+			req := rawReq.(request.RouteToggle)
+			count := c.telnet.SetRouteEnabled(req.ChannelID, req.IsEnabled)
+			count += c.eqlog.SetRouteEnabled(req.ChannelID, req.IsEnabled)
+			tlog.Infof("[talkeq] %d routes to channel %s set to enabled: %t", count, req.ChannelID, req.IsEnabled)
+			return request.SendResult{}, nil
+		},
+		reflect.TypeOf(request.Reconnect{}): func(rawReq interface{}) (request.SendResult, error) {
+			req := rawReq.(request.Reconnect)
+			if req.IsManual {
+				return request.SendResult{}, c.reconnectNow(req.Endpoint)
+			}
+			select {
+			case c.reconnect <- req.Endpoint:
+			default:
+				// a reconnect is already pending
+			}
+			return request.SendResult{}, nil
+		},
+		reflect.TypeOf(request.TelnetSend{}): func(rawReq interface{}) (request.SendResult, error) {
+			req := rawReq.(request.TelnetSend)
+			return c.relay("discord", req.FromName, req.Text, req.Message, c.echo.toTelnetIsEcho, func() (request.SendResult, error) {
+				return c.telnet.SendWithResult(req)
+			}, func() {
+				c.logChat("discord", req.DiscordChannelID, req.FromName, req.Message)
+			})
+		},
+	}
+}
+
+// handle relays a request published by an endpoint, and returns what was sent
+func (c *Client) handle(rawReq interface{}) (request.SendResult, error) {
+	handler, ok := c.handlers[reflect.TypeOf(rawReq)]
+	if !ok {
+		return request.SendResult{}, fmt.Errorf("unknown request type")
+	}
+	result, err := handler(rawReq)
+	if err != nil {
+		return result, fmt.Errorf("send: %w", err)
+	}
+	return result, nil
+}
+
+// handleDiscordSend relays a message to discord, sending auction messages as an embed when the route allows it
+func (c *Client) handleDiscordSend(req request.DiscordSend) (request.SendResult, error) {
+	return c.relay("telnet", req.FromName, req.Text, req.Message, c.echo.toDiscordIsEcho, func() (request.SendResult, error) {
+		if req.IsAuction && req.UseEmbed != "plain" && req.Embed == nil && auction.IsAuctionMessage(req.Text) {
+			listing := auction.Parse(req.FromName, req.Text)
+			embeds := listing.ToEmbeds()
+			req.Embed = embeds[0]
+			req.ExtraEmbeds = embeds[1:]
+		}
+		return c.discord.SendWithResult(req)
+	}, func() {
+		c.logChat("eq", req.ChannelID, req.FromName, req.Message)
+	})
+}
+
+// relay drops text that echoes a message recently relayed to the other side by the same author, otherwise sends it and logs it to the chat log.
+// to is the endpoint an echoed message was originally relayed to
+func (c *Client) relay(to string, author string, text string, message string, isEcho func(string, string) bool, send func() (request.SendResult, error), onSent func()) (request.SendResult, error) {
+	if text != "" && isEcho(author, text) {
+		tlog.Debugf("[talkeq] dropped echo of a message relayed to %s: %s", to, message)
+		return request.SendResult{}, nil
+	}
+	result, err := send()
+	if err != nil {
+		return result, err
+	}
+	onSent()
+	return result, nil
+}
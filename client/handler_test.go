@@ -0,0 +1,43 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/xackery/talkeq/request"
+)
+
+func TestClient_handle(t *testing.T) {
+	c := &Client{echo: newEchoGuard(), reconnect: make(chan string, 1)}
+	c.registerHandlers()
+
+	_, err := c.handle("not a request")
+	if err == nil {
+		t.Fatalf("handle() of unknown type wanted error")
+	}
+
+	c.echo.toTelnetIsEcho("Xackery", "hello there")
+	sent := false
+	result, err := c.relay("telnet", "Xackery", "hello there", "hello there", c.echo.toDiscordIsEcho, func() (request.SendResult, error) {
+		sent = true
+		return request.SendResult{}, nil
+	}, func() {})
+	if err != nil {
+		t.Fatalf("relay: %s", err)
+	}
+	if sent || result.MessageID != "" {
+		t.Fatalf("relay() sent an echo")
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err = c.handle(request.Reconnect{Endpoint: "telnet"})
+		if err != nil {
+			t.Fatalf("handle reconnect: %s", err)
+		}
+	}
+	if name := <-c.reconnect; name != "telnet" {
+		t.Fatalf("reconnect wanted telnet, got %s", name)
+	}
+	if len(c.reconnect) != 0 {
+		t.Fatalf("reconnect wanted pending requests merged, got %d more", len(c.reconnect))
+	}
+}
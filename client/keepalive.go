@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// keepAliveTracker counts failed reconnects of each endpoint, and which endpoints keep alive gave up on
+type keepAliveTracker struct {
+	mu       sync.Mutex
+	failures map[string]int
+	givenUp  map[string]bool
+}
+
+func newKeepAliveTracker() *keepAliveTracker {
+	return &keepAliveTracker{
+		failures: make(map[string]int),
+		givenUp:  make(map[string]bool),
+	}
+}
+
+// failed records a failed reconnect, and returns true if the endpoint reached maxRetries and is now given up on.
+// A maxRetries of 0 never gives up
+func (k *keepAliveTracker) failed(name string, maxRetries int) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.failures[name]++
+	if maxRetries < 1 || k.failures[name] < maxRetries {
+		return false
+	}
+	k.givenUp[name] = true
+	return true
+}
+
+// succeeded resets the failed reconnects of an endpoint
+func (k *keepAliveTracker) succeeded(name string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.failures, name)
+}
+
+// isGivenUp returns true if keep alive stopped reconnecting an endpoint
+func (k *keepAliveTracker) isGivenUp(name string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.givenUp[name]
+}
+
+// resume lets keep alive reconnect an endpoint it gave up on
+func (k *keepAliveTracker) resume(name string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.givenUp[name] {
+		tlog.Infof("[%s] reconnecting resumed", name)
+	}
+	delete(k.givenUp, name)
+	delete(k.failures, name)
+}
+
+// giveUp logs and announces that keep alive stopped reconnecting an endpoint
+func (c *Client) giveUp(ctx context.Context, name string) {
+	msg := fmt.Sprintf("giving up reconnecting to %s after %d failed attempts, use /reconnect %s to try again", name, c.config.KeepAliveMaxRetries, name)
+	tlog.Errorf("[%s] %s", name, msg)
+	if c.config.KeepAliveChannelID == "" || name == "discord" || !c.discord.IsConnected() {
+		return
+	}
+	err := c.discord.Send(request.DiscordSend{
+		Ctx:       ctx,
+		ChannelID: c.config.KeepAliveChannelID,
+		Message:   msg,
+	})
+	if err != nil {
+		tlog.Warnf("[discord] give up notice failed: %s", err)
+	}
+}
+
+// reconnectNow disconnects and connects an endpoint in the background, and lets keep alive retry it again if it gave up
+func (c *Client) reconnectNow(name string) error {
+	for _, e := range c.endpoints {
+		if e.name != name {
+			continue
+		}
+		if !e.isEnabled {
+			return fmt.Errorf("%s is not enabled", name)
+		}
+		c.keepAlive.resume(name)
+		// the endpoint may be the one that asked, so it is reconnected after the request returns
+		go func(e endpoint) {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			tlog.Infof("[%s] reconnecting by request", e.name)
+			// a cancelled ctx tells the endpoint this disconnect is intended, not a lost connection
+			ctx, cancel := context.WithCancel(c.ctx)
+			cancel()
+			err := e.Disconnect(ctx)
+			if err != nil {
+				tlog.Warnf("[%s] disconnect failed: %s", e.name, err)
+			}
+			err = e.Connect(c.ctx)
+			if err != nil {
+				tlog.Warnf("[%s] reconnect failed: %s", e.name, err)
+			}
+		}(e)
+		return nil
+	}
+	return fmt.Errorf("unknown endpoint %s", name)
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xackery/talkeq/config"
+)
+
+// fakeEndpoint fails the test if it is connected or disconnected by two goroutines at once
+type fakeEndpoint struct {
+	t           *testing.T
+	busy        int32
+	isConnected int32
+	disconnects int32
+	// disconnectErr is returned by Disconnect
+	disconnectErr error
+}
+
+func (f *fakeEndpoint) enter() {
+	if !atomic.CompareAndSwapInt32(&f.busy, 0, 1) {
+		f.t.Errorf("endpoint reconnected concurrently")
+	}
+	time.Sleep(time.Millisecond)
+}
+
+func (f *fakeEndpoint) Connect(ctx context.Context) error {
+	f.enter()
+	atomic.StoreInt32(&f.isConnected, 1)
+	atomic.StoreInt32(&f.busy, 0)
+	return nil
+}
+
+func (f *fakeEndpoint) Disconnect(ctx context.Context) error {
+	f.enter()
+	atomic.StoreInt32(&f.isConnected, 0)
+	atomic.AddInt32(&f.disconnects, 1)
+	atomic.StoreInt32(&f.busy, 0)
+	return f.disconnectErr
+}
+
+func (f *fakeEndpoint) IsConnected() bool {
+	return atomic.LoadInt32(&f.isConnected) == 1
+}
+
+func (f *fakeEndpoint) Subscribe(ctx context.Context, onMessage func(interface{}) error) error {
+	return nil
+}
+
+func TestKeepAliveTracker(t *testing.T) {
+	k := newKeepAliveTracker()
+	if k.failed("telnet", 0) || k.failed("telnet", 0) {
+		t.Fatalf("max retries 0 wanted to never give up")
+	}
+	k.succeeded("telnet")
+
+	if k.failed("telnet", 2) {
+		t.Fatalf("first failure wanted to keep retrying")
+	}
+	if !k.failed("telnet", 2) {
+		t.Fatalf("second failure wanted to give up")
+	}
+	if !k.isGivenUp("telnet") || k.isGivenUp("discord") {
+		t.Fatalf("only telnet wanted to be given up")
+	}
+	k.resume("telnet")
+	if k.isGivenUp("telnet") {
+		t.Fatalf("resume wanted telnet retried")
+	}
+	if k.failed("telnet", 2) {
+		t.Fatalf("resume wanted failures reset")
+	}
+}
+
+func TestClient_reconnectNow(t *testing.T) {
+	c := &Client{keepAlive: newKeepAliveTracker()}
+	c.addEndpoint("telnet", nil, false, true, true)
+	if err := c.reconnectNow("nats"); err == nil {
+		t.Fatalf("unknown endpoint wanted error")
+	}
+	if err := c.reconnectNow("telnet"); err == nil {
+		t.Fatalf("disabled endpoint wanted error")
+	}
+}
+
+func TestClient_reconnectNow_keepAlive(t *testing.T) {
+	f := &fakeEndpoint{t: t}
+	c := &Client{ctx: context.Background(), config: &config.Config{}, keepAlive: newKeepAliveTracker()}
+	c.addEndpoint("telnet", f, true, true, true)
+	for i := 0; i < 20; i++ {
+		if err := c.reconnectNow("telnet"); err != nil {
+			t.Fatalf("reconnectNow: %s", err)
+		}
+		c.keepAliveEndpoint(context.Background(), c.endpoints[0])
+	}
+	for atomic.LoadInt32(&f.disconnects) < 20 {
+		time.Sleep(time.Millisecond)
+	}
+	// the last reconnect holds the lock until it connected
+	c.endpoints[0].mu.Lock()
+	defer c.endpoints[0].mu.Unlock()
+}
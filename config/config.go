@@ -4,28 +4,45 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/jbsmith7741/toml"
 	"github.com/rs/zerolog"
+	"github.com/xackery/talkeq/tlog"
 )
 
 // Config represents a configuration parse
 type Config struct {
-	Debug                         bool      `toml:"debug" desc:"TalkEQ Configuration\n\n# Debug messages are displayed. This will cause console to be more verbose, but also more informative"`
-	IsKeepAliveEnabled            bool      `toml:"keep_alive" desc:"Keep all connections alive?\n# If false, endpoint disconnects will not self repair\n# Not recommended to turn off except in advanced cases"`
-	KeepAliveRetry                string    `toml:"keep_alive_retry" desc:"How long before retrying to connect (requires keep_alive = true)\n# default: 10s"`
-	IsFallbackGuildChannelEnabled bool      `toml:"is_fallback_guild_channel_enabled" desc:"If a guild chat occurs and it isn't mapped inside talkeq_guilds, chat is echod to the globalguild channel route channelid"`
-	UsersDatabasePath             string    `toml:"users_database" desc:"Users by ID are mapped to their display names via the raw text file called users database\n# If users database file does not exist, a new one is created\n# This file is actively monitored. if you edit it while talkeq is running, it will reload the changes instantly\n# This file overrides the IGN: playerName role tags in discord\n# If a user is not found on this list, it will fall back to check for IGN tags"`
-	GuildsDatabasePath            string    `toml:"guilds_database" desc:"Guilds by ID are mapped to their database ID via the raw text file called guilds database\n# If guilds database file does not exist, a new one is created\n# This file is actively monitored. if you edit it while talkeq is running, it will reload the changes instantly"`
-	API                           API       `toml:"api" desc:"NOT YET SUPPORTED, can be ignored for now (it's fine to keep enabled): API is a service to allow external tools to talk to TalkEQ via HTTP requests.\n# It uses Restful style (JSON) with a /api suffix for all endpoints"`
-	Discord                       Discord   `toml:"discord" desc:"Discord is a chat service that you can listen and relay EQ chat with"`
-	Telnet                        Telnet    `toml:"telnet" desc:"Telnet is a service eqemu/server can use, that relays messages over"`
-	EQLog                         EQLog     `toml:"eqlog" desc:"EQ Log is used to parse everquest client logs. Primarily for live EQ, non server owners"`
-	PEQEditor                     PEQEditor `toml:"peq_editor"`
-	SQLReport                     SQLReport `toml:"sql_report" desc:"SQL Report can be used to show stats on discord\n# An ideal way to set this up is create a private voice channel\n# Then bind it to various queries"`
+	Debug                         bool              `toml:"debug" desc:"TalkEQ Configuration\n\n# Debug messages are displayed. This will cause console to be more verbose, but also more informative\n# Only used if log_level is empty, log_level takes priority"`
+	LogLevel                      string            `toml:"log_level" desc:"Minimum level of messages to log: trace, debug, info, warn or error\n# If empty, debug = true is the same as log_level = \"debug\"\n# default: info"`
+	LogLevels                     map[string]string `toml:"log_levels" desc:"Override log_level for a component, based on the [component] prefix of a log line. e.g. telnet = \"debug\""`
+	ConfigVersion                 int               `toml:"config_version" desc:"Version of this config file, used to upgrade older configs with new options. Do not edit"`
+	IsKeepAliveEnabled            bool              `toml:"keep_alive" desc:"Keep all connections alive?\n# If false, endpoint disconnects will not self repair\n# Not recommended to turn off except in advanced cases"`
+	KeepAliveRetry                string            `toml:"keep_alive_retry" desc:"How long before retrying to connect (requires keep_alive = true)\n# default: 10s"`
+	KeepAliveMaxRetries           int               `toml:"keep_alive_max_retries,omitempty" desc:"Optional. Stop reconnecting an endpoint after this many failed attempts in a row, until an admin uses /reconnect. 0 retries forever"`
+	KeepAliveChannelID            string            `toml:"keep_alive_channel_id,omitempty" desc:"Optional. Discord channel ID to post to when talkeq gives up reconnecting an endpoint"`
+	IsFallbackGuildChannelEnabled bool              `toml:"is_fallback_guild_channel_enabled" desc:"If a guild chat occurs and it isn't mapped inside talkeq_guilds, chat is echod to the globalguild channel route channelid"`
+	UsersDatabasePath             string            `toml:"users_database" desc:"Users by ID are mapped to their display names via the raw text file called users database\n# If users database file does not exist, a new one is created\n# This file is actively monitored. if you edit it while talkeq is running, it will reload the changes instantly\n# This file overrides the IGN: playerName role tags in discord\n# If a user is not found on this list, it will fall back to check for IGN tags"`
+	Includes                      []string          `toml:"include,omitempty" desc:"Additional config files to merge into this one, relative to this file. e.g. [\"routes/server1.conf\"]"`
+	GuildsDatabasePath            string            `toml:"guilds_database" desc:"Guilds by ID are mapped to their database ID via the raw text file called guilds database\n# If guilds database file does not exist, a new one is created\n# This file is actively monitored. if you edit it while talkeq is running, it will reload the changes instantly"`
+	AuctionAliasesDatabasePath    string            `toml:"auction_aliases_database" desc:"Abbreviated item names in auctions are mapped to full item names via the raw text file called auction aliases database, as alias:item name\n# If auction aliases database file does not exist, a new one is created\n# This file is actively monitored. if you edit it while talkeq is running, it will reload the changes instantly"`
+	LastSeenDatabasePath          string            `toml:"last_seen_database" desc:"When characters were last seen in the telnet who list is saved to this file, used by returning player notifications"`
+	CharacterDatabaseMax          int               `toml:"character_database_max" desc:"Most characters kept in memory and in the last seen database. The least recently seen are removed first, and a warning is logged, as reaching it may mean who lines are being misread\n# default: 10000"`
+	API                           API               `toml:"api" desc:"NOT YET SUPPORTED, can be ignored for now (it's fine to keep enabled): API is a service to allow external tools to talk to TalkEQ via HTTP requests.\n# It uses Restful style (JSON) with a /api suffix for all endpoints"`
+	Discord                       Discord           `toml:"discord" desc:"Discord is a chat service that you can listen and relay EQ chat with"`
+	Telnet                        Telnet            `toml:"telnet" desc:"Telnet is a service eqemu/server can use, that relays messages over"`
+	EQLog                         EQLog             `toml:"eqlog" desc:"EQ Log is used to parse everquest client logs. Primarily for live EQ, non server owners"`
+	PEQEditor                     PEQEditor         `toml:"peq_editor"`
+	SQLReport                     SQLReport         `toml:"sql_report" desc:"SQL Report can be used to show stats on discord\n# An ideal way to set this up is create a private voice channel\n# Then bind it to various queries"`
+	ChatLog                       ChatLog           `toml:"chat_log" desc:"Chat log saves relayed messages to disk, to search with /search"`
+	AuctionHistory                AuctionHistory    `toml:"auction_history" desc:"Auction history saves the prices of auction listings on routes with auction_embed, to look up with /market"`
+	AuctionParsing                AuctionParsing    `toml:"auction_parsing" desc:"Auction parsing sets how auction messages are split into items and prices, to tune to your server's auction habits"`
+	AuctionDigest                 AuctionDigest     `toml:"auction_digest" desc:"Auction digest posts a periodic summary of auctions relayed on routes with auction_embed: listings by kind, and the most auctioned items with their price range"`
+	AnonymizeSecret               string            `toml:"anonymize_secret,omitempty" desc:"Optional, secret that routes with anonymize = \"hash\" key names with. If empty, a random secret is generated and kept in talkeq_anonymize_secret.txt beside this file\n# Keep it private and do not change it, or every hashed name changes"`
 }
 
 // Trigger is a regex pattern matching
@@ -84,10 +101,19 @@ func NewConfig(ctx context.Context) (*Config, error) {
 		os.Exit(0)
 	}
 
-	_, err = toml.DecodeReader(f, &cfg)
+	md, err := toml.DecodeReader(f, &cfg)
 	if err != nil {
 		return nil, fmt.Errorf("decode talkeq.conf: %w", err)
 	}
+	f.Close()
+
+	warnUndecoded(md)
+	if cfg.migrate() {
+		err = cfg.writeUpgrade(path)
+		if err != nil {
+			return nil, fmt.Errorf("upgrade talkeq.conf: %w", err)
+		}
+	}
 
 	/*fw, err := os.Create("talkeq2.toml")
 	if err != nil {
@@ -101,22 +127,69 @@ func NewConfig(ctx context.Context) (*Config, error) {
 		return nil, fmt.Errorf("encode: %w", err)
 	}*/
 
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	if cfg.Debug {
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	err = cfg.prepare(path, md)
+	if err != nil {
+		return nil, err
 	}
-	sort.SliceStable(cfg.SQLReport.Entries, func(i, j int) bool {
-		return cfg.SQLReport.Entries[i].Index > cfg.SQLReport.Entries[j].Index
-	})
 
-	err = cfg.Verify()
+	return &cfg, nil
+}
+
+// Check loads the config at path without creating or upgrading it, used to validate a config before deploying it
+func Check(path string) (*Config, error) {
+	cfg := Config{}
+	md, err := toml.DecodeFile(path, &cfg)
 	if err != nil {
-		return nil, fmt.Errorf("verify: %w", err)
+		return nil, fmt.Errorf("decode %s: %w", path, err)
 	}
+	warnUndecoded(md)
+	cfg.migrate()
 
+	err = cfg.prepare(path, md)
+	if err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
 
+// prepare merges includes, expands environment variables, then validates and verifies a decoded config.
+// md is the metadata from decoding path
+func (c *Config) prepare(path string, md toml.MetaData) error {
+	err := c.loadIncludes(path, md)
+	if err != nil {
+		return fmt.Errorf("include: %w", err)
+	}
+
+	err = c.applyLogLevel()
+	if err != nil {
+		return fmt.Errorf("log level: %w", err)
+	}
+	sort.SliceStable(c.SQLReport.Entries, func(i, j int) bool {
+		return c.SQLReport.Entries[i].Index > c.SQLReport.Entries[j].Index
+	})
+
+	err = c.ExpandEnv()
+	if err != nil {
+		return fmt.Errorf("expand env: %w", err)
+	}
+
+	err = c.applyAnonymizeSecret(path)
+	if err != nil {
+		return fmt.Errorf("anonymize secret: %w", err)
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	err = c.Verify()
+	if err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	return nil
+}
+
 // Verify returns an error if configuration appears off
 func (c *Config) Verify() error {
 
@@ -128,6 +201,18 @@ func (c *Config) Verify() error {
 		c.GuildsDatabasePath = "./guilds.txt"
 	}
 
+	if c.AuctionAliasesDatabasePath == "" {
+		c.AuctionAliasesDatabasePath = "talkeq_auction_aliases.txt"
+	}
+
+	if c.CharacterDatabaseMax < 1 {
+		c.CharacterDatabaseMax = 10000
+	}
+
+	if c.LastSeenDatabasePath == "" {
+		c.LastSeenDatabasePath = "talkeq_last_seen.toml"
+	}
+
 	if c.IsKeepAliveEnabled && c.KeepAliveRetryDuration().Seconds() < 2 {
 		c.KeepAliveRetry = "30s"
 	}
@@ -150,6 +235,83 @@ func (c *Config) Verify() error {
 	if err := c.Telnet.Verify(); err != nil {
 		return fmt.Errorf("telnet: %w", err)
 	}
+	if err := c.ChatLog.Verify(); err != nil {
+		return fmt.Errorf("chatlog: %w", err)
+	}
+	if err := c.AuctionHistory.Verify(); err != nil {
+		return fmt.Errorf("auction history: %w", err)
+	}
+	if err := c.AuctionParsing.Verify(); err != nil {
+		return fmt.Errorf("auction parsing: %w", err)
+	}
+	if err := c.AuctionDigest.Verify(); err != nil {
+		return fmt.Errorf("auction digest: %w", err)
+	}
+	return nil
+}
+
+// applyAnonymizeSecret keys the hashed names of every route with anonymize_secret.
+// If it is empty and a route hashes names, the secret is read from, or generated into, a file beside path
+func (c *Config) applyAnonymizeSecret(path string) error {
+	routes := []*Route{}
+	for i := range c.Telnet.Routes {
+		routes = append(routes, &c.Telnet.Routes[i])
+	}
+	for i := range c.EQLog.Routes {
+		routes = append(routes, &c.EQLog.Routes[i])
+	}
+	for i := range c.PEQEditor.SQL.Routes {
+		routes = append(routes, &c.PEQEditor.SQL.Routes[i])
+	}
+
+	isHashed := false
+	for _, route := range routes {
+		if route.Anonymize == "hash" {
+			isHashed = true
+			break
+		}
+	}
+	if c.AnonymizeSecret == "" && isHashed {
+		secret, err := loadAnonymizeSecret(filepath.Join(filepath.Dir(path), anonymizeSecretFile))
+		if err != nil {
+			return err
+		}
+		c.AnonymizeSecret = secret
+	}
+	for _, route := range routes {
+		route.anonymizeKey = []byte(c.AnonymizeSecret)
+	}
+	return nil
+}
+
+// LogLevelName returns the effective log level. log_level wins, debug = true is only used if log_level is empty
+func (c *Config) LogLevelName() string {
+	name := strings.ToLower(c.LogLevel)
+	if name != "" {
+		return name
+	}
+	if c.Debug {
+		return "debug"
+	}
+	return "info"
+}
+
+// applyLogLevel sets the log level of tlog and zerolog
+func (c *Config) applyLogLevel() error {
+	name := c.LogLevelName()
+	err := tlog.SetLevel(name)
+	if err != nil {
+		return err
+	}
+	err = tlog.SetComponentLevels(c.LogLevels)
+	if err != nil {
+		return fmt.Errorf("log_levels: %w", err)
+	}
+	level, err := zerolog.ParseLevel(name)
+	if err != nil {
+		return fmt.Errorf("parse: %w", err)
+	}
+	zerolog.SetGlobalLevel(level)
 	return nil
 }
 
@@ -166,14 +328,66 @@ func (c *Config) KeepAliveRetryDuration() time.Duration {
 	return retryDuration
 }
 
+// defaultEQLogChannelRoutes returns disabled eqlog routes for say, group and raid chat
+func defaultEQLogChannelRoutes() []Route {
+	return []Route{
+		{
+			Trigger: Trigger{
+				Regex:        `(\w+) says, '(.*)'`,
+				NameIndex:    1,
+				MessageIndex: 2,
+			},
+			Target:         "discord",
+			ChannelID:      "INSERTSAYCHANNELHERE",
+			MessagePattern: "{{.Name}} **SAY**: {{.Message}}",
+		},
+		{
+			Trigger: Trigger{
+				Regex:        `(\w+) tells the group, '(.*)'`,
+				NameIndex:    1,
+				MessageIndex: 2,
+			},
+			Target:         "discord",
+			ChannelID:      "INSERTGROUPCHANNELHERE",
+			MessagePattern: "{{.Name}} **GROUP**: {{.Message}}",
+		},
+		{
+			Trigger: Trigger{
+				Regex:        `(\w+) tells the raid, +'(.*)'`,
+				NameIndex:    1,
+				MessageIndex: 2,
+			},
+			Target:         "discord",
+			ChannelID:      "INSERTRAIDCHANNELHERE",
+			MessagePattern: "{{.Name}} **RAID**: {{.Message}}",
+		},
+	}
+}
+
 func getDefaultConfig() Config {
 	cfg := Config{
-		Debug:              true,
-		IsKeepAliveEnabled: true,
-		KeepAliveRetry:     "10s",
-		UsersDatabasePath:  "talkeq_users.txt",
-		GuildsDatabasePath: "talkeq_guilds.txt",
+		Debug:                false,
+		LogLevel:             "info",
+		LogLevels:            map[string]string{},
+		ConfigVersion:        currentConfigVersion,
+		IsKeepAliveEnabled:   true,
+		KeepAliveRetry:       "10s",
+		UsersDatabasePath:    "talkeq_users.txt",
+		GuildsDatabasePath:   "talkeq_guilds.txt",
+		LastSeenDatabasePath: "talkeq_last_seen.toml",
+		CharacterDatabaseMax: 10000,
+
+		AuctionAliasesDatabasePath: "talkeq_auction_aliases.txt",
 	}
+	cfg.ChatLog.Path = "chatlog"
+	cfg.ChatLog.RetentionDays = 30
+	cfg.AuctionHistory.Path = "talkeq_auction_history.jsonl"
+	cfg.AuctionHistory.LookbackDays = 14
+	cfg.AuctionParsing.SeparatorPattern = defaultSeparatorPattern
+	cfg.AuctionParsing.PricePattern = defaultPricePattern
+	cfg.AuctionDigest.Interval = 10
+	cfg.AuctionDigest.TopItems = 5
+
 	cfg.API.IsEnabled = true
 	cfg.API.Host = ":9933"
 	cfg.API.APIRegister.IsEnabled = true
@@ -181,18 +395,118 @@ func getDefaultConfig() Config {
 
 	cfg.Discord.IsEnabled = true
 	cfg.Discord.BotStatus = "EQ: {{.PlayerCount}} Online"
+	cfg.Discord.BotStatusOffline = "EQ: Server Offline"
+	cfg.Discord.OnlineCountName = "Online: {{.PlayerCount}}"
+	cfg.Discord.MaxMessageLength = 400
+	cfg.Discord.TellDMCooldown = 5
+	cfg.Discord.AuditLogPath = "talkeq_audit.log"
+	cfg.Discord.AuditLogMaxSize = 1024
+	cfg.Discord.CommandCooldowns = map[string]int{
+		"who": 10,
+	}
+	cfg.Discord.CommandEphemeral = map[string]bool{
+		"who":    false,
+		"bridge": true,
+		"config": true,
+		"search": true,
+		"market": true,
+		"tells":  true,
+	}
+	cfg.Discord.ChannelNumbers = defaultChannelNumbers()
 	cfg.Discord.Routes = append(cfg.Discord.Routes, DiscordRoute{
 		IsEnabled: true,
 		Trigger: DiscordTrigger{
 			ChannelID: "INSERTOOCCHANNELHERE",
 		},
 		Target:         "telnet",
-		ChannelID:      "260",
+		ChannelID:      "ooc",
 		MessagePattern: "emote world {{.ChannelID}} {{.Name}} says from discord, '{{.Message}}'",
 	})
+	cfg.Discord.Moderation = append(cfg.Discord.Moderation, DiscordModeration{
+		Emoji:          "🚫",
+		MessagePattern: "kick {{.Name}}",
+	})
 
 	cfg.Telnet.IsEnabled = true
 	cfg.Telnet.Host = "127.0.0.1:9000"
+	cfg.Telnet.WhoFormat = "auto"
+	cfg.Telnet.WhoDumpWindow = 2
+	cfg.Telnet.Heartbeat = TelnetHeartbeat{
+		Interval: 60,
+		Command:  "echo off",
+	}
+	cfg.Telnet.ZoneChange = PlayerNotification{
+		ChannelID:      "INSERTZONECHANGECHANNELHERE",
+		MessagePattern: "{{.Name}} entered {{.Zone}}",
+	}
+	cfg.Telnet.LevelUp = PlayerNotification{
+		ChannelID:      "INSERTLEVELUPCHANNELHERE",
+		MessagePattern: "🎉 {{.Name}} dinged {{.Level}}!",
+	}
+	cfg.Telnet.ReturningPlayer = PlayerNotification{
+		ChannelID:      "INSERTRETURNINGPLAYERCHANNELHERE",
+		MessagePattern: "👋 Welcome back {{.Name}}! Last seen {{.DaysAway}} days ago",
+	}
+	cfg.Telnet.ReturningPlayerDays = 7
+	cfg.Telnet.Deaths = TelnetDeaths{
+		ChannelID:      "INSERTDEATHSCHANNELHERE",
+		Regex:          `^(\w+) (?:has been|was) slain by (.+) in (.+?)[.!]?$`,
+		NameIndex:      1,
+		KillerIndex:    2,
+		ZoneIndex:      3,
+		MessagePattern: "💀 {{.Name}} was slain by {{.Killer}} in {{.Zone}}",
+		Color:          0x8B0000,
+	}
+	cfg.Telnet.SpawnAlert = TelnetSpawnAlert{
+		ChannelID:      "INSERTSPAWNALERTCHANNELHERE",
+		Regex:          `^(.+) has spawned in (.+?)[.!]?$`,
+		MobIndex:       1,
+		ZoneIndex:      2,
+		Mobs:           []string{},
+		Cooldown:       300,
+		MessagePattern: "⚔️ {{.Mob}} spawned in {{.Zone}}",
+		Color:          0xFFA500,
+	}
+	cfg.Telnet.Loot = TelnetLoot{
+		ChannelID:      "INSERTLOOTCHANNELHERE",
+		Regex:          `(\w+) has looted (.+) from (.+?)[.!]?$`,
+		NameIndex:      1,
+		ItemIndex:      2,
+		MobIndex:       3,
+		Rarities:       []string{},
+		MessagePattern: "💰 {{.Name}} looted {{.Item}} from {{.Mob}}",
+		Color:          0xA020F0,
+	}
+	cfg.Telnet.LFG = TelnetLFG{
+		ChannelID:     "INSERTLFGCHANNELHERE",
+		Regex:         `(\w+) says ooc, '(.*)'`,
+		NameIndex:     1,
+		MessageIndex:  2,
+		ExpireMinutes: 30,
+		Color:         0x3498DB,
+	}
+	cfg.Telnet.Commands = TelnetCommands{
+		Prefix:       "!",
+		Regex:        `(\w+) says ooc, '(.*)'`,
+		NameIndex:    1,
+		MessageIndex: 2,
+		ReplyPattern: "tell {{.Name}} {{.Message}}",
+		Cooldowns: map[string]int{
+			"who":    10,
+			"uptime": 10,
+		},
+	}
+	cfg.Telnet.RegisterCode = TelnetRegisterCode{
+		Regex:        `(\w+) says ooc, '(.*)'`,
+		NameIndex:    1,
+		MessageIndex: 2,
+	}
+	cfg.Telnet.TellDM = TelnetTellDM{
+		Regex:        `(\w+) tells (\w+), '(.*)'`,
+		FromIndex:    1,
+		ToIndex:      2,
+		MessageIndex: 3,
+	}
 	cfg.Telnet.ItemURL = "http://everquest.allakhazam.com/db/item.html?item="
 	cfg.Telnet.IsServerAnnounceEnabled = true
 	cfg.Telnet.IsOOCAuctionEnabled = true
@@ -218,6 +532,7 @@ func getDefaultConfig() Config {
 		Target:         "discord",
 		ChannelID:      "INSERTAUCTIONCHANNELHERE",
 		MessagePattern: "{{.Name}} **auction**: {{.Message}}",
+		IsAuctionEmbed: true,
 	})
 
 	cfg.Telnet.Routes = append(cfg.Telnet.Routes, Route{
@@ -277,6 +592,7 @@ func getDefaultConfig() Config {
 	})
 
 	cfg.EQLog.Path = `c:\Program Files\Everquest\Logs\eqlog_CharacterName_Server.txt`
+	cfg.EQLog.SelfNames = []string{"You"}
 	cfg.EQLog.Routes = append(cfg.EQLog.Routes, Route{
 		IsEnabled: true,
 		Trigger: Trigger{
@@ -298,6 +614,7 @@ func getDefaultConfig() Config {
 		Target:         "discord",
 		ChannelID:      "INSERTAUCTIONCHANNELHERE",
 		MessagePattern: "{{.Name}} **AUCTION**: {{.Message}}",
+		IsAuctionEmbed: true,
 	})
 	cfg.EQLog.Routes = append(cfg.EQLog.Routes, Route{
 		IsEnabled: true,
@@ -334,6 +651,8 @@ func getDefaultConfig() Config {
 		MessagePattern: "{{.Name}} **OOC**: {{.Message}}",
 	})
 
+	cfg.EQLog.Routes = append(cfg.EQLog.Routes, defaultEQLogChannelRoutes()...)
+
 	cfg.PEQEditor.SQL.Path = "/var/www/peq/peqphpeditor/logs"
 	cfg.PEQEditor.SQL.FilePattern = "sql_log_{{.Month}}-{{.Year}}.sql"
 
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"strings"
 
 	"github.com/xackery/talkeq/tlog"
 )
@@ -10,7 +12,11 @@ import (
 type API struct {
 	IsEnabled   bool        `toml:"enabled" desc:"Enable API service"`
 	Host        string      `toml:"host" desc:"What address and port to bind to (default is 127.0.0.1, so only local traffic can talk to it)"`
+	Token       string      `toml:"token,omitempty" desc:"Optional, POST endpoints like /api/who require the header Authorization: Bearer <token>"`
+	AllowedIPs  []string    `toml:"allowed_ips,omitempty" desc:"Optional, only requests from these IPs or CIDR ranges are answered, others get 403. e.g. [\"127.0.0.1\", \"10.0.0.0/8\"]"`
+	IsDebug     bool        `toml:"debug,omitempty" desc:"Optional, enable POST /api/debug/telnet-line to run a sample line through telnet routes as if the server sent it. Only answered from localhost"`
 	APIRegister APIRegister `toml:"register" desc:"!register command"`
+	allowedNets []*net.IPNet
 }
 
 // APIRegister is used for Register command management
@@ -36,5 +42,42 @@ func (c *API) Verify() error {
 		c.Host = "127.0.0.1:9933"
 	}
 
+	c.allowedNets = nil
+	for _, value := range c.AllowedIPs {
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return fmt.Errorf("allowed_ips: %q is not an IP or CIDR range", value)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			c.allowedNets = append(c.allowedNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return fmt.Errorf("allowed_ips: %w", err)
+		}
+		c.allowedNets = append(c.allowedNets, ipNet)
+	}
+
 	return nil
 }
+
+// IsAllowedIP returns true if no allowed_ips are set, or ip is in one of them
+func (c *API) IsAllowedIP(ip net.IP) bool {
+	if len(c.allowedNets) == 0 {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range c.allowedNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package config
+
+import "time"
+
+// AuctionHistory represents config settings for saving parsed auction listings
+type AuctionHistory struct {
+	IsEnabled    bool   `toml:"enabled" desc:"Enable saving prices of parsed auction listings, used by /market"`
+	Path         string `toml:"path" desc:"File the auction history is written to, as JSON lines\n# default: talkeq_auction_history.jsonl"`
+	LookbackDays int    `toml:"lookback_days" desc:"Days of auction history kept and used for /market prices, older listings are dropped\n# default: 14"`
+}
+
+// Verify checks if config looks valid
+func (c *AuctionHistory) Verify() error {
+	if !c.IsEnabled {
+		return nil
+	}
+	if c.Path == "" {
+		c.Path = "talkeq_auction_history.jsonl"
+	}
+	if c.LookbackDays < 1 {
+		c.LookbackDays = 14
+	}
+	return nil
+}
+
+// AuctionParsing represents config settings for splitting auction messages into items and prices
+type AuctionParsing struct {
+	SeparatorPattern string `toml:"separator_pattern" desc:"Regex that splits an auction message into items\n# default: \\s*(?:,|/|\\||;|\\s-\\s)\\s*"`
+	PricePattern     string `toml:"price_pattern" desc:"Regex that finds an item's price. The first group is the amount, the second the unit, where a unit of k is thousands\n# default: (?i)(\\d+(?:\\.\\d+)?)\\s*(k|pp|p|plat)\\b"`
+}
+
+// Verify checks if config looks valid
+func (c *AuctionParsing) Verify() error {
+	if c.SeparatorPattern == "" {
+		c.SeparatorPattern = defaultSeparatorPattern
+	}
+	if c.PricePattern == "" {
+		c.PricePattern = defaultPricePattern
+	}
+	return nil
+}
+
+const (
+	defaultSeparatorPattern = `\s*(?:,|/|\||;|\s-\s)\s*`
+	defaultPricePattern     = `(?i)(\d+(?:\.\d+)?)\s*(k|pp|p|plat)\b`
+)
+
+// AuctionDigest represents config settings for a periodic summary of auction activity
+type AuctionDigest struct {
+	IsEnabled bool   `toml:"enabled" desc:"Enable posting a summary of auction activity to discord every interval"`
+	ChannelID string `toml:"channel_id" desc:"Discord channel ID the digest is posted to"`
+	Interval  int    `toml:"interval" desc:"Minutes between digests, a digest is skipped when there were no auctions\n# default: 10"`
+	TopItems  int    `toml:"top_items" desc:"How many of the most auctioned items are listed\n# default: 5"`
+}
+
+// Verify checks if config looks valid
+func (c *AuctionDigest) Verify() error {
+	if c.Interval < 1 {
+		c.Interval = 10
+	}
+	if c.TopItems < 1 {
+		c.TopItems = 5
+	}
+	return nil
+}
+
+// IntervalDuration returns the interval as a duration
+func (c *AuctionDigest) IntervalDuration() time.Duration {
+	return time.Duration(c.Interval) * time.Minute
+}
@@ -0,0 +1,22 @@
+package config
+
+// ChatLog represents config settings for the relayed chat log
+type ChatLog struct {
+	IsEnabled     bool   `toml:"enabled" desc:"Enable saving every relayed message to a daily JSON lines file, searchable with /search"`
+	Path          string `toml:"path" desc:"Directory the daily chat log files are written to\n# default: chatlog"`
+	RetentionDays int    `toml:"retention_days" desc:"Days of chat log files to keep, older files are deleted when a new day starts. 0 keeps every file\n# default: 30"`
+}
+
+// Verify checks if config looks valid
+func (c *ChatLog) Verify() error {
+	if !c.IsEnabled {
+		return nil
+	}
+	if c.Path == "" {
+		c.Path = "chatlog"
+	}
+	if c.RetentionDays < 0 {
+		c.RetentionDays = 0
+	}
+	return nil
+}
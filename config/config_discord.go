@@ -2,18 +2,53 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"text/template"
 )
 
 // Discord represents config settings for discord
 type Discord struct {
-	IsEnabled       bool           `toml:"enabled" desc:"Enable Discord"`
-	Token           string         `toml:"bot_token" desc:"Required. Found at https://discordapp.com/developers/ under your app's bot token area."`
-	ServerID        string         `toml:"server_id" desc:"Required. In Discord, right click the circle button representing your server, and Copy ID, and paste it here."`
-	ClientID        string         `toml:"client_id" desc:"Required. Found at https://discordapp.com/developers/ under your app's general information page, called Application ID"`
-	BotStatus       string         `toml:"bot_status" desc:"Status to show below bot. e.g. \"Playing EQ: 123 Online\"\n# {{.PlayerCount}} to show playercount"`
-	CommandChannels []string       `toml:"command_channels" desc:"Commands are parsed in provided channel ids"`
-	Routes          []DiscordRoute `toml:"routes" desc:"When a message is created in discord, how to route it"`
+	IsEnabled               bool                `toml:"enabled" desc:"Enable Discord"`
+	Token                   string              `toml:"bot_token" desc:"Required. Found at https://discordapp.com/developers/ under your app's bot token area."`
+	ServerID                string              `toml:"server_id" desc:"Required. In Discord, right click the circle button representing your server, and Copy ID, and paste it here."`
+	ServerIDs               []string            `toml:"server_ids,omitempty" desc:"Optional. Additional server IDs the bot is in and bridges the same EQ server to. Routes can use channel_ids to relay to a channel in each server"`
+	ClientID                string              `toml:"client_id" desc:"Required. Found at https://discordapp.com/developers/ under your app's general information page, called Application ID"`
+	BotStatus               string              `toml:"bot_status" desc:"Status to show below bot. e.g. \"Playing EQ: 123 Online\"\n# {{.PlayerCount}} to show playercount"`
+	BotStatusOffline        string              `toml:"bot_status_offline" desc:"Status to show below bot while telnet is not connected to the server\n# default: EQ: Server Offline"`
+	OnlineCountChannelID    string              `toml:"online_count_channel_id" desc:"Optional. Voice channel ID to rename with how many players are online, updated every minute"`
+	OnlineCountName         string              `toml:"online_count_name" desc:"Name of the online count channel. {{.PlayerCount}} to show playercount\n# default: Online: {{.PlayerCount}}"`
+	IsCommandsEnabled       bool                `toml:"commands_enabled" desc:"Register slash commands (e.g. /who, /bridge) with discord when connecting"`
+	IsCommandsGlobal        bool                `toml:"commands_global,omitempty" desc:"Optional. Register slash commands globally for every server the bot is in, instead of only server_id. Global commands can take up to an hour to appear after changes"`
+	IsCommandCleanupEnabled bool                `toml:"commands_cleanup,omitempty" desc:"Optional. Delete talkeq's slash commands from discord when disconnecting"`
+	IsWhoAnonForAdmins      bool                `toml:"who_anon_for_admins,omitempty" desc:"Optional. Adds an anon option to /who that lets admins see ANON and RolePlay players, other users still cannot"`
+	IsAFKEnabled            bool                `toml:"afk_enabled,omitempty" desc:"Optional. Adds /afk, letting registered users set an away message that answers in game tells to their character. Requires [telnet.tell_dm] to be enabled"`
+	IsEditRelayEnabled      bool                `toml:"edit_relay,omitempty" desc:"Optional. When a message relayed in game is edited or deleted in discord, relay the edit or deletion in game through the same routes, e.g. Xackery says, 'edited: hello' or Xackery says, 'deleted a message'"`
+	CommandCooldowns        map[string]int      `toml:"command_cooldowns" desc:"Seconds a user must wait before using a command again. e.g. who = 10"`
+	EmbedFooter             string              `toml:"embed_footer,omitempty" desc:"Optional. Footer text shown on every embed talkeq posts, e.g. your server name"`
+	EmbedFooterIcon         string              `toml:"embed_footer_icon,omitempty" desc:"Optional. URL of an image shown next to the embed footer, e.g. your server logo"`
+	IsEmbedTimestampEnabled bool                `toml:"embed_timestamp,omitempty" desc:"Optional. Show the time posted on every embed talkeq posts"`
+	ClassIcons              map[string]string   `toml:"class_icons,omitempty" desc:"Optional. Emoji shown before player names in /who, by class. Classes without an icon show the class name\n# e.g. Enchanter = \":crystal_ball:\" or a custom emoji like \"<:enc:1234>\""`
+	BridgeTag               string              `toml:"bridge_tag,omitempty" desc:"Optional. Prefix added to messages relayed from discord in game, so players know where they came from\n# e.g. [Discord]"`
+	ChannelNumbers          map[string]int      `toml:"channel_numbers" desc:"EQ channel numbers by name, so telnet routes can set channel_id = \"ooc\". Numbers differ between EQEmu versions, see MT_ values in https://docs.eqemu.io/server/operation/chat-channel-types/\n# default: guild = 259, ooc = 260, auction = 261, shout = 262"`
+	CommandEphemeral        map[string]bool     `toml:"command_ephemeral" desc:"If a command's response is only visible to the user who ran it. Commands not listed are only visible to the user\n# e.g. who = false to show /who results to the whole channel"`
+	AuditLogPath            string              `toml:"audit_log" desc:"Optional. File to record who ran which command or moderation action. e.g. talkeq_audit.log"`
+	AuditLogMaxSize         int                 `toml:"audit_log_max_size" desc:"Size in KB before the audit log is rotated to a .1 file\n# default: 1024"`
+	AuditChannelID          string              `toml:"audit_channel_id" desc:"Optional. Discord channel ID to also post audit entries to"`
+	TellDMCooldown          int                 `toml:"tell_dm_cooldown" desc:"Seconds between in game tells relayed as DMs to the same user, tells in between are dropped\n# default: 5"`
+	CommandChannels         []string            `toml:"command_channels" desc:"Commands are parsed in provided channel ids"`
+	MaxMessageLength        int                 `toml:"max_message_length" desc:"Maximum length of a discord message relayed in game, including the route's message pattern and bridge tag. Longer messages are split into multiple lines with a (1/3) style marker\n# default: 400"`
+	Routes                  []DiscordRoute      `toml:"routes" desc:"When a message is created in discord, how to route it"`
+	AdminRoles              []string            `toml:"admin_roles" desc:"Discord role IDs that are allowed to moderate and use admin commands"`
+	Moderation              []DiscordModeration `toml:"moderation" desc:"When an admin reacts to a relayed EQ message with provided emoji, a telnet command is issued against the original sender"`
+}
+
+// DiscordModeration maps a reaction on a relayed message to a telnet command
+type DiscordModeration struct {
+	IsEnabled              bool   `toml:"enabled" desc:"Is moderation reaction enabled?"`
+	Emoji                  string `toml:"emoji" desc:"Emoji to react with, e.g. 🚫"`
+	MessagePattern         string `toml:"message_pattern" desc:"Telnet command to issue. {{.Name}} is the original EQ sender. E.g. kick {{.Name}}"`
+	messagePatternTemplate *template.Template
 }
 
 // DiscordRoute is custom for discord triggering
@@ -21,10 +56,11 @@ type DiscordRoute struct {
 	IsEnabled              bool           `toml:"enabled" desc:"Is route enabled?"`
 	Trigger                DiscordTrigger `toml:"discord_trigger" desc:"condition to trigger route"`
 	Target                 string         `toml:"target" desc:"target service, examples: telnet, discord"`
-	ChannelID              string         `toml:"channel_id" desc:"Destination channel ID, For telnet->ooc, set to 260. More values have MT_ prefix in this link: https://docs.eqemu.io/server/operation/chat-channel-types/"`
+	ChannelID              string         `toml:"channel_id" desc:"Destination channel ID, For telnet, a name from channel_numbers like ooc, or a channel number like 260. More values have MT_ prefix in this link: https://docs.eqemu.io/server/operation/chat-channel-types/"`
 	GuildID                string         `toml:"guild_id,omitempty" desc:"Optional, and likely not needed to be set since guilddb file is better, destination guild ID to relay the discord message to"`
 	MessagePattern         string         `toml:"message_pattern" desc:"Destination message in. E.g. {{.Name}} says {{.ChannelName}}, '{{.Message}}"`
 	messagePatternTemplate *template.Template
+	stats                  *RouteStats
 	IsAnyoneAllowed        bool `toml:"is_anyone_allowed" desc:"Can anyone use this route? E.g., instead of IGN or a users.txt, anyone given access to provided channel will be able to relay in game using their discord name."`
 }
 
@@ -33,12 +69,67 @@ type DiscordTrigger struct {
 	ChannelID string `toml:"channel_id" desc:"source channel ID to trigger event"`
 }
 
+// defaultChannelNumbers returns the EQ channel numbers used by EQEmu
+func defaultChannelNumbers() map[string]int {
+	return map[string]int{
+		"guild":   259,
+		"ooc":     260,
+		"auction": 261,
+		"shout":   262,
+	}
+}
+
+// ChannelNumber returns the EQ channel number of a channel_numbers name, or channelID unchanged if it is not a name
+func (c *Discord) ChannelNumber(channelID string) string {
+	for name, number := range c.ChannelNumbers {
+		if strings.EqualFold(name, channelID) {
+			return strconv.Itoa(number)
+		}
+	}
+	return channelID
+}
+
+// Servers returns server_id followed by any server_ids, without duplicates
+func (c *Discord) Servers() []string {
+	servers := []string{}
+	seen := map[string]bool{}
+	for _, serverID := range append([]string{c.ServerID}, c.ServerIDs...) {
+		serverID = strings.TrimSpace(serverID)
+		if serverID == "" || seen[serverID] {
+			continue
+		}
+		seen[serverID] = true
+		servers = append(servers, serverID)
+	}
+	return servers
+}
+
 // Verify checks if config looks valid
 func (c *Discord) Verify() error {
 	if !c.IsEnabled {
 		return nil
 	}
 
+	if c.BotStatusOffline == "" {
+		c.BotStatusOffline = "EQ: Server Offline"
+	}
+
+	if c.AuditLogMaxSize < 1 {
+		c.AuditLogMaxSize = 1024
+	}
+
+	if c.TellDMCooldown < 1 {
+		c.TellDMCooldown = 5
+	}
+
+	if c.OnlineCountName == "" {
+		c.OnlineCountName = "Online: {{.PlayerCount}}"
+	}
+
+	if c.MaxMessageLength < 1 {
+		c.MaxMessageLength = 400
+	}
+
 	for i := range c.Routes {
 		if c.Routes[i].ChannelID == "" {
 			return fmt.Errorf("route %d: invalid channel id", i)
@@ -48,6 +139,19 @@ func (c *Discord) Verify() error {
 			return fmt.Errorf("route %d: %w", i, err)
 		}
 	}
+
+	for i := range c.Moderation {
+		if !c.Moderation[i].IsEnabled {
+			continue
+		}
+		if c.Moderation[i].Emoji == "" {
+			return fmt.Errorf("moderation %d: emoji cannot be empty", i)
+		}
+		err := c.Moderation[i].LoadMessagePattern()
+		if err != nil {
+			return fmt.Errorf("moderation %d: %w", i, err)
+		}
+	}
 	return nil
 }
 
@@ -56,8 +160,31 @@ func (r *DiscordRoute) MessagePatternTemplate() *template.Template {
 	return r.messagePatternTemplate
 }
 
+// Stats returns the match stats of the route
+func (r *DiscordRoute) Stats() *RouteStats {
+	return r.stats
+}
+
 // LoadMessagePattern is called after config is loaded, and verified patterns are valid
 func (r *DiscordRoute) LoadMessagePattern() error {
+	if r.stats == nil {
+		r.stats = &RouteStats{}
+	}
+	var err error
+	r.messagePatternTemplate, err = template.New("root").Parse(r.MessagePattern)
+	if err != nil {
+		return fmt.Errorf("failed to parse: %w", err)
+	}
+	return nil
+}
+
+// MessagePatternTemplate returns a template for provided moderation
+func (r *DiscordModeration) MessagePatternTemplate() *template.Template {
+	return r.messagePatternTemplate
+}
+
+// LoadMessagePattern is called after config is loaded, and verified patterns are valid
+func (r *DiscordModeration) LoadMessagePattern() error {
 	var err error
 	r.messagePatternTemplate, err = template.New("root").Parse(r.MessagePattern)
 	if err != nil {
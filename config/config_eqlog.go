@@ -1,13 +1,28 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // EQLog represents config settings for the EQ live eqlog file
 type EQLog struct {
-	IsEnabled                   bool    `toml:"enabled"`
-	Path                        string  `toml:"path"`
-	Routes                      []Route `toml:"routes" desc:"Routes from EQLog to other services"`
-	IsGeneralChatAuctionEnabled bool    `toml:"convert_general_auction" desc:"convert WTS and WTB messages in general chat to auction channel"`
+	IsEnabled                   bool      `toml:"enabled"`
+	Path                        string    `toml:"path"`
+	Routes                      []Route   `toml:"routes" desc:"Routes from EQLog to other services"`
+	Unmatched                   Unmatched `toml:"unmatched" desc:"Optional. Relay log lines that matched no enabled route, to help write new triggers"`
+	SelfNames                   []string  `toml:"self_names" desc:"Names of the character(s) whose log is read. Lines authored by these names are not relayed, so messages relayed in game do not echo back\n# e.g. [\"You\", \"Xackery\"]"`
+	IsGeneralChatAuctionEnabled bool      `toml:"convert_general_auction" desc:"convert WTS and WTB messages in general chat to auction channel"`
+}
+
+// IsSelf returns true if name is one of self_names
+func (c *EQLog) IsSelf(name string) bool {
+	for _, self := range c.SelfNames {
+		if strings.EqualFold(self, name) {
+			return true
+		}
+	}
+	return false
 }
 
 // Verify checks if config looks valid
@@ -16,7 +31,7 @@ func (c *EQLog) Verify() error {
 		return nil
 	}
 	for i := range c.Routes {
-		if c.Routes[i].ChannelID == "" {
+		if c.Routes[i].ChannelID == "" && len(c.Routes[i].ChannelIDs) == 0 {
 			return fmt.Errorf("route %d: invalid channel id", i)
 		}
 		err := c.Routes[i].LoadMessagePattern()
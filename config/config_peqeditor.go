@@ -29,7 +29,7 @@ func (c *PEQEditor) Verify() error {
 			return fmt.Errorf("sql: file pattern is empty")
 		}
 		for i := range c.SQL.Routes {
-			if c.SQL.Routes[i].ChannelID == "" {
+			if c.SQL.Routes[i].ChannelID == "" && len(c.SQL.Routes[i].ChannelIDs) == 0 {
 				return fmt.Errorf("route %d: invalid channel id", i)
 			}
 			err := c.SQL.Routes[i].LoadMessagePattern()
@@ -2,25 +2,247 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"text/template"
+	"time"
 )
 
 // Telnet represents config settings for telnet
 type Telnet struct {
-	IsEnabled               bool    `toml:"enabled" desc:"Enable Telnet"`
-	IsLegacy                bool    `toml:"legacy" desc:"EQEMU servers that run 0.8.0 versions need this set to true for item link support, everyone running any newer versions can leave it default (false)"`
-	LinkChunk1Size          int     `toml:"link_chunk1_size" desc:"Size of item links. Can leave at 0, will dynamically detect, Secrets custom is 9. but RoF2 is 6. Titanium is 6. Left for super custom servers."`
-	LinkChunk2Size          int     `toml:"link_chunk2_size" desc:"Size of item links. Can leave at 0, will dynamically detect, Secrets custom is 68. but RoF2 is 50. Titanium is 39. Left for super custom servers."`
-	IsLegacyLinks           bool    `toml:"legacy_links" desc:"If true, will not use masked links and revert to classic style where e.g. http://foo.com?item=123 (Rawr)"`
-	IsLinksEmbedded         bool    `toml:"links_embedded" desc:"If true, a preview of item links will appear below messages. Default is false."`
-	Host                    string  `toml:"host" desc:"Address where telnet is found. By default, newer telnet clients will auto success on 127.0.0.1:9000"`
-	Username                string  `toml:"username" desc:"Optional. Username to connect to telnet to. (By default, newer telnet clients will auto succeed if localhost)"`
-	Password                string  `toml:"password" desc:"Optional. Password to connect to telnet to. (By default, newer telnet clients will auto succeed if localhost)"`
-	Routes                  []Route `toml:"routes" desc:"Routes from telnet to other services"`
-	ItemURL                 string  `toml:"item_url" desc:"Optional. Converts item URLs to provided field. defaults to allakhazam. To disable, change to \n# default: \"http://everquest.allakhazam.com/db/item.html?item=\""`
-	ProfileURL              string  `toml:"profile_url" desc:"Optional. Converts a character's name to a profile URL (e.g. Magelo link). Example: https://retributioneq.com/magelo/index.php?page=character&char= ."`
-	IsServerAnnounceEnabled bool    `toml:"announce_server_status" desc:"Optional. Annunce when a server changes state to OOC channel (Server UP/Down)"`
-	IsOOCAuctionEnabled     bool    `toml:"convert_ooc_auction" desc:"if a OOC message uses prefix WTS or WTB, convert them into auction"`
+	IsEnabled               bool               `toml:"enabled" desc:"Enable Telnet"`
+	IsLegacy                bool               `toml:"legacy" desc:"EQEMU servers that run 0.8.0 versions need this set to true for item link support, everyone running any newer versions can leave it default (false)"`
+	LinkChunk1Size          int                `toml:"link_chunk1_size" desc:"Size of item links. Can leave at 0, will dynamically detect, Secrets custom is 9. but RoF2 is 6. Titanium is 6. Left for super custom servers."`
+	LinkChunk2Size          int                `toml:"link_chunk2_size" desc:"Size of item links. Can leave at 0, will dynamically detect, Secrets custom is 68. but RoF2 is 50. Titanium is 39. Left for super custom servers."`
+	IsLegacyLinks           bool               `toml:"legacy_links" desc:"If true, will not use masked links and revert to classic style where e.g. http://foo.com?item=123 (Rawr)"`
+	IsLinksEmbedded         bool               `toml:"links_embedded" desc:"If true, a preview of item links will appear below messages. Default is false."`
+	Host                    string             `toml:"host" desc:"Address where telnet is found. By default, newer telnet clients will auto success on 127.0.0.1:9000"`
+	Username                string             `toml:"username" desc:"Optional. Username to connect to telnet to. (By default, newer telnet clients will auto succeed if localhost)"`
+	Password                string             `toml:"password" desc:"Optional. Password to connect to telnet to. (By default, newer telnet clients will auto succeed if localhost)"`
+	Heartbeat               TelnetHeartbeat    `toml:"heartbeat" desc:"Optional. Periodically send a harmless command, so idle connections are not dropped by firewalls and a dead connection is found quickly"`
+	Routes                  []Route            `toml:"routes" desc:"Routes from telnet to other services"`
+	WhoFormat               string             `toml:"who_format" desc:"Format of who output lines. auto tries every format, or set legacy or v2 for servers running newer EQEmu builds\n# default: auto"`
+	WhoDumpWindow           int                `toml:"who_dump_window" desc:"Seconds without a new who entry before a who list is treated as complete. Lists normally end at the 'N players online' line, this only matters when that line is missed. Raise it if who lists on a laggy server are cut short\n# default: 2"`
+	ZoneChange              PlayerNotification `toml:"zone_change" desc:"Optional. Announce when a player in the who list changes zones"`
+	LevelUp                 PlayerNotification `toml:"level_up" desc:"Optional. Announce when a player in the who list gains a level"`
+	ReturningPlayer         PlayerNotification `toml:"returning_player" desc:"Optional. Announce when a player logs in who was not seen in the who list for returning_player_days"`
+	ReturningPlayerDays     int                `toml:"returning_player_days" desc:"Days a player must be away to be announced by returning_player\n# default: 7"`
+	Deaths                  TelnetDeaths       `toml:"deaths" desc:"Optional. Post player deaths announced over telnet to a discord channel as an embed"`
+	SpawnAlert              TelnetSpawnAlert   `toml:"spawn_alert" desc:"Optional. Alert a discord channel when a watched mob's spawn is announced over telnet"`
+	Loot                    TelnetLoot         `toml:"loot" desc:"Optional. Post rare loot broadcast over telnet to a discord channel as an embed, linking the item with item_url"`
+	Commands                TelnetCommands     `toml:"commands" desc:"Optional. Let players use talkeq commands in game, e.g. !who and !uptime in ooc"`
+	LFG                     TelnetLFG          `toml:"lfg" desc:"Optional. Collect LFG and LFM messages into a group finder discord channel as embeds, which are marked expired after a while"`
+	RegisterCode            TelnetRegisterCode `toml:"register_code" desc:"Optional. Confirm a !register request when the player says the code talkeq sent them by DM in game, proving they own the character. Requires [api.register]"`
+	TellDM                  TelnetTellDM       `toml:"tell_dm" desc:"Optional. Relay in game tells to the recipient's discord user as a DM, if they registered and opted in with /tells on"`
+	Unmatched               Unmatched          `toml:"unmatched" desc:"Optional. Relay telnet lines that matched no enabled route, to help write new triggers"`
+	ItemURL                 string             `toml:"item_url" desc:"Optional. Converts item URLs to provided field. defaults to allakhazam. To disable, change to \n# default: \"http://everquest.allakhazam.com/db/item.html?item=\""`
+	ProfileURL              string             `toml:"profile_url" desc:"Optional. Converts a character's name to a profile URL (e.g. Magelo link). Example: https://retributioneq.com/magelo/index.php?page=character&char= ."`
+	IsServerAnnounceEnabled bool               `toml:"announce_server_status" desc:"Optional. Annunce when a server changes state to OOC channel (Server UP/Down)"`
+	IsOOCAuctionEnabled     bool               `toml:"convert_ooc_auction" desc:"if a OOC message uses prefix WTS or WTB, convert them into auction"`
+}
+
+// WhoDumpWindowDuration returns the who dump window as a duration, 2 seconds if unset
+func (c *Telnet) WhoDumpWindowDuration() time.Duration {
+	if c.WhoDumpWindow < 1 {
+		return 2 * time.Second
+	}
+	return time.Duration(c.WhoDumpWindow) * time.Second
+}
+
+// TelnetHeartbeat represents config for a periodic telnet keep alive command
+type TelnetHeartbeat struct {
+	IsEnabled bool   `toml:"enabled" desc:"Enable the heartbeat. Some servers log every console command, so it is off by default"`
+	Interval  int    `toml:"interval" desc:"Seconds between heartbeats\n# default: 60"`
+	Command   string `toml:"command" desc:"Command sent as the heartbeat, it should have no effect on the server\n# default: echo off"`
+}
+
+// IntervalDuration returns the interval as a duration
+func (c *TelnetHeartbeat) IntervalDuration() time.Duration {
+	return time.Duration(c.Interval) * time.Second
+}
+
+// TelnetRegisterCode represents config for confirming registrations with a code said in game
+type TelnetRegisterCode struct {
+	IsEnabled    bool   `toml:"enabled" desc:"Enable confirming registrations in game"`
+	Regex        string `toml:"telnet_pattern" desc:"Input telnet regex of the chat codes are read from\n# default: (\\w+) says ooc, '(.*)'"`
+	NameIndex    int    `toml:"name_index" desc:"Name is found in this regex index grouping"`
+	MessageIndex int    `toml:"message_index" desc:"Message is found in this regex index grouping"`
+}
+
+// TelnetTellDM represents config for relaying tells to discord DMs
+type TelnetTellDM struct {
+	IsEnabled    bool   `toml:"enabled" desc:"Enable relaying tells to discord DMs"`
+	Regex        string `toml:"telnet_pattern" desc:"Input telnet tell regex\n# default: (\\w+) tells (\\w+), '(.*)'"`
+	FromIndex    int    `toml:"from_index" desc:"Sender is found in this regex index grouping"`
+	ToIndex      int    `toml:"to_index" desc:"Recipient is found in this regex index grouping"`
+	MessageIndex int    `toml:"message_index" desc:"Message is found in this regex index grouping"`
+}
+
+// TelnetDeaths represents config for the player death feed
+type TelnetDeaths struct {
+	IsEnabled              bool   `toml:"enabled" desc:"Enable the death feed"`
+	ChannelID              string `toml:"channel_id" desc:"Discord channel ID to post deaths to"`
+	Regex                  string `toml:"telnet_pattern" desc:"Input telnet death regex\n# default: ^(\\w+) (?:has been|was) slain by (.+) in (.+?)[.!]?$"`
+	NameIndex              int    `toml:"name_index" desc:"Name of the player who died is found in this regex index grouping"`
+	KillerIndex            int    `toml:"killer_index" desc:"Killer is found in this regex index grouping (0 is ignored)"`
+	ZoneIndex              int    `toml:"zone_index" desc:"Zone is found in this regex index grouping (0 is ignored)"`
+	MessagePattern         string `toml:"message_pattern" desc:"Message to post. Variables: {{.Name}}, {{.Killer}}, {{.Zone}}"`
+	Color                  int    `toml:"color" desc:"Color of the embed, as a decimal number\n# default: 9109504 (dark red)"`
+	messagePatternTemplate *template.Template
+}
+
+// MessagePatternTemplate returns a template for the death feed
+func (c *TelnetDeaths) MessagePatternTemplate() *template.Template {
+	if c.messagePatternTemplate == nil {
+		c.messagePatternTemplate, _ = template.New("root").Parse(c.MessagePattern)
+	}
+	return c.messagePatternTemplate
+}
+
+// LoadMessagePattern is called after config is loaded, and verified patterns are valid
+func (c *TelnetDeaths) LoadMessagePattern() error {
+	if !c.IsEnabled {
+		return nil
+	}
+	var err error
+	c.messagePatternTemplate, err = template.New("root").Parse(c.MessagePattern)
+	if err != nil {
+		return fmt.Errorf("failed to parse: %w", err)
+	}
+	return nil
+}
+
+// TelnetSpawnAlert represents config for named mob spawn alerts
+type TelnetSpawnAlert struct {
+	IsEnabled              bool     `toml:"enabled" desc:"Enable spawn alerts"`
+	ChannelID              string   `toml:"channel_id" desc:"Discord channel ID to post alerts to"`
+	RoleID                 string   `toml:"role_id" desc:"Optional. Discord role ID to mention with each alert"`
+	Regex                  string   `toml:"telnet_pattern" desc:"Input telnet spawn announcement regex\n# default: ^(.+) has spawned in (.+?)[.!]?$"`
+	MobIndex               int      `toml:"mob_index" desc:"Mob name is found in this regex index grouping"`
+	ZoneIndex              int      `toml:"zone_index" desc:"Zone is found in this regex index grouping (0 is ignored)"`
+	Mobs                   []string `toml:"mobs" desc:"Mob names to alert on. An announced mob must match one of these exactly, ignoring case and underscores. e.g. [\"Lord Nagafen\", \"Lady Vox\"]"`
+	Cooldown               int      `toml:"cooldown" desc:"Seconds before the same mob is alerted on again\n# default: 300"`
+	MessagePattern         string   `toml:"message_pattern" desc:"Message to post. Variables: {{.Mob}}, {{.Zone}}"`
+	Color                  int      `toml:"color" desc:"Color of the embed, as a decimal number\n# default: 16753920 (orange)"`
+	messagePatternTemplate *template.Template
+}
+
+// WatchedMob returns the watched mob name equal to mob, or an empty string if mob is not watched
+func (c *TelnetSpawnAlert) WatchedMob(mob string) string {
+	mob = strings.TrimSpace(strings.ReplaceAll(mob, "_", " "))
+	for _, watched := range c.Mobs {
+		if watched != "" && strings.EqualFold(mob, strings.TrimSpace(strings.ReplaceAll(watched, "_", " "))) {
+			return watched
+		}
+	}
+	return ""
+}
+
+// MessagePatternTemplate returns a template for spawn alerts
+func (c *TelnetSpawnAlert) MessagePatternTemplate() *template.Template {
+	if c.messagePatternTemplate == nil {
+		c.messagePatternTemplate, _ = template.New("root").Parse(c.MessagePattern)
+	}
+	return c.messagePatternTemplate
+}
+
+// LoadMessagePattern is called after config is loaded, and verified patterns are valid
+func (c *TelnetSpawnAlert) LoadMessagePattern() error {
+	if !c.IsEnabled {
+		return nil
+	}
+	var err error
+	c.messagePatternTemplate, err = template.New("root").Parse(c.MessagePattern)
+	if err != nil {
+		return fmt.Errorf("failed to parse: %w", err)
+	}
+	return nil
+}
+
+// TelnetLoot represents config for loot announcements
+type TelnetLoot struct {
+	IsEnabled              bool     `toml:"enabled" desc:"Enable loot announcements"`
+	ChannelID              string   `toml:"channel_id" desc:"Discord channel ID to post loot to"`
+	Regex                  string   `toml:"telnet_pattern" desc:"Input telnet loot broadcast regex\n# default: (\\w+) has looted (.+) from (.+?)[.!]?$"`
+	NameIndex              int      `toml:"name_index" desc:"Name of the player who looted is found in this regex index grouping"`
+	ItemIndex              int      `toml:"item_index" desc:"Item, or item link, is found in this regex index grouping"`
+	MobIndex               int      `toml:"mob_index" desc:"Mob looted from is found in this regex index grouping (0 is ignored)"`
+	RarityIndex            int      `toml:"rarity_index" desc:"Optional. Rarity is found in this regex index grouping, if the server's broadcast includes it (0 is ignored)"`
+	Rarities               []string `toml:"rarities" desc:"Rarities from lowest to highest, used with min_rarity. e.g. [\"common\", \"rare\", \"legendary\"]"`
+	MinRarity              string   `toml:"min_rarity" desc:"Optional. Only announce loot of this rarity or higher in rarities"`
+	MessagePattern         string   `toml:"message_pattern" desc:"Message to post. Variables: {{.Name}}, {{.Item}}, {{.Mob}}, {{.Rarity}}"`
+	Color                  int      `toml:"color" desc:"Color of the embed, as a decimal number\n# default: 10494192 (purple)"`
+	messagePatternTemplate *template.Template
+}
+
+// IsRarityAllowed returns true if rarity is min_rarity or higher, or if no min_rarity is set
+func (c *TelnetLoot) IsRarityAllowed(rarity string) bool {
+	if c.MinRarity == "" {
+		return true
+	}
+	minRank := -1
+	rank := -1
+	for i, value := range c.Rarities {
+		if strings.EqualFold(value, c.MinRarity) {
+			minRank = i
+		}
+		if strings.EqualFold(value, rarity) {
+			rank = i
+		}
+	}
+	return rank >= minRank && rank >= 0
+}
+
+// MessagePatternTemplate returns a template for loot announcements
+func (c *TelnetLoot) MessagePatternTemplate() *template.Template {
+	if c.messagePatternTemplate == nil {
+		c.messagePatternTemplate, _ = template.New("root").Parse(c.MessagePattern)
+	}
+	return c.messagePatternTemplate
+}
+
+// LoadMessagePattern is called after config is loaded, and verified patterns are valid
+func (c *TelnetLoot) LoadMessagePattern() error {
+	if !c.IsEnabled {
+		return nil
+	}
+	var err error
+	c.messagePatternTemplate, err = template.New("root").Parse(c.MessagePattern)
+	if err != nil {
+		return fmt.Errorf("failed to parse: %w", err)
+	}
+	return nil
+}
+
+// TelnetLFG represents config for the group finder channel
+type TelnetLFG struct {
+	IsEnabled     bool   `toml:"enabled" desc:"Enable the group finder channel"`
+	ChannelID     string `toml:"channel_id" desc:"Discord channel ID to post listings to"`
+	Regex         string `toml:"telnet_pattern" desc:"Input telnet regex of the chat listings are read from\n# default: (\\w+) says ooc, '(.*)'"`
+	NameIndex     int    `toml:"name_index" desc:"Name is found in this regex index grouping"`
+	MessageIndex  int    `toml:"message_index" desc:"Message is found in this regex index grouping"`
+	ExpireMinutes int    `toml:"expire_minutes" desc:"Minutes before a listing is marked as expired\n# default: 30"`
+	Color         int    `toml:"color" desc:"Color of the embed, as a decimal number\n# default: 3447003 (blue)"`
+}
+
+// TelnetCommands represents config for in game commands
+type TelnetCommands struct {
+	IsEnabled            bool   `toml:"enabled" desc:"Enable in game commands"`
+	Prefix               string `toml:"prefix" desc:"Messages starting with this are commands\n# default: !"`
+	Regex                string `toml:"telnet_pattern" desc:"Input telnet regex of the chat commands are read from\n# default: (\\w+) says ooc, '(.*)'"`
+	NameIndex            int    `toml:"name_index" desc:"Name is found in this regex index grouping"`
+	MessageIndex         int    `toml:"message_index" desc:"Message is found in this regex index grouping"`
+	ReplyPattern         string `toml:"reply_pattern" desc:"Telnet command used to reply. Variables: {{.Name}}, {{.Message}}\n# e.g. emote world 260 {{.Message}} to reply in ooc\n# default: tell {{.Name}} {{.Message}}"`
+	replyPatternTemplate *template.Template
+	// Cooldowns rate limit each player, commands used again too soon are ignored
+	Cooldowns map[string]int `toml:"cooldowns" desc:"Seconds a player must wait before using a command again. e.g. who = 10"`
+}
+
+// ReplyPatternTemplate returns a template for command replies
+func (c *TelnetCommands) ReplyPatternTemplate() *template.Template {
+	if c.replyPatternTemplate == nil {
+		c.replyPatternTemplate, _ = template.New("root").Parse(c.ReplyPattern)
+	}
+	return c.replyPatternTemplate
 }
 
 // TelnetEntry represents telnet event pattern detection
@@ -37,7 +259,7 @@ func (c *Telnet) Verify() error {
 		return nil
 	}
 	for i := range c.Routes {
-		if c.Routes[i].ChannelID == "" {
+		if c.Routes[i].ChannelID == "" && len(c.Routes[i].ChannelIDs) == 0 {
 			return fmt.Errorf("route %d: invalid channel id", i)
 		}
 		err := c.Routes[i].LoadMessagePattern()
@@ -45,5 +267,96 @@ func (c *Telnet) Verify() error {
 			return fmt.Errorf("route %d: %w", i, err)
 		}
 	}
+	err := c.ZoneChange.LoadMessagePattern()
+	if err != nil {
+		return fmt.Errorf("zone_change: %w", err)
+	}
+	err = c.LevelUp.LoadMessagePattern()
+	if err != nil {
+		return fmt.Errorf("level_up: %w", err)
+	}
+	err = c.ReturningPlayer.LoadMessagePattern()
+	if err != nil {
+		return fmt.Errorf("returning_player: %w", err)
+	}
+	if c.Heartbeat.Interval < 1 {
+		c.Heartbeat.Interval = 60
+	}
+	if c.WhoDumpWindow < 1 {
+		c.WhoDumpWindow = 2
+	}
+	if c.Heartbeat.Command == "" {
+		c.Heartbeat.Command = "echo off"
+	}
+	err = c.Deaths.LoadMessagePattern()
+	if err != nil {
+		return fmt.Errorf("deaths: %w", err)
+	}
+	err = c.SpawnAlert.LoadMessagePattern()
+	if err != nil {
+		return fmt.Errorf("spawn_alert: %w", err)
+	}
+	if c.SpawnAlert.Cooldown < 1 {
+		c.SpawnAlert.Cooldown = 300
+	}
+	err = c.Loot.LoadMessagePattern()
+	if err != nil {
+		return fmt.Errorf("loot: %w", err)
+	}
+	if c.LFG.ExpireMinutes < 1 {
+		c.LFG.ExpireMinutes = 30
+	}
+	if c.Commands.IsEnabled {
+		if c.Commands.Prefix == "" {
+			c.Commands.Prefix = "!"
+		}
+		c.Commands.replyPatternTemplate, err = template.New("root").Parse(c.Commands.ReplyPattern)
+		if err != nil {
+			return fmt.Errorf("commands: %w", err)
+		}
+	}
+	return nil
+}
+
+// PlayerNotification announces a change to a player seen in the who list
+type PlayerNotification struct {
+	IsEnabled              bool     `toml:"enabled" desc:"Is notification enabled?"`
+	ChannelID              string   `toml:"channel_id" desc:"Discord channel ID to announce to"`
+	MessagePattern         string   `toml:"message_pattern" desc:"Message to announce. Variables: {{.Name}}, {{.Zone}}, {{.OldZone}}, {{.Level}}, {{.OldLevel}}, {{.Class}}, {{.DaysAway}}"`
+	Zones                  []string `toml:"zones,omitempty" desc:"Optional, only announce for players in these zone short names, e.g. [\"fearplane\", \"hateplane\"]"`
+	messagePatternTemplate *template.Template
+}
+
+// IsZoneAllowed returns true if zone is in Zones, or Zones is empty
+func (n *PlayerNotification) IsZoneAllowed(zone string) bool {
+	if len(n.Zones) == 0 {
+		return true
+	}
+	for _, allowed := range n.Zones {
+		if strings.EqualFold(allowed, zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// MessagePatternTemplate returns a template for provided notification
+func (n *PlayerNotification) MessagePatternTemplate() *template.Template {
+	if n.messagePatternTemplate == nil {
+		n.messagePatternTemplate, _ = template.New("root").Parse(n.MessagePattern)
+	}
+	return n.messagePatternTemplate
+}
+
+// LoadMessagePattern is called after config is loaded, and verified patterns are valid
+func (n *PlayerNotification) LoadMessagePattern() error {
+	if !n.IsEnabled {
+		return nil
+	}
+	var err error
+	n.messagePatternTemplate, err = template.New("root").Parse(n.MessagePattern)
+	if err != nil {
+		return fmt.Errorf("failed to parse: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestConfig_LogLevelName(t *testing.T) {
+	tests := []struct {
+		debug    bool
+		logLevel string
+		want     string
+	}{
+		{false, "", "info"},
+		{true, "", "debug"},
+		{true, "warn", "warn"},
+		{false, "TRACE", "trace"},
+	}
+	for _, tt := range tests {
+		c := &Config{Debug: tt.debug, LogLevel: tt.logLevel}
+		if got := c.LogLevelName(); got != tt.want {
+			t.Errorf("LogLevelName() with debug %t log_level %q = %q, want %q", tt.debug, tt.logLevel, got, tt.want)
+		}
+	}
+
+	c := &Config{Debug: true, ConfigVersion: 1}
+	c.migrate()
+	if c.LogLevel != "debug" {
+		t.Fatalf("migrate with debug = true wanted log_level debug, got %q", c.LogLevel)
+	}
+}
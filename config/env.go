@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+)
+
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandEnv replaces ${ENV_VAR} references in every string config value with the environment variable's value.
+// An error is returned if a referenced variable is not set
+func (c *Config) ExpandEnv() error {
+	return expandEnvValue(reflect.ValueOf(c).Elem(), "")
+}
+
+// expandEnvValue walks v, expanding all settable strings in place
+func expandEnvValue(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		value, err := expandEnvString(v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetString(value)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			err := expandEnvValue(v.Field(i), joinEnvPath(path, t.Field(i)))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			err := expandEnvValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			value, err := expandEnvString(iter.Value().String())
+			if err != nil {
+				return fmt.Errorf("%s.%v: %w", path, iter.Key(), err)
+			}
+			v.SetMapIndex(iter.Key(), reflect.ValueOf(value))
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return expandEnvValue(v.Elem(), path)
+	}
+	return nil
+}
+
+// expandEnvString replaces ${ENV_VAR} references in value
+func expandEnvString(value string) (string, error) {
+	var missing string
+	value = envPattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := envPattern.FindStringSubmatch(match)[1]
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			if missing == "" {
+				missing = name
+			}
+			return match
+		}
+		return env
+	})
+	if missing != "" {
+		return "", fmt.Errorf("environment variable %s is not set", missing)
+	}
+	return value, nil
+}
+
+// joinEnvPath returns a dotted toml path for a field, used to point at the offending value in errors
+func joinEnvPath(path string, field reflect.StructField) string {
+	name := field.Tag.Get("toml")
+	for i := 0; i < len(name); i++ {
+		if name[i] == ',' {
+			name = name[:i]
+			break
+		}
+	}
+	if name == "" || name == "-" {
+		name = field.Name
+	}
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
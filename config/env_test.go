@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestConfig_ExpandEnv(t *testing.T) {
+	t.Setenv("TALKEQ_TEST_TOKEN", "secret")
+	cfg := getDefaultConfig()
+	cfg.Discord.Token = "${TALKEQ_TEST_TOKEN}"
+	cfg.Telnet.Password = "pre-${TALKEQ_TEST_TOKEN}-$HOME"
+	cfg.Discord.Routes[0].Trigger.ChannelID = "${TALKEQ_TEST_TOKEN}"
+	err := cfg.ExpandEnv()
+	if err != nil {
+		t.Fatalf("expandEnv: %s", err)
+	}
+	if cfg.Discord.Token != "secret" {
+		t.Fatalf("token wanted secret, got %s", cfg.Discord.Token)
+	}
+	if cfg.Telnet.Password != "pre-secret-$HOME" {
+		t.Fatalf("password wanted pre-secret-$HOME, got %s", cfg.Telnet.Password)
+	}
+	if cfg.Discord.Routes[0].Trigger.ChannelID != "secret" {
+		t.Fatalf("route channel wanted secret, got %s", cfg.Discord.Routes[0].Trigger.ChannelID)
+	}
+
+	cfg.SQLReport.Password = "${TALKEQ_TEST_MISSING}"
+	err = cfg.ExpandEnv()
+	if err == nil {
+		t.Fatalf("expandEnv wanted error for missing variable, got nil")
+	}
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/jbsmith7741/toml"
+)
+
+// isDefinedFunc returns true if a config file set the toml key, like toml.MetaData.IsDefined
+type isDefinedFunc func(key ...string) bool
+
+// loadIncludes decodes every file listed in include, relative to the file that lists it, and merges it into c.
+// md is the metadata of path, so settings path sets are kept even if they are false or empty
+func (c *Config) loadIncludes(path string, md toml.MetaData) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("abs %s: %w", path, err)
+	}
+	_, err = c.mergeIncludes(absPath, []string{absPath}, md.IsDefined)
+	return err
+}
+
+// mergeIncludes resolves c.Includes found in parentPath. chain is the list of files currently being included, to detect cycles.
+// isDefined reports the keys parentPath set, and the returned func also reports the keys set by the files it includes
+func (c *Config) mergeIncludes(parentPath string, chain []string, isDefined isDefinedFunc) (isDefinedFunc, error) {
+	includes := c.Includes
+	c.Includes = nil
+	for _, include := range includes {
+		includePath := include
+		if !filepath.IsAbs(includePath) {
+			includePath = filepath.Join(filepath.Dir(parentPath), includePath)
+		}
+		includePath = filepath.Clean(includePath)
+		for _, p := range chain {
+			if p == includePath {
+				return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), includePath)
+			}
+		}
+
+		child := Config{}
+		md, err := toml.DecodeFile(includePath, &child)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", includePath, err)
+		}
+
+		isChildDefined, err := child.mergeIncludes(includePath, append(chain[:len(chain):len(chain)], includePath), md.IsDefined)
+		if err != nil {
+			return nil, err
+		}
+		mergeValue(reflect.ValueOf(c).Elem(), reflect.ValueOf(child), isDefined, isChildDefined, nil)
+
+		isParentDefined := isDefined
+		isDefined = func(key ...string) bool {
+			return isParentDefined(key...) || isChildDefined(key...)
+		}
+	}
+	return isDefined, nil
+}
+
+// mergeValue merges src into dst. Slices are appended and map keys missing in dst are added.
+// Other settings are taken from src if src set them and dst did not, found by the toml key of each field
+func mergeValue(dst reflect.Value, src reflect.Value, isDstDefined isDefinedFunc, isSrcDefined isDefinedFunc, key []string) {
+	switch dst.Kind() {
+	case reflect.Struct:
+		t := dst.Type()
+		for i := 0; i < dst.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			name := strings.Split(t.Field(i).Tag.Get("toml"), ",")[0]
+			if name == "" {
+				name = t.Field(i).Name
+			}
+			mergeValue(dst.Field(i), src.Field(i), isDstDefined, isSrcDefined, append(key[:len(key):len(key)], name))
+		}
+	case reflect.Slice:
+		if src.Len() == 0 {
+			return
+		}
+		dst.Set(reflect.AppendSlice(dst, src))
+	case reflect.Map:
+		if src.Len() == 0 {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			if dst.MapIndex(iter.Key()).IsValid() {
+				continue
+			}
+			dst.SetMapIndex(iter.Key(), iter.Value())
+		}
+	default:
+		if !isDstDefined(key...) && isSrcDefined(key...) {
+			dst.Set(src)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jbsmith7741/toml"
+)
+
+func TestConfig_loadIncludes(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "routes"), 0755)
+	if err != nil {
+		t.Fatalf("mkdir: %s", err)
+	}
+	files := map[string]string{
+		"talkeq.conf": `include = ["routes/telnet.conf"]
+[telnet]
+enabled = true
+[[telnet.routes]]
+enabled = true
+channel_id = "1"
+`,
+		"routes/telnet.conf": `include = ["discord.conf"]
+[telnet]
+host = "127.0.0.1:23"
+[[telnet.routes]]
+enabled = true
+channel_id = "2"
+`,
+		"routes/discord.conf": `keep_alive = false
+[telnet]
+enabled = false
+[discord]
+bot_token = "token"
+`,
+	}
+	for name, data := range files {
+		err = os.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatalf("write %s: %s", name, err)
+		}
+	}
+
+	cfg, md := loadIncludeTestConfig(t, filepath.Join(dir, "talkeq.conf"))
+	// a default filled in by a migration, which an included file can still turn off
+	cfg.IsKeepAliveEnabled = true
+	err = cfg.loadIncludes(filepath.Join(dir, "talkeq.conf"), md)
+	if err != nil {
+		t.Fatalf("loadIncludes: %s", err)
+	}
+	if cfg.IsKeepAliveEnabled {
+		t.Fatalf("keep_alive set false by an include wanted false")
+	}
+	if !cfg.Telnet.IsEnabled {
+		t.Fatalf("telnet enabled set by talkeq.conf wanted to win over the include")
+	}
+	if len(cfg.Telnet.Routes) != 2 {
+		t.Fatalf("telnet routes wanted 2, got %d", len(cfg.Telnet.Routes))
+	}
+	if cfg.Telnet.Host != "127.0.0.1:23" {
+		t.Fatalf("telnet host wanted 127.0.0.1:23, got %s", cfg.Telnet.Host)
+	}
+	if cfg.Discord.Token != "token" {
+		t.Fatalf("discord token wanted token, got %s", cfg.Discord.Token)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "routes/discord.conf"), []byte(`include = ["../talkeq.conf"]`), 0644)
+	if err != nil {
+		t.Fatalf("write cycle: %s", err)
+	}
+	cfg, md = loadIncludeTestConfig(t, filepath.Join(dir, "talkeq.conf"))
+	err = cfg.loadIncludes(filepath.Join(dir, "talkeq.conf"), md)
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Fatalf("loadIncludes wanted include cycle error, got %v", err)
+	}
+}
+
+func loadIncludeTestConfig(t *testing.T, path string) (*Config, toml.MetaData) {
+	cfg := &Config{}
+	md, err := toml.DecodeFile(path, cfg)
+	if err != nil {
+		t.Fatalf("decode %s: %s", path, err)
+	}
+	return cfg, md
+}
@@ -0,0 +1,14 @@
+package config
+
+import "strings"
+
+// MaskToken hides all but the last 4 characters of a secret, so it can be shown to users
+func MaskToken(token string) string {
+	if token == "" {
+		return "(not set)"
+	}
+	if len(token) <= 8 {
+		return strings.Repeat("*", len(token))
+	}
+	return strings.Repeat("*", 8) + token[len(token)-4:]
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/jbsmith7741/toml"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// migrations upgrade a config from the version matching their index to the next version.
+// New migrations are appended, and should only fill in defaults for fields that were added.
+// An upgraded config is not written over talkeq.conf, so migrations run again on every start and must give the same result each time
+var migrations = []func(c *Config){
+	// 0 -> 1: offline status, message splitting, command cooldowns and moderation
+	func(c *Config) {
+		if c.Discord.BotStatusOffline == "" {
+			c.Discord.BotStatusOffline = "EQ: Server Offline"
+		}
+		if c.Discord.MaxMessageLength == 0 {
+			c.Discord.MaxMessageLength = 400
+		}
+		if c.Discord.CommandCooldowns == nil {
+			c.Discord.CommandCooldowns = map[string]int{
+				"who": 10,
+			}
+		}
+		if c.Discord.AuditLogMaxSize == 0 {
+			c.Discord.AuditLogMaxSize = 1024
+		}
+		if c.Discord.Moderation == nil {
+			c.Discord.Moderation = getDefaultConfig().Discord.Moderation
+		}
+	},
+	// 1 -> 2: log_level, keeping debug output for configs that had debug = true
+	func(c *Config) {
+		if c.LogLevel == "" && c.Debug {
+			c.LogLevel = "debug"
+		}
+		if c.LogLevel == "" {
+			c.LogLevel = "info"
+		}
+		if c.LogLevels == nil {
+			c.LogLevels = map[string]string{}
+		}
+	},
+	// 2 -> 3: tell DMs
+	func(c *Config) {
+		if c.Discord.TellDMCooldown == 0 {
+			c.Discord.TellDMCooldown = 5
+		}
+		if c.Telnet.TellDM.Regex == "" {
+			c.Telnet.TellDM = getDefaultConfig().Telnet.TellDM
+		}
+	},
+	// 3 -> 4: who format
+	func(c *Config) {
+		if c.Telnet.WhoFormat == "" {
+			c.Telnet.WhoFormat = "auto"
+		}
+	},
+	// 4 -> 5: zone change notifications
+	func(c *Config) {
+		if c.Telnet.ZoneChange.MessagePattern == "" {
+			c.Telnet.ZoneChange = getDefaultConfig().Telnet.ZoneChange
+		}
+	},
+	// 5 -> 6: level up notifications
+	func(c *Config) {
+		if c.Telnet.LevelUp.MessagePattern == "" {
+			c.Telnet.LevelUp = getDefaultConfig().Telnet.LevelUp
+		}
+	},
+	// 6 -> 7: returning player notifications
+	func(c *Config) {
+		if c.LastSeenDatabasePath == "" {
+			c.LastSeenDatabasePath = getDefaultConfig().LastSeenDatabasePath
+		}
+		if c.Telnet.ReturningPlayer.MessagePattern == "" {
+			c.Telnet.ReturningPlayer = getDefaultConfig().Telnet.ReturningPlayer
+		}
+		if c.Telnet.ReturningPlayerDays == 0 {
+			c.Telnet.ReturningPlayerDays = getDefaultConfig().Telnet.ReturningPlayerDays
+		}
+	},
+	// 7 -> 8: online count channel
+	func(c *Config) {
+		if c.Discord.OnlineCountName == "" {
+			c.Discord.OnlineCountName = getDefaultConfig().Discord.OnlineCountName
+		}
+	},
+	// 8 -> 9: chat log
+	func(c *Config) {
+		if c.ChatLog.Path == "" {
+			c.ChatLog = getDefaultConfig().ChatLog
+		}
+	},
+	// 9 -> 10: chat log retention
+	func(c *Config) {
+		if c.ChatLog.RetentionDays == 0 {
+			c.ChatLog.RetentionDays = getDefaultConfig().ChatLog.RetentionDays
+		}
+	},
+	// 10 -> 11: command response visibility
+	func(c *Config) {
+		if c.Discord.CommandEphemeral == nil {
+			c.Discord.CommandEphemeral = getDefaultConfig().Discord.CommandEphemeral
+		}
+	},
+	// 11 -> 12: death feed
+	func(c *Config) {
+		if c.Telnet.Deaths.Regex == "" {
+			c.Telnet.Deaths = getDefaultConfig().Telnet.Deaths
+		}
+	},
+	// 12 -> 13: spawn alerts
+	func(c *Config) {
+		if c.Telnet.SpawnAlert.Regex == "" {
+			c.Telnet.SpawnAlert = getDefaultConfig().Telnet.SpawnAlert
+		}
+	},
+	// 13 -> 14: loot announcements
+	func(c *Config) {
+		if c.Telnet.Loot.Regex == "" {
+			c.Telnet.Loot = getDefaultConfig().Telnet.Loot
+		}
+	},
+	// 14 -> 15: in game commands
+	func(c *Config) {
+		if c.Telnet.Commands.Regex == "" {
+			c.Telnet.Commands = getDefaultConfig().Telnet.Commands
+		}
+	},
+	// 15 -> 16: eqlog self names
+	func(c *Config) {
+		if c.EQLog.SelfNames == nil {
+			c.EQLog.SelfNames = getDefaultConfig().EQLog.SelfNames
+		}
+	},
+	// 16 -> 17: disabled eqlog say, group and raid routes
+	func(c *Config) {
+		for _, route := range defaultEQLogChannelRoutes() {
+			isFound := false
+			for _, existing := range c.EQLog.Routes {
+				if existing.Trigger.Regex == route.Trigger.Regex {
+					isFound = true
+					break
+				}
+			}
+			if !isFound {
+				c.EQLog.Routes = append(c.EQLog.Routes, route)
+			}
+		}
+	},
+	// 17 -> 18: group finder channel
+	func(c *Config) {
+		if c.Telnet.LFG.Regex == "" {
+			c.Telnet.LFG = getDefaultConfig().Telnet.LFG
+		}
+	},
+	// 18 -> 19: auction embeds. Nothing to fill in, existing auction routes keep relaying as text until auction_embed is set
+	func(c *Config) {},
+	// 19 -> 20: auction history
+	func(c *Config) {
+		if c.AuctionHistory.Path == "" {
+			c.AuctionHistory = getDefaultConfig().AuctionHistory
+		}
+	},
+	// 20 -> 21: telnet heartbeat
+	func(c *Config) {
+		if c.Telnet.Heartbeat.Command == "" {
+			c.Telnet.Heartbeat = getDefaultConfig().Telnet.Heartbeat
+		}
+	},
+	// 21 -> 22: discord channel_numbers
+	func(c *Config) {
+		if c.Discord.ChannelNumbers == nil {
+			c.Discord.ChannelNumbers = defaultChannelNumbers()
+		}
+	},
+	// 22 -> 23: register codes said in game
+	func(c *Config) {
+		if c.Telnet.RegisterCode.Regex == "" {
+			c.Telnet.RegisterCode = getDefaultConfig().Telnet.RegisterCode
+		}
+	},
+	// 23 -> 24: auction parsing patterns
+	func(c *Config) {
+		if c.AuctionParsing.SeparatorPattern == "" {
+			c.AuctionParsing = getDefaultConfig().AuctionParsing
+		}
+	},
+	// 24 -> 25: auction digest
+	func(c *Config) {
+		if c.AuctionDigest.Interval == 0 {
+			c.AuctionDigest = getDefaultConfig().AuctionDigest
+		}
+	},
+	// 25 -> 26: character database max
+	func(c *Config) {
+		if c.CharacterDatabaseMax == 0 {
+			c.CharacterDatabaseMax = getDefaultConfig().CharacterDatabaseMax
+		}
+	},
+	// 26 -> 27: telnet who dump window
+	func(c *Config) {
+		if c.Telnet.WhoDumpWindow == 0 {
+			c.Telnet.WhoDumpWindow = getDefaultConfig().Telnet.WhoDumpWindow
+		}
+	},
+	// 27 -> 28: anchor the default deaths pattern, so chat quoting a death is not posted as one
+	func(c *Config) {
+		if c.Telnet.Deaths.Regex == `(\w+) (?:has been|was) slain by (.+) in (.+?)[.!]?$` {
+			c.Telnet.Deaths.Regex = getDefaultConfig().Telnet.Deaths.Regex
+		}
+	},
+	// 28 -> 29: anchor the default spawn alert pattern, so players cannot fake a spawn in chat
+	func(c *Config) {
+		if c.Telnet.SpawnAlert.Regex == `(.+) has spawned in (.+?)[.!]?$` {
+			c.Telnet.SpawnAlert.Regex = getDefaultConfig().Telnet.SpawnAlert.Regex
+		}
+	},
+	// 29 -> 30: anonymize secret. Nothing to fill in, a generated secret is kept in its own file so every start uses the same one
+	func(c *Config) {},
+	// 30 -> 31: in game command cooldowns
+	func(c *Config) {
+		if c.Telnet.Commands.Cooldowns == nil {
+			c.Telnet.Commands.Cooldowns = getDefaultConfig().Telnet.Commands.Cooldowns
+		}
+	},
+}
+
+// currentConfigVersion is the config_version of a fully migrated config, and must equal len(migrations)
+const currentConfigVersion = 31
+
+// migrate upgrades c to the current config version, returning true if any migration was applied
+func (c *Config) migrate() bool {
+	if c.ConfigVersion >= currentConfigVersion {
+		return false
+	}
+	for version := c.ConfigVersion; version < currentConfigVersion; version++ {
+		migrations[version](c)
+	}
+	tlog.Infof("[config] upgraded config from version %d to %d", c.ConfigVersion, currentConfigVersion)
+	c.ConfigVersion = currentConfigVersion
+	return true
+}
+
+// warnUndecoded warns about keys in the config that talkeq no longer uses
+func warnUndecoded(md toml.MetaData) {
+	for _, key := range md.Undecoded() {
+		tlog.Warnf("[config] %s is unknown or deprecated and will be ignored", key.String())
+	}
+}
+
+// writeUpgrade writes c to path.new. path is left untouched, so comments the operator wrote in it are kept.
+// Migrations only fill in defaults, so until path is upgraded every start builds the same path.new, which is then left alone
+func (c *Config) writeUpgrade(path string) error {
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(c)
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	old, err := os.ReadFile(path + ".new")
+	if err == nil && bytes.Equal(old, buf.Bytes()) {
+		tlog.Debugf("[config] %s is missing new options, %s.new already has them", path, path)
+		return nil
+	}
+	err = os.WriteFile(path+".new", buf.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	tlog.Warnf("[config] %s is missing new options, an upgraded copy was written to %s.new. Copy the new options into %s, or replace it with %s.new, to stop this warning", path, path, path, path)
+	return nil
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_migrate(t *testing.T) {
+	if len(migrations) != currentConfigVersion {
+		t.Fatalf("migrations wanted %d, got %d", currentConfigVersion, len(migrations))
+	}
+	cfg := Config{}
+	cfg.Discord.MaxMessageLength = 200
+	cfg.Telnet.Routes = []Route{{Trigger: Trigger{Regex: `(\w+) auctions, '(.*)'`}}}
+	if !cfg.migrate() {
+		t.Fatalf("migrate wanted true for version 0, got false")
+	}
+	if cfg.ConfigVersion != currentConfigVersion {
+		t.Fatalf("config version wanted %d, got %d", currentConfigVersion, cfg.ConfigVersion)
+	}
+	if cfg.Discord.MaxMessageLength != 200 {
+		t.Fatalf("max message length wanted 200, got %d", cfg.Discord.MaxMessageLength)
+	}
+	if cfg.Discord.BotStatusOffline == "" {
+		t.Fatalf("bot status offline wanted default, got empty")
+	}
+	if cfg.Telnet.Heartbeat.Command != "echo off" {
+		t.Fatalf("telnet heartbeat command wanted default, got %q", cfg.Telnet.Heartbeat.Command)
+	}
+	if cfg.AuctionParsing.PricePattern != defaultPricePattern {
+		t.Fatalf("auction price pattern wanted default, got %q", cfg.AuctionParsing.PricePattern)
+	}
+	if cfg.Telnet.Routes[0].IsAuctionEmbed {
+		t.Fatalf("existing auction route wanted left as text, got auction embed")
+	}
+	if cfg.migrate() {
+		t.Fatalf("migrate wanted false for current version, got true")
+	}
+
+	def := getDefaultConfig()
+	if def.migrate() {
+		t.Fatalf("default config should already be current version")
+	}
+}
+
+func TestConfig_writeUpgrade(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "talkeq.conf")
+	original := "# my server\nconfig_version = 1\n"
+	err := os.WriteFile(path, []byte(original), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+	cfg := Config{ConfigVersion: 1}
+	cfg.migrate()
+	err = cfg.writeUpgrade(path)
+	if err != nil {
+		t.Fatalf("writeUpgrade: %s", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	if string(data) != original {
+		t.Fatalf("writeUpgrade changed %s, got %q", path, string(data))
+	}
+	data, err = os.ReadFile(path + ".new")
+	if err != nil {
+		t.Fatalf("read upgraded copy: %s", err)
+	}
+	if !strings.Contains(string(data), "config_version") {
+		t.Fatalf("upgraded copy missing config_version, got %q", string(data))
+	}
+}
+
+func TestNewConfig_oldVersionTwice(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %s", err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatalf("chdir: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	original := "# my server\nconfig_version = 29\n"
+	err = os.WriteFile("talkeq.conf", []byte(original), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+
+	upgrades := []string{}
+	for i := 0; i < 2; i++ {
+		cfg, err := NewConfig(context.Background())
+		if err != nil {
+			t.Fatalf("new config %d: %s", i, err)
+		}
+		if cfg.ConfigVersion != currentConfigVersion {
+			t.Fatalf("new config %d version wanted %d, got %d", i, currentConfigVersion, cfg.ConfigVersion)
+		}
+		data, err := os.ReadFile("talkeq.conf.new")
+		if err != nil {
+			t.Fatalf("read upgraded copy %d: %s", i, err)
+		}
+		upgrades = append(upgrades, string(data))
+	}
+	if upgrades[0] != upgrades[1] {
+		t.Fatalf("loading an old config twice wanted the same upgraded copy")
+	}
+	data, err := os.ReadFile("talkeq.conf")
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	if string(data) != original {
+		t.Fatalf("talkeq.conf wanted untouched, got %q", string(data))
+	}
+}
@@ -1,39 +1,211 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"os"
+	"regexp"
+	"strings"
 	"text/template"
+
+	"github.com/xackery/talkeq/tlog"
 )
 
 // Route is how to route telnet messages
 type Route struct {
-	IsEnabled              bool    `toml:"enabled" desc:"Is route enabled?"`
-	Trigger                Trigger `toml:"trigger" desc:"condition to trigger route"`
-	Target                 string  `toml:"target" desc:"target service, e.g. telnet"`
-	ChannelID              string  `toml:"channel_id" desc:"Destination channel ID"`
-	GuildID                string  `toml:"guild_id,omitempty" desc:"Optional, Destination guild ID"`
-	MessagePattern         string  `toml:"message_pattern" desc:"Destination message in. E.g. {{.Name}} says {{.ChannelName}}, '{{.Message}}"`
+	IsEnabled              bool        `toml:"enabled" desc:"Is route enabled?"`
+	Trigger                Trigger     `toml:"trigger" desc:"condition to trigger route"`
+	Target                 string      `toml:"target" desc:"target service, e.g. telnet"`
+	ChannelID              string      `toml:"channel_id" desc:"Destination channel ID"`
+	ChannelIDs             []string    `toml:"channel_ids,omitempty" desc:"Optional, additional destination channel IDs the message is also sent to"`
+	GuildID                string      `toml:"guild_id,omitempty" desc:"Optional, Destination guild ID"`
+	MessagePattern         string      `toml:"message_pattern" desc:"Destination message in. E.g. {{.Name}} says {{.ChannelName}}, '{{.Message}}"`
+	IsTypingEnabled        bool        `toml:"typing_indicator,omitempty" desc:"Optional, show a discord typing indicator in the destination channel while EQ chat is active"`
+	Conditions             []Condition `toml:"conditions,omitempty" desc:"Optional, every condition must pass against the message for the route to send"`
+	IsAuctionEmbed         bool        `toml:"auction_embed,omitempty" desc:"Optional, send buy and sell messages as an embed listing each item and price"`
+	GuildThreadName        string      `toml:"guild_thread_name,omitempty" desc:"Optional, guild routes post each guild's chat in a thread of the destination channel with this name, e.g. {{.GuildName}}. {{.GuildID}} is also available\n# GuildName is the optional third field of the guilds database, guildid:channelid:guild name"`
+	Anonymize              string      `toml:"anonymize,omitempty" desc:"Optional, hide character names in relayed messages. anonymous shows every name as Anonymous, hash shows a short name like Anon-1a2b3c4d that is the same for each character\n# hash names are keyed with anonymize_secret, so they can not be traced back to a character without it\n# Moderation reactions can not find the original sender of anonymized messages"`
+	UseEmbed               string      `toml:"use_embed,omitempty" desc:"Optional, embed always sends messages as an embed, plain always sends plain text. If empty, only auction_embed messages are embeds"`
 	messagePatternTemplate *template.Template
+	threadNameTemplate     *template.Template
+	triggerRegex           *regexp.Regexp
+	stats                  *RouteStats
+	anonymizeKey           []byte
+}
+
+// Stats returns the match stats of the route
+func (r *Route) Stats() *RouteStats {
+	return r.stats
+}
+
+// DisplayName returns the name to relay for a character, anonymized if the route anonymizes names
+func (r *Route) DisplayName(name string) string {
+	if name == "" {
+		return name
+	}
+	switch r.Anonymize {
+	case "anonymous":
+		return "Anonymous"
+	case "hash":
+		h := hmac.New(sha256.New, r.anonymizeKey)
+		h.Write([]byte(strings.ToLower(name)))
+		return fmt.Sprintf("Anon-%x", h.Sum(nil)[:4])
+	}
+	return name
+}
+
+// anonymizeSecretFile keeps the generated anonymize secret, beside the config, when anonymize_secret is empty
+const anonymizeSecretFile = "talkeq_anonymize_secret.txt"
+
+// loadAnonymizeSecret returns the secret saved in path, generating and saving a random one if there is none yet
+func loadAnonymizeSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret != "" {
+		return secret, nil
+	}
+
+	raw := make([]byte, 32)
+	_, err = rand.Read(raw)
+	if err != nil {
+		return "", fmt.Errorf("rand: %w", err)
+	}
+	secret = hex.EncodeToString(raw)
+	err = os.WriteFile(path, []byte(secret+"\n"), 0600)
+	if err != nil {
+		return "", fmt.Errorf("write %s: %w", path, err)
+	}
+	tlog.Infof("[config] generated an anonymize secret for hashed names, saved to %s", path)
+	return secret, nil
+}
+
+// GuildThreadNameTemplate returns the parsed guild_thread_name template, or nil if threads are not used
+func (r *Route) GuildThreadNameTemplate() *template.Template {
+	return r.threadNameTemplate
+}
+
+// Unmatched relays lines that matched no enabled route, to help find message formats that need a new trigger
+type Unmatched struct {
+	IsEnabled bool   `toml:"enabled" desc:"Relay lines that matched no enabled route"`
+	ChannelID string `toml:"channel_id" desc:"Optional, discord channel ID to relay unmatched lines to. If empty, unmatched lines are only logged at debug level"`
+}
+
+// Condition filters a route by message content, after the trigger matched
+type Condition struct {
+	Contains    string `toml:"contains,omitempty" desc:"Message must contain this text (case insensitive)"`
+	NotContains string `toml:"not_contains,omitempty" desc:"Message must not contain this text (case insensitive)"`
+	Regex       string `toml:"regex,omitempty" desc:"Message must match this regex"`
+	regex       *regexp.Regexp
+}
+
+// IsMatch returns true if message passes every condition of the route
+func (r *Route) IsMatch(message string) bool {
+	for i := range r.Conditions {
+		if !r.Conditions[i].IsMatch(message) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsMatch returns true if message passes the condition
+func (c *Condition) IsMatch(message string) bool {
+	lowerMessage := strings.ToLower(message)
+	if c.Contains != "" && !strings.Contains(lowerMessage, strings.ToLower(c.Contains)) {
+		return false
+	}
+	if c.NotContains != "" && strings.Contains(lowerMessage, strings.ToLower(c.NotContains)) {
+		return false
+	}
+	if c.Regex != "" {
+		// the regex is compiled by LoadMessagePattern, a condition that was not loaded never passes
+		if c.regex == nil || !c.regex.MatchString(message) {
+			return false
+		}
+	}
+	return true
+}
+
+// Destinations returns every channel ID the route sends to, ChannelID first
+func (r *Route) Destinations() []string {
+	destinations := []string{}
+	if r.ChannelID != "" {
+		destinations = append(destinations, r.ChannelID)
+	}
+	for _, channelID := range r.ChannelIDs {
+		if channelID == "" || channelID == r.ChannelID {
+			continue
+		}
+		destinations = append(destinations, channelID)
+	}
+	return destinations
+}
+
+// HasDestination returns true if the route sends to provided channel ID
+func (r *Route) HasDestination(channelID string) bool {
+	for _, destination := range r.Destinations() {
+		if destination == channelID {
+			return true
+		}
+	}
+	return false
 }
 
-// MessagePatternTemplate returns a template for provided route
+// TriggerRegex returns the trigger regex compiled by LoadMessagePattern
+func (r *Route) TriggerRegex() (*regexp.Regexp, error) {
+	if r.triggerRegex == nil {
+		return nil, fmt.Errorf("trigger regex %q is not loaded", r.Trigger.Regex)
+	}
+	return r.triggerRegex, nil
+}
+
+// MessagePatternTemplate returns the template parsed by LoadMessagePattern.
+// If the route was not loaded, an empty template is returned, which fails to execute
 func (r *Route) MessagePatternTemplate() *template.Template {
 	if r.messagePatternTemplate == nil {
-		// fallback logic
-		r.messagePatternTemplate, _ = template.New("root").Parse(r.MessagePattern)
+		return template.New("root")
 	}
 	return r.messagePatternTemplate
 }
 
-// LoadMessagePattern is called after config is loaded, and verified patterns are valid
+// LoadMessagePattern is called after config is loaded, and verified patterns are valid.
+// Disabled routes are loaded too, since /bridge can enable them later while routes are read concurrently
 func (r *Route) LoadMessagePattern() error {
-	if !r.IsEnabled {
-		return nil
+	if r.stats == nil {
+		// routes can be enabled later with /bridge, so disabled routes are counted too
+		r.stats = &RouteStats{}
 	}
 	var err error
 	r.messagePatternTemplate, err = template.New("root").Parse(r.MessagePattern)
 	if err != nil {
 		return fmt.Errorf("failed to parse: %w", err)
 	}
+	if r.GuildThreadName != "" {
+		r.threadNameTemplate, err = template.New("thread").Parse(r.GuildThreadName)
+		if err != nil {
+			return fmt.Errorf("guild thread name: %w", err)
+		}
+	}
+	if r.Trigger.Custom == "" {
+		r.triggerRegex, err = regexp.Compile(r.Trigger.Regex)
+		if err != nil {
+			return fmt.Errorf("trigger regex: %w", err)
+		}
+	}
+	for i := range r.Conditions {
+		if r.Conditions[i].Regex == "" {
+			continue
+		}
+		r.Conditions[i].regex, err = regexp.Compile(r.Conditions[i].Regex)
+		if err != nil {
+			return fmt.Errorf("condition %d regex: %w", i, err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// RouteStats counts how often a route's trigger matched since talkeq started.
+// Copies of a route share the same stats, so endpoints can count on the copy they range over
+type RouteStats struct {
+	matches int64
+	// lastMatch is unix nanoseconds of the last match
+	lastMatch int64
+}
+
+// Matched records a trigger match
+func (s *RouteStats) Matched() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.matches, 1)
+	atomic.StoreInt64(&s.lastMatch, time.Now().UnixNano())
+}
+
+// Matches returns how many times the trigger matched
+func (s *RouteStats) Matches() int64 {
+	if s == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&s.matches)
+}
+
+// LastMatch returns when the trigger last matched, or a zero time if it never matched
+func (s *RouteStats) LastMatch() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	lastMatch := atomic.LoadInt64(&s.lastMatch)
+	if lastMatch == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, lastMatch)
+}
+
+// SourceRoutes are the routes of one source
+type SourceRoutes struct {
+	Source string
+	Routes []Route
+}
+
+// SourceRoutes returns the routes of every source that uses telnet style routes
+func (c *Config) SourceRoutes() []SourceRoutes {
+	return []SourceRoutes{
+		{Source: "telnet", Routes: c.Telnet.Routes},
+		{Source: "eqlog", Routes: c.EQLog.Routes},
+		{Source: "peq_editor", Routes: c.PEQEditor.SQL.Routes},
+	}
+}
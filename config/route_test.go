@@ -1,7 +1,10 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"text/template"
 )
@@ -75,3 +78,146 @@ func TestRoute_LoadMessagePattern(t *testing.T) {
 		})
 	}
 }
+
+func TestRoute_Destinations(t *testing.T) {
+	tests := []struct {
+		name       string
+		channelID  string
+		channelIDs []string
+		want       []string
+	}{
+		{"single", "1", nil, []string{"1"}},
+		{"fan out", "1", []string{"2", "3"}, []string{"1", "2", "3"}},
+		{"duplicate", "1", []string{"1", "2"}, []string{"1", "2"}},
+		{"only list", "", []string{"2"}, []string{"2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Route{ChannelID: tt.channelID, ChannelIDs: tt.channelIDs}
+			if got := r.Destinations(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Route.Destinations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoute_IsMatch(t *testing.T) {
+	r := &Route{
+		Conditions: []Condition{
+			{Contains: "lfg"},
+			{NotContains: "wts"},
+			{Regex: `\d+`},
+		},
+	}
+	err := r.LoadMessagePattern()
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"LFG level 50 cleric", true},
+		{"LFG cleric", false},
+		{"WTS LFG 50", false},
+		{"hello 50", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if got := r.IsMatch(tt.message); got != tt.want {
+				t.Errorf("Route.IsMatch(%q) = %v, want %v", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoute_DisplayName(t *testing.T) {
+	tests := []struct {
+		anonymize string
+		name      string
+		want      string
+	}{
+		{"", "Shin", "Shin"},
+		{"anonymous", "Shin", "Anonymous"},
+		{"anonymous", "", ""},
+	}
+	for _, tt := range tests {
+		r := &Route{Anonymize: tt.anonymize}
+		if got := r.DisplayName(tt.name); got != tt.want {
+			t.Errorf("DisplayName(%q) with anonymize %q = %q, want %q", tt.name, tt.anonymize, got, tt.want)
+		}
+	}
+
+	r := &Route{Anonymize: "hash", anonymizeKey: []byte("secret")}
+	hashed := r.DisplayName("Shin")
+	if hashed == "Shin" || !strings.HasPrefix(hashed, "Anon-") {
+		t.Fatalf("DisplayName() hash = %q, want Anon- prefix", hashed)
+	}
+	if r.DisplayName("shin") != hashed {
+		t.Fatalf("DisplayName() hash wanted the same name regardless of case")
+	}
+	if r.DisplayName("Xackery") == hashed {
+		t.Fatalf("DisplayName() hash wanted different names for different characters")
+	}
+	other := &Route{Anonymize: "hash", anonymizeKey: []byte("other secret")}
+	if other.DisplayName("Shin") == hashed {
+		t.Fatalf("DisplayName() hash wanted different names for different secrets")
+	}
+}
+
+func TestRoute_DisplayName_stableSecret(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "talkeq.conf")
+	err := os.WriteFile(path, []byte(`config_version = 29
+[telnet]
+[[telnet.routes]]
+enabled = false
+anonymize = "hash"
+[telnet.routes.trigger]
+telnet_pattern = "(\\w+) says ooc, '(.*)'"
+`), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+
+	names := []string{}
+	for i := 0; i < 2; i++ {
+		cfg, err := Check(path)
+		if err != nil {
+			t.Fatalf("check %d: %s", i, err)
+		}
+		names = append(names, cfg.Telnet.Routes[0].DisplayName("Shin"))
+	}
+	if names[0] != names[1] {
+		t.Fatalf("loading an old config twice wanted the same hashed name, got %q and %q", names[0], names[1])
+	}
+	_, err = os.Stat(filepath.Join(dir, anonymizeSecretFile))
+	if err != nil {
+		t.Fatalf("wanted the generated secret saved beside the config: %s", err)
+	}
+}
+
+func TestRoute_LoadMessagePattern_disabled(t *testing.T) {
+	r := &Route{
+		Trigger:         Trigger{Regex: `(\w+) says ooc, '(.*)'`},
+		MessagePattern:  "{{.Name}}: {{.Message}}",
+		GuildThreadName: "{{.GuildName}}",
+	}
+	err := r.LoadMessagePattern()
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if r.GuildThreadNameTemplate() == nil {
+		t.Fatalf("disabled route wanted its guild thread name loaded, so /bridge can enable it")
+	}
+	_, err = r.TriggerRegex()
+	if err != nil {
+		t.Fatalf("disabled route wanted its trigger regex loaded: %s", err)
+	}
+
+	unloaded := &Route{MessagePattern: "{{.Name}}"}
+	err = unloaded.MessagePatternTemplate().Execute(new(strings.Builder), nil)
+	if err == nil {
+		t.Fatalf("unloaded route wanted an execute error")
+	}
+}
@@ -0,0 +1,417 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ValidationError is a single problem found while validating configuration
+type ValidationError struct {
+	Section string
+	Message string
+}
+
+// Error returns the problem prefixed by the section it was found in
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Section, e.Message)
+}
+
+// ValidationErrors is every problem found while validating configuration
+type ValidationErrors []ValidationError
+
+// Error returns all problems, one per line
+func (e ValidationErrors) Error() string {
+	lines := []string{}
+	for _, problem := range e {
+		lines = append(lines, problem.Error())
+	}
+	return fmt.Sprintf("%d problems found:\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+func (e *ValidationErrors) add(section string, format string, args ...interface{}) {
+	*e = append(*e, ValidationError{Section: section, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the entire configuration, and returns a ValidationErrors containing every problem found.
+// Unlike Verify, it does not stop on the first problem
+func (c *Config) Validate() error {
+	problems := ValidationErrors{}
+
+	if c.KeepAliveMaxRetries < 0 {
+		problems.add("talkeq", "keep_alive_max_retries must be 0 or more")
+	}
+	if c.KeepAliveChannelID != "" && !isNumeric(c.KeepAliveChannelID) {
+		problems.add("talkeq", "keep_alive_channel_id %q is not a discord channel id", c.KeepAliveChannelID)
+	}
+	if c.KeepAliveRetry != "" {
+		_, err := time.ParseDuration(c.KeepAliveRetry)
+		if err != nil {
+			problems.add("talkeq", "keep_alive_retry %q is not a valid duration (e.g. 10s)", c.KeepAliveRetry)
+		}
+	}
+
+	switch strings.ToLower(c.LogLevel) {
+	case "", "trace", "debug", "info", "warn", "error":
+	default:
+		problems.add("talkeq", "log_level %q must be trace, debug, info, warn or error", c.LogLevel)
+	}
+	for component, level := range c.LogLevels {
+		switch strings.ToLower(level) {
+		case "trace", "debug", "info", "warn", "error":
+		default:
+			problems.add("talkeq", "log_levels %s %q must be trace, debug, info, warn or error", component, level)
+		}
+	}
+
+	if c.Discord.IsEnabled {
+		_, err := template.New("status").Parse(c.Discord.BotStatus)
+		if err != nil {
+			problems.add("discord", "bot_status: %s", err)
+		}
+		for _, serverID := range c.Discord.ServerIDs {
+			if !isNumeric(serverID) {
+				problems.add("discord", "server_ids %q is not a discord server id", serverID)
+			}
+		}
+		if c.Discord.OnlineCountChannelID != "" && !isNumeric(c.Discord.OnlineCountChannelID) {
+			problems.add("discord", "online_count_channel_id %q is not a discord channel id", c.Discord.OnlineCountChannelID)
+		}
+		_, err = template.New("online").Parse(c.Discord.OnlineCountName)
+		if err != nil {
+			problems.add("discord", "online_count_name: %s", err)
+		}
+		for i, route := range c.Discord.Routes {
+			if !route.IsEnabled {
+				continue
+			}
+			if !isNumeric(route.Trigger.ChannelID) {
+				problems.add("discord", "route %d: discord_trigger channel_id %q is not a discord channel id", i, route.Trigger.ChannelID)
+			}
+			if route.Target == "telnet" && !isNumeric(c.Discord.ChannelNumber(route.ChannelID)) {
+				problems.add("discord", "route %d: channel_id %q is not an EQ channel number or a channel_numbers name", i, route.ChannelID)
+			}
+			_, err = template.New("root").Parse(route.MessagePattern)
+			if err != nil {
+				problems.add("discord", "route %d: message_pattern: %s", i, err)
+			}
+		}
+		for name, number := range c.Discord.ChannelNumbers {
+			if number < 1 {
+				problems.add("discord", "channel_numbers %s must be greater than 0", name)
+			}
+		}
+		if c.Discord.IsAFKEnabled && (!c.Telnet.IsEnabled || !c.Telnet.TellDM.IsEnabled) {
+			problems.add("discord", "afk_enabled requires telnet and telnet tell_dm to be enabled")
+		}
+		for i, moderation := range c.Discord.Moderation {
+			if !moderation.IsEnabled {
+				continue
+			}
+			_, err = template.New("root").Parse(moderation.MessagePattern)
+			if err != nil {
+				problems.add("discord", "moderation %d: message_pattern: %s", i, err)
+			}
+		}
+	}
+
+	if c.Telnet.IsEnabled {
+		validateRoutes(&problems, "telnet", c.Telnet.Routes)
+		switch c.Telnet.WhoFormat {
+		case "", "auto", "legacy", "v2":
+		default:
+			problems.add("telnet", "who_format %q must be auto, legacy or v2", c.Telnet.WhoFormat)
+		}
+		validatePlayerNotification(&problems, "zone_change", &c.Telnet.ZoneChange)
+		validatePlayerNotification(&problems, "level_up", &c.Telnet.LevelUp)
+		validatePlayerNotification(&problems, "returning_player", &c.Telnet.ReturningPlayer)
+		if c.Telnet.ReturningPlayer.IsEnabled && c.Telnet.ReturningPlayerDays < 1 {
+			problems.add("telnet", "returning_player_days %d must be at least 1", c.Telnet.ReturningPlayerDays)
+		}
+		if c.Telnet.TellDM.IsEnabled {
+			pattern, err := regexp.Compile(c.Telnet.TellDM.Regex)
+			if err != nil {
+				problems.add("telnet", "tell_dm telnet_pattern: %s", err)
+			} else {
+				groups := pattern.NumSubexp()
+				if c.Telnet.TellDM.FromIndex < 1 || c.Telnet.TellDM.FromIndex > groups ||
+					c.Telnet.TellDM.ToIndex < 1 || c.Telnet.TellDM.ToIndex > groups ||
+					c.Telnet.TellDM.MessageIndex < 1 || c.Telnet.TellDM.MessageIndex > groups {
+					problems.add("telnet", "tell_dm from_index, to_index and message_index must be between 1 and the %d groups in telnet_pattern", groups)
+				}
+			}
+		}
+		if c.Telnet.Deaths.IsEnabled {
+			if !isNumeric(c.Telnet.Deaths.ChannelID) {
+				problems.add("telnet", "deaths channel_id %q is not a discord channel id", c.Telnet.Deaths.ChannelID)
+			}
+			pattern, err := regexp.Compile(c.Telnet.Deaths.Regex)
+			if err != nil {
+				problems.add("telnet", "deaths telnet_pattern: %s", err)
+			} else {
+				groups := pattern.NumSubexp()
+				if c.Telnet.Deaths.NameIndex < 1 || c.Telnet.Deaths.NameIndex > groups ||
+					c.Telnet.Deaths.KillerIndex > groups || c.Telnet.Deaths.ZoneIndex > groups {
+					problems.add("telnet", "deaths name_index, killer_index and zone_index must be at most the %d groups in telnet_pattern, and name_index at least 1", groups)
+				}
+			}
+			_, err = template.New("root").Parse(c.Telnet.Deaths.MessagePattern)
+			if err != nil {
+				problems.add("telnet", "deaths message_pattern: %s", err)
+			}
+		}
+		if c.Telnet.SpawnAlert.IsEnabled {
+			if !isNumeric(c.Telnet.SpawnAlert.ChannelID) {
+				problems.add("telnet", "spawn_alert channel_id %q is not a discord channel id", c.Telnet.SpawnAlert.ChannelID)
+			}
+			if c.Telnet.SpawnAlert.RoleID != "" && !isNumeric(c.Telnet.SpawnAlert.RoleID) {
+				problems.add("telnet", "spawn_alert role_id %q is not a discord role id", c.Telnet.SpawnAlert.RoleID)
+			}
+			pattern, err := regexp.Compile(c.Telnet.SpawnAlert.Regex)
+			if err != nil {
+				problems.add("telnet", "spawn_alert telnet_pattern: %s", err)
+			} else {
+				groups := pattern.NumSubexp()
+				if c.Telnet.SpawnAlert.MobIndex < 1 || c.Telnet.SpawnAlert.MobIndex > groups || c.Telnet.SpawnAlert.ZoneIndex > groups {
+					problems.add("telnet", "spawn_alert mob_index and zone_index must be at most the %d groups in telnet_pattern, and mob_index at least 1", groups)
+				}
+			}
+			if len(c.Telnet.SpawnAlert.Mobs) == 0 {
+				problems.add("telnet", "spawn_alert mobs is empty, no spawns will be alerted on")
+			}
+			_, err = template.New("root").Parse(c.Telnet.SpawnAlert.MessagePattern)
+			if err != nil {
+				problems.add("telnet", "spawn_alert message_pattern: %s", err)
+			}
+		}
+		if c.Telnet.Loot.IsEnabled {
+			loot := c.Telnet.Loot
+			if !isNumeric(loot.ChannelID) {
+				problems.add("telnet", "loot channel_id %q is not a discord channel id", loot.ChannelID)
+			}
+			pattern, err := regexp.Compile(loot.Regex)
+			if err != nil {
+				problems.add("telnet", "loot telnet_pattern: %s", err)
+			} else {
+				groups := pattern.NumSubexp()
+				if loot.NameIndex < 1 || loot.NameIndex > groups || loot.ItemIndex < 1 || loot.ItemIndex > groups ||
+					loot.MobIndex > groups || loot.RarityIndex > groups {
+					problems.add("telnet", "loot name_index, item_index, mob_index and rarity_index must be at most the %d groups in telnet_pattern, and name_index and item_index at least 1", groups)
+				}
+			}
+			if loot.MinRarity != "" {
+				if loot.RarityIndex < 1 {
+					problems.add("telnet", "loot min_rarity is set, but rarity_index is not")
+				}
+				isKnown := false
+				for _, rarity := range loot.Rarities {
+					if strings.EqualFold(rarity, loot.MinRarity) {
+						isKnown = true
+					}
+				}
+				if !isKnown {
+					problems.add("telnet", "loot min_rarity %q is not in rarities", loot.MinRarity)
+				}
+			}
+			_, err = template.New("root").Parse(loot.MessagePattern)
+			if err != nil {
+				problems.add("telnet", "loot message_pattern: %s", err)
+			}
+		}
+		if c.Telnet.LFG.IsEnabled {
+			lfg := c.Telnet.LFG
+			if !isNumeric(lfg.ChannelID) {
+				problems.add("telnet", "lfg channel_id %q is not a discord channel id", lfg.ChannelID)
+			}
+			pattern, err := regexp.Compile(lfg.Regex)
+			if err != nil {
+				problems.add("telnet", "lfg telnet_pattern: %s", err)
+			} else {
+				groups := pattern.NumSubexp()
+				if lfg.NameIndex < 1 || lfg.NameIndex > groups || lfg.MessageIndex < 1 || lfg.MessageIndex > groups {
+					problems.add("telnet", "lfg name_index and message_index must be between 1 and the %d groups in telnet_pattern", groups)
+				}
+			}
+		}
+		if c.Telnet.RegisterCode.IsEnabled {
+			register := c.Telnet.RegisterCode
+			if !c.API.IsEnabled || !c.API.APIRegister.IsEnabled {
+				problems.add("telnet", "register_code requires api and api register to be enabled")
+			}
+			pattern, err := regexp.Compile(register.Regex)
+			if err != nil {
+				problems.add("telnet", "register_code telnet_pattern: %s", err)
+			} else {
+				groups := pattern.NumSubexp()
+				if register.NameIndex < 1 || register.NameIndex > groups || register.MessageIndex < 1 || register.MessageIndex > groups {
+					problems.add("telnet", "register_code name_index and message_index must be between 1 and the %d groups in telnet_pattern", groups)
+				}
+			}
+		}
+		if c.Telnet.Commands.IsEnabled {
+			commands := c.Telnet.Commands
+			pattern, err := regexp.Compile(commands.Regex)
+			if err != nil {
+				problems.add("telnet", "commands telnet_pattern: %s", err)
+			} else {
+				groups := pattern.NumSubexp()
+				if commands.NameIndex < 1 || commands.NameIndex > groups || commands.MessageIndex < 1 || commands.MessageIndex > groups {
+					problems.add("telnet", "commands name_index and message_index must be between 1 and the %d groups in telnet_pattern", groups)
+				}
+			}
+			_, err = template.New("root").Parse(commands.ReplyPattern)
+			if err != nil {
+				problems.add("telnet", "commands reply_pattern: %s", err)
+			}
+		}
+		if c.Telnet.Unmatched.IsEnabled && c.Telnet.Unmatched.ChannelID != "" && !isNumeric(c.Telnet.Unmatched.ChannelID) {
+			problems.add("telnet", "unmatched channel_id %q is not a discord channel id", c.Telnet.Unmatched.ChannelID)
+		}
+	}
+
+	if c.EQLog.IsEnabled {
+		validateRoutes(&problems, "eqlog", c.EQLog.Routes)
+		if c.EQLog.Unmatched.IsEnabled && c.EQLog.Unmatched.ChannelID != "" && !isNumeric(c.EQLog.Unmatched.ChannelID) {
+			problems.add("eqlog", "unmatched channel_id %q is not a discord channel id", c.EQLog.Unmatched.ChannelID)
+		}
+	}
+
+	if c.PEQEditor.IsEnabled && c.PEQEditor.SQL.IsEnabled {
+		validateRoutes(&problems, "peq_editor", c.PEQEditor.SQL.Routes)
+	}
+
+	if c.SQLReport.IsEnabled {
+		for i, e := range c.SQLReport.Entries {
+			if !isNumeric(e.ChannelID) {
+				problems.add("sql_report", "entry %d: channel_id %q is not a discord channel id", i, e.ChannelID)
+			}
+			refresh, err := time.ParseDuration(e.Refresh)
+			if err != nil {
+				problems.add("sql_report", "entry %d: refresh %q is not a valid duration (e.g. 60s)", i, e.Refresh)
+			} else if refresh < 30*time.Second {
+				problems.add("sql_report", "entry %d: refresh %s is lower than 30s", i, e.Refresh)
+			}
+			_, err = template.New("pattern").Parse(e.Pattern)
+			if err != nil {
+				problems.add("sql_report", "entry %d: pattern: %s", i, err)
+			}
+		}
+	}
+
+	if c.AuctionDigest.IsEnabled && !isNumeric(c.AuctionDigest.ChannelID) {
+		problems.add("auction_digest", "channel_id %q is not a discord channel id", c.AuctionDigest.ChannelID)
+	}
+
+	// empty patterns are set to the defaults by Verify
+	if c.AuctionParsing.SeparatorPattern != "" {
+		_, err := regexp.Compile(c.AuctionParsing.SeparatorPattern)
+		if err != nil {
+			problems.add("auction_parsing", "separator_pattern: %s", err)
+		}
+	}
+	if c.AuctionParsing.PricePattern != "" {
+		pattern, err := regexp.Compile(c.AuctionParsing.PricePattern)
+		if err != nil {
+			problems.add("auction_parsing", "price_pattern: %s", err)
+		} else if pattern.NumSubexp() < 2 {
+			problems.add("auction_parsing", "price_pattern needs an amount and a unit group, has %d groups", pattern.NumSubexp())
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// validateRoutes checks trigger regexes, indexes, destinations and message patterns of enabled routes
+func validateRoutes(problems *ValidationErrors, section string, routes []Route) {
+	for i, route := range routes {
+		if !route.IsEnabled {
+			continue
+		}
+		if route.Trigger.Custom == "" {
+			pattern, err := regexp.Compile(route.Trigger.Regex)
+			if err != nil {
+				problems.add(section, "route %d: telnet_pattern: %s", i, err)
+			} else {
+				groups := pattern.NumSubexp()
+				if route.Trigger.NameIndex > groups {
+					problems.add(section, "route %d: name_index %d is greater than the %d groups in telnet_pattern", i, route.Trigger.NameIndex, groups)
+				}
+				if route.Trigger.MessageIndex > groups {
+					problems.add(section, "route %d: message_index %d is greater than the %d groups in telnet_pattern", i, route.Trigger.MessageIndex, groups)
+				}
+				if route.Trigger.GuildIndex > groups {
+					problems.add(section, "route %d: guild_index %d is greater than the %d groups in telnet_pattern", i, route.Trigger.GuildIndex, groups)
+				}
+			}
+		}
+
+		// guild routes are mapped to a channel via the guilds database, and only fall back to channel_id
+		if route.Target == "discord" && route.Trigger.GuildIndex == 0 && (route.ChannelID != "" || len(route.ChannelIDs) == 0) && !isNumeric(route.ChannelID) {
+			problems.add(section, "route %d: channel_id %q is not a discord channel id", i, route.ChannelID)
+		}
+		for _, channelID := range route.ChannelIDs {
+			if route.Target == "discord" && !isNumeric(channelID) {
+				problems.add(section, "route %d: channel_ids %q is not a discord channel id", i, channelID)
+			}
+		}
+
+		_, err := template.New("root").Parse(route.MessagePattern)
+		if err != nil {
+			problems.add(section, "route %d: message_pattern: %s", i, err)
+		}
+
+		if route.GuildThreadName != "" {
+			_, err = template.New("thread").Parse(route.GuildThreadName)
+			if err != nil {
+				problems.add(section, "route %d: guild_thread_name: %s", i, err)
+			}
+		}
+
+		if route.Anonymize != "" && route.Anonymize != "anonymous" && route.Anonymize != "hash" {
+			problems.add(section, "route %d: anonymize %q must be anonymous, hash or empty", i, route.Anonymize)
+		}
+
+		if route.UseEmbed != "" && route.UseEmbed != "embed" && route.UseEmbed != "plain" {
+			problems.add(section, "route %d: use_embed %q must be embed, plain or empty", i, route.UseEmbed)
+		}
+
+		for j, condition := range route.Conditions {
+			if condition.Contains == "" && condition.NotContains == "" && condition.Regex == "" {
+				problems.add(section, "route %d: condition %d needs contains, not_contains or regex", i, j)
+			}
+			if condition.Regex == "" {
+				continue
+			}
+			_, err = regexp.Compile(condition.Regex)
+			if err != nil {
+				problems.add(section, "route %d: condition %d regex: %s", i, j, err)
+			}
+		}
+	}
+}
+
+// validatePlayerNotification checks the channel and message pattern of an enabled player notification
+func validatePlayerNotification(problems *ValidationErrors, name string, n *PlayerNotification) {
+	if !n.IsEnabled {
+		return
+	}
+	if !isNumeric(n.ChannelID) {
+		problems.add("telnet", "%s channel_id %q is not a discord channel id", name, n.ChannelID)
+	}
+	_, err := template.New("root").Parse(n.MessagePattern)
+	if err != nil {
+		problems.add("telnet", "%s message_pattern: %s", name, err)
+	}
+}
+
+// isNumeric returns true if value is a non-empty unsigned integer, like discord IDs and EQ channel numbers
+func isNumeric(value string) bool {
+	_, err := strconv.ParseUint(value, 10, 64)
+	return err == nil
+}
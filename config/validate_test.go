@@ -0,0 +1,41 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := getDefaultConfig()
+	cfg.Discord.Routes[0].Trigger.ChannelID = "123"
+	for i := range cfg.Telnet.Routes {
+		cfg.Telnet.Routes[i].ChannelID = "456"
+	}
+	cfg.EQLog.IsEnabled = false
+	err := cfg.Validate()
+	if err != nil {
+		t.Fatalf("validate default config: %s", err)
+	}
+	if number := cfg.Discord.ChannelNumber("OOC"); number != "260" {
+		t.Fatalf("channel number of OOC wanted 260, got %s", number)
+	}
+
+	cfg.KeepAliveRetry = "soon"
+	cfg.Telnet.Routes[0].Trigger.Regex = `(\w+ says`
+	cfg.Telnet.Routes[1].Trigger.MessageIndex = 5
+	cfg.Telnet.Routes[2].ChannelID = "INSERTGENERALCHANNELHERE"
+	cfg.Telnet.Routes[3].MessagePattern = "{{.Name"
+	cfg.Telnet.Routes[4].UseEmbed = "fancy"
+	cfg.Discord.Routes[0].ChannelID = "raid"
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatalf("validate wanted error, got nil")
+	}
+	problems := ValidationErrors{}
+	if !errors.As(err, &problems) {
+		t.Fatalf("validate wanted ValidationErrors, got %T", err)
+	}
+	if len(problems) != 7 {
+		t.Fatalf("validate wanted 7 problems, got %d: %s", len(problems), err)
+	}
+}
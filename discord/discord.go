@@ -3,10 +3,10 @@ package discord
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"net/http"
 	"regexp"
-	"runtime"
 	"strings"
 	"sync"
 	"text/template"
@@ -25,17 +25,77 @@ const (
 
 // Discord represents a discord connection
 type Discord struct {
-	ctx           context.Context
-	cancel        context.CancelFunc
-	isConnected   bool
-	mu            sync.RWMutex
-	config        config.Discord
-	conn          *discordgo.Session
-	subscribers   []func(interface{}) error
-	id            string
-	lastMessageID string
-	lastChannelID string
-	commands      map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) (string, error)
+	ctx         context.Context
+	cancel      context.CancelFunc
+	isConnected bool
+	mu          sync.RWMutex
+	config      config.Discord
+	rootConfig  *config.Config
+	conn        *discordgo.Session
+	subscribers []func(interface{}) error
+	id          string
+	commands    map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error)
+	typingMu    sync.Mutex
+	lastTyping  map[string]time.Time
+	relayMu     sync.Mutex
+	relays      map[string]string
+	relayOrder  []string
+	cooldowns   map[string]time.Time
+	auditMu     sync.Mutex
+	tellMu      sync.Mutex
+	lastTellDM  map[string]time.Time
+	// away are /afk messages keyed by discord ID
+	away map[string]string
+	// lastAwayReply is when an away reply was last sent, keyed by sender and recipient
+	lastAwayReply map[string]time.Time
+	retryMu       sync.Mutex
+	retryQueue    []pendingSend
+	droppedSends  int64
+	// flushMu is held while queued sends are retried, so they are sent once and in order
+	flushMu sync.Mutex
+	// lastOnlineCountName is the last name set on the online count channel
+	lastOnlineCountName string
+	renameMu            sync.Mutex
+	renames             map[string]*channelRename
+	lfgMu               sync.Mutex
+	lfgPosts            map[string]*lfgPost
+	threadMu            sync.Mutex
+	// threads are thread IDs keyed by parent channel ID and lowercase thread name
+	threads map[string]string
+	// forums caches if a channel ID is a forum channel
+	forums map[string]bool
+	// channelProblems are configured channels found unusable on connect
+	channelProblems []string
+	// existingCommands are slash commands already in syncGuildID while syncing commands
+	existingCommands map[string]*discordgo.ApplicationCommand
+	syncGuildID      string
+	// commandIDs are slash command IDs registered by talkeq, keyed by guild ID then name
+	commandIDs map[string]map[string]string
+	editMu     sync.Mutex
+	// edits are messages relayed in game, keyed by discord message ID, so edits and deletes can be relayed
+	edits     map[string]relayedMessage
+	editOrder []string
+	// connMu guards conn and isConnected, so they can be read without t.mu while a reconnect replaces them
+	connMu sync.RWMutex
+	// connCancel stops the loop of the current connection, and is replaced on each connect
+	connCancel context.CancelFunc
+	// unusableRoutes are indexes of routes disabled on connect because their channel is missing or not allowed.
+	// They are enabled and checked again on the next connect
+	unusableRoutes map[int]bool
+}
+
+// SetRootConfig gives discord access to the entire config, used by admin commands
+func (t *Discord) SetRootConfig(cfg *config.Config) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rootConfig = cfg
+}
+
+// rootCfg returns the config set by SetRootConfig, or nil if it was not set
+func (t *Discord) rootCfg() *config.Config {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.rootConfig
 }
 
 // New creates a new discord connect
@@ -43,12 +103,32 @@ func New(ctx context.Context, config config.Discord) (*Discord, error) {
 	ctx, cancel := context.WithCancel(ctx)
 
 	t := &Discord{
-		ctx:    ctx,
-		cancel: cancel,
-		config: config,
-	}
-	t.commands = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) (string, error){
-		"who": t.who,
+		ctx:        ctx,
+		cancel:     cancel,
+		config:     config,
+		lastTyping: make(map[string]time.Time),
+		relays:     make(map[string]string),
+		cooldowns:  make(map[string]time.Time),
+		lastTellDM: make(map[string]time.Time),
+		away:       make(map[string]string),
+		renames:    make(map[string]*channelRename),
+		lfgPosts:   make(map[string]*lfgPost),
+		threads:    make(map[string]string),
+		forums:     make(map[string]bool),
+		edits:      make(map[string]relayedMessage),
+
+		unusableRoutes: make(map[int]bool),
+	}
+	t.commands = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error){
+		"who":       t.who,
+		"bridge":    t.bridge,
+		"config":    t.configCmd,
+		"tells":     t.tells,
+		"search":    t.search,
+		"market":    t.market,
+		"routes":    t.routes,
+		"reconnect": t.reconnect,
+		"afk":       t.afk,
 	}
 
 	t.mu.Lock()
@@ -72,6 +152,10 @@ func New(ctx context.Context, config config.Discord) (*Discord, error) {
 		return nil, fmt.Errorf("server_id must be set. On discord, right click your server's icon on very left, and Copy ID, and place it in talkeq.conf in the server_id section")
 	}
 
+	go t.retryLoop(ctx)
+	go t.renameLoop(ctx)
+	go t.lfgLoop(ctx)
+
 	return t, nil
 }
 
@@ -86,52 +170,60 @@ func (t *Discord) Connect(ctx context.Context) error {
 		return nil
 	}
 
-	tlog.Infof("[discord] connecting to server_id %s...", t.config.ServerID)
+	tlog.Infof("[discord] connecting to server_id %s...", strings.Join(t.config.Servers(), ", "))
 
-	if t.conn != nil {
-		t.conn.Close()
-		t.conn = nil
-		t.cancel()
+	if previous := t.session(); previous != nil {
+		t.setSession(nil)
+		previous.Close()
 	}
-	t.ctx, t.cancel = context.WithCancel(ctx)
+	t.stopLoop()
 
-	t.conn, err = discordgo.New("Bot " + t.config.Token)
+	conn, err := discordgo.New("Bot " + t.config.Token)
 	if err != nil {
 		return fmt.Errorf("new: %w", err)
 	}
 
-	t.conn.StateEnabled = true
-	t.conn.AddHandler(t.handleMessage)
-	t.conn.AddHandler(t.handleCommand)
+	conn.StateEnabled = true
+	conn.AddHandler(t.handleMessage)
+	conn.AddHandler(t.handleCommand)
+	conn.AddHandler(t.handleReaction)
+	conn.AddHandler(t.handleMessageUpdate)
+	conn.AddHandler(t.handleMessageDelete)
 
-	err = t.conn.Open()
+	err = conn.Open()
 	if err != nil {
 		return fmt.Errorf("open: %w", err)
 	}
 
-	go t.loop(ctx)
+	loopCtx, cancel := context.WithCancel(ctx)
+	t.connCancel = cancel
+	go t.loop(loopCtx)
 
-	t.isConnected = true
+	t.setSession(conn)
 	tlog.Infof("[discord] connected successfully")
+	t.resumeUnusableRoutes()
 	var st *discordgo.Channel
-	for _, route := range t.config.Routes {
+	for routeIndex := range t.config.Routes {
+		route := &t.config.Routes[routeIndex]
 		if !route.IsEnabled {
 			continue
 		}
-		st, err = t.conn.Channel(route.Trigger.ChannelID)
+		st, err = conn.Channel(route.Trigger.ChannelID)
 		if err != nil {
-			tlog.Errorf("[discord] your bot appears to not be allowed to listen to route %s's channel %s. visit https://discordapp.com/oauth2/authorize?&client_id=%s&scope=bot&permissions=268504080 and authorize", route.Target, route.Trigger.ChannelID, t.config.ClientID)
-			if runtime.GOOS == "windows" {
-				option := ""
-				fmt.Println("press a key then enter to exit.")
-				fmt.Scan(&option)
+			if !isChannelUnusable(err) {
+				tlog.Warnf("[discord] route %d could not check channel %s, keeping it enabled: %s", routeIndex, route.Trigger.ChannelID, err)
+				continue
 			}
-			os.Exit(1)
+			// one unusable channel should not stop the other routes, so only this route is disabled
+			tlog.Warnf("[discord] route %d disabled: your bot appears to not be allowed to listen to channel %s (%s). Check the channel id, or visit https://discordapp.com/oauth2/authorize?&client_id=%s&scope=bot&permissions=268504080 and authorize, then use /reconnect discord to check it again", routeIndex, route.Trigger.ChannelID, err, t.config.ClientID)
+			route.IsEnabled = false
+			t.unusableRoutes[routeIndex] = true
+			continue
 		}
 		tlog.Infof("[discord->%s] registered route for chat in #%s", route.Target, st.Name)
 	}
 
-	myUser, err := t.conn.User("@me")
+	myUser, err := conn.User("@me")
 	if err != nil {
 		return fmt.Errorf("get my username: %w", err)
 	}
@@ -139,16 +231,17 @@ func (t *Discord) Connect(ctx context.Context) error {
 	t.id = myUser.ID
 	tlog.Debugf("[discord] @me id: %s", t.id)
 
-	err = t.StatusUpdate(ctx, 0, "Status: Online")
+	t.checkChannels()
+
+	err = t.StatusUpdate(ctx, true, 0, "Status: Online")
 	if err != nil {
 		return err
 	}
 
-	// TODO: Get this working
-	if 1 == 0 {
-		err = t.whoRegister()
+	if t.config.IsCommandsEnabled {
+		err = t.syncCommands()
 		if err != nil {
-			return fmt.Errorf("whoRegister: %w", err)
+			return fmt.Errorf("syncCommands: %w", err)
 		}
 	}
 
@@ -161,18 +254,73 @@ func (t *Discord) loop(ctx context.Context) {
 		case <-ctx.Done():
 			tlog.Debugf("[discord] loop exit")
 			return
-		default:
+		case <-time.After(60 * time.Second):
+		}
+
+		t.mu.Lock()
+		for key, expiry := range t.cooldowns {
+			if time.Now().After(expiry) {
+				delete(t.cooldowns, key)
+			}
 		}
+		t.mu.Unlock()
+	}
+}
 
-		time.Sleep(60 * time.Second)
+// resumeUnusableRoutes enables routes disabled on a previous connect for an unusable channel, so they are checked again.
+// Must be called while holding t.mu
+func (t *Discord) resumeUnusableRoutes() {
+	for routeIndex := range t.unusableRoutes {
+		if routeIndex < len(t.config.Routes) {
+			t.config.Routes[routeIndex].IsEnabled = true
+		}
+		delete(t.unusableRoutes, routeIndex)
 	}
 }
 
-// StatusUpdate updates the status text on discord
-func (t *Discord) StatusUpdate(ctx context.Context, online int, customText string) error {
+// isChannelUnusable returns true if discord says a channel does not exist or the bot is not allowed to see it
+func isChannelUnusable(err error) bool {
+	restErr := &discordgo.RESTError{}
+	if !errors.As(err, &restErr) || restErr.Response == nil {
+		return false
+	}
+	return restErr.Response.StatusCode == http.StatusForbidden || restErr.Response.StatusCode == http.StatusNotFound
+}
+
+// stopLoop stops the loop of the current connection. Must be called while holding t.mu
+func (t *Discord) stopLoop() {
+	if t.connCancel == nil {
+		return
+	}
+	t.connCancel()
+	t.connCancel = nil
+}
+
+// StatusUpdate updates the status text on discord.
+// If the server is not online, the bot is set to do not disturb with the offline status text
+func (t *Discord) StatusUpdate(ctx context.Context, isServerOnline bool, online int, customText string) error {
 	var err error
+	conn := t.session()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	if !isServerOnline {
+		err = conn.UpdateStatusComplex(discordgo.UpdateStatusData{
+			Status: string(discordgo.StatusDoNotDisturb),
+			Activities: []*discordgo.Activity{
+				{
+					Name: t.config.BotStatusOffline,
+					Type: discordgo.ActivityTypeGame,
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return nil
+	}
 	if customText != "" {
-		err = t.conn.UpdateGameStatus(0, customText)
+		err = conn.UpdateGameStatus(0, customText)
 		if err != nil {
 			return err
 		}
@@ -188,7 +336,7 @@ func (t *Discord) StatusUpdate(ctx context.Context, online int, customText strin
 		online,
 	})
 
-	err = t.conn.UpdateGameStatus(0, buf.String())
+	err = conn.UpdateGameStatus(0, buf.String())
 	if err != nil {
 		return err
 	}
@@ -197,10 +345,9 @@ func (t *Discord) StatusUpdate(ctx context.Context, online int, customText strin
 
 // IsConnected returns if a connection is established
 func (t *Discord) IsConnected() bool {
-	t.mu.RLock()
-	isConnected := t.isConnected
-	t.mu.RUnlock()
-	return isConnected
+	t.connMu.RLock()
+	defer t.connMu.RUnlock()
+	return t.isConnected
 }
 
 // Disconnect stops a previously started connection with Discord.
@@ -210,38 +357,182 @@ func (t *Discord) Disconnect(ctx context.Context) error {
 		tlog.Debugf("[discord] is disabled, skipping disconnect")
 		return nil
 	}
-	if !t.isConnected {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopLoop()
+	if !t.IsConnected() {
 		tlog.Debugf("[discord] already disconnected, skipping disconnect")
 		return nil
 	}
-	err := t.conn.Close()
+	// the session is cleared first, so sends from now on are queued instead of using the closing session
+	conn := t.session()
+	t.setSession(nil)
+	if conn == nil {
+		return nil
+	}
+	if t.config.IsCommandCleanupEnabled {
+		t.deleteCommands(conn)
+	}
+	err := conn.Close()
 	if err != nil {
 		tlog.Warnf("[discord] disconnect failed: %s", err)
 	}
-	t.conn = nil
-	t.isConnected = false
 	return nil
 }
 
+// session returns the discord session, or nil if discord is not connected.
+// Safe to call while holding t.mu
+func (t *Discord) session() *discordgo.Session {
+	t.connMu.RLock()
+	defer t.connMu.RUnlock()
+	return t.conn
+}
+
+// setSession replaces the discord session, nil when disconnecting, and sets if discord is connected
+func (t *Discord) setSession(conn *discordgo.Session) {
+	t.connMu.Lock()
+	defer t.connMu.Unlock()
+	t.conn = conn
+	t.isConnected = conn != nil
+}
+
 // Send sends a message to discord
+// Failed sends that look transient are queued and retried with backoff, and are not an error
 func (t *Discord) Send(req request.DiscordSend) error {
+	_, err := t.SendWithResult(req)
+	return err
+}
+
+// SendWithResult sends a message to discord, and returns the ID of the sent message
+func (t *Discord) SendWithResult(req request.DiscordSend) (request.SendResult, error) {
+	result := request.SendResult{Endpoint: "discord", ChannelID: req.ChannelID}
 	if !t.config.IsEnabled {
-		return fmt.Errorf("not enabled")
+		return result, fmt.Errorf("not enabled")
 	}
 
-	if !t.isConnected {
-		return fmt.Errorf("not connected")
+	if !t.IsConnected() {
+		if !t.queueRetry(req) {
+			return result, fmt.Errorf("not connected, retry queue full, dropped")
+		}
+		tlog.Debugf("[discord] not connected, send to channel %s queued for retry", req.ChannelID)
+		result.IsQueued = true
+		return result, nil
+	}
+
+	// sends waiting to be retried go first, so messages arrive in the order they were sent
+	if !t.flushRetries() {
+		if !t.queueRetry(req) {
+			return result, fmt.Errorf("earlier sends waiting to be retried, retry queue full, dropped")
+		}
+		result.IsQueued = true
+		return result, nil
+	}
+
+	messageID, err := t.send(req)
+	if err != nil {
+		if !isRetryable(err) {
+			return result, err
+		}
+		if !t.queueRetry(req) {
+			return result, fmt.Errorf("%w (retry queue full, dropped)", err)
+		}
+		tlog.Debugf("[discord] send to channel %s failed, queued for retry: %s", req.ChannelID, err)
+		result.IsQueued = true
+		return result, nil
 	}
+	result.MessageID = messageID
+	return result, nil
+}
 
-	msg, err := t.conn.ChannelMessageSendComplex(req.ChannelID, &discordgo.MessageSend{
+// send sends a message to discord, returning the sent message ID
+func (t *Discord) send(req request.DiscordSend) (string, error) {
+	switch req.UseEmbed {
+	case "plain":
+		req.Embed = nil
+	case "embed":
+		if req.Embed == nil {
+			req.Embed = &request.DiscordEmbed{}
+		}
+	}
+	send := &discordgo.MessageSend{
 		Content:         req.Message,
 		AllowedMentions: &discordgo.MessageAllowedMentions{},
-	})
+	}
+	if req.Embed != nil {
+		send.Content = ""
+		send.Embeds = []*discordgo.MessageEmbed{messageEmbed(req.Embed, req.Message)}
+		for _, embed := range req.ExtraEmbeds {
+			send.Embeds = append(send.Embeds, messageEmbed(embed, ""))
+		}
+		// branding goes on the last embed, below the rest of the message
+		t.decorateEmbed(send.Embeds[len(send.Embeds)-1])
+	}
+	if req.RoleID != "" {
+		mention := fmt.Sprintf("<@&%s>", req.RoleID)
+		if send.Content == "" {
+			send.Content = mention
+		} else {
+			send.Content = mention + " " + send.Content
+		}
+		send.AllowedMentions.Roles = []string{req.RoleID}
+	}
+	if req.ThreadName == "" && t.isForum(req.ChannelID) {
+		return t.sendForumPost(req, send)
+	}
+	channelID := req.ChannelID
+	if req.ThreadName != "" {
+		threadID, err := t.threadID(req.ChannelID, req.ThreadName)
+		if err != nil {
+			tlog.Warnf("[discord] thread %s in channel %s failed, sending to channel: %s", req.ThreadName, req.ChannelID, err)
+		} else {
+			channelID = threadID
+		}
+	}
+	conn := t.session()
+	if conn == nil {
+		return "", fmt.Errorf("not connected")
+	}
+	msg, err := conn.ChannelMessageSendComplex(channelID, send)
 	if err != nil {
-		return fmt.Errorf("ChannelMessageSend: %w", err)
+		if channelID != req.ChannelID {
+			t.forgetThread(req.ChannelID, req.ThreadName)
+		}
+		return "", fmt.Errorf("ChannelMessageSend: %w", err)
+	}
+	if req.FromName != "" {
+		t.trackRelay(msg.ID, req.FromName)
+	}
+	return msg.ID, nil
+}
+
+// Typing shows a typing indicator on provided channel.
+// Requests are debounced per channel, since an indicator lasts around 10 seconds
+func (t *Discord) Typing(req request.DiscordTyping) error {
+	if !t.config.IsEnabled {
+		return fmt.Errorf("not enabled")
+	}
+
+	if !t.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+
+	t.typingMu.Lock()
+	lastTyping, ok := t.lastTyping[req.ChannelID]
+	if ok && time.Since(lastTyping) < 8*time.Second {
+		t.typingMu.Unlock()
+		return nil
+	}
+	t.lastTyping[req.ChannelID] = time.Now()
+	t.typingMu.Unlock()
+
+	conn := t.session()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	err := conn.ChannelTyping(req.ChannelID)
+	if err != nil {
+		return fmt.Errorf("ChannelTyping: %w", err)
 	}
-	t.lastMessageID = msg.ID
-	t.lastChannelID = msg.ChannelID
 	return nil
 }
 
@@ -261,19 +552,6 @@ func sanitize(data string) string {
 	return data
 }
 
-// SetChannelName is used for voice channel setting via SQLReport
-func (t *Discord) SetChannelName(channelID string, name string) error {
-	if !t.isConnected {
-		return fmt.Errorf("discord not connected")
-	}
-
-	if _, err := t.conn.ChannelEdit(channelID, &discordgo.ChannelEdit{Name: name}); err != nil {
-		return fmt.Errorf("edit channel failed: %w", err)
-	}
-	tlog.Debugf("[discord] setting channel to %s", name)
-	return nil
-}
-
 // GetIGNName returns an IGN: tagged name from discord if applicable
 func (t *Discord) GetIGNName(s *discordgo.Session, serverID string, userid string) string {
 	if serverID == "" {
@@ -307,15 +585,27 @@ func (t *Discord) GetIGNName(s *discordgo.Session, serverID string, userid strin
 	return ""
 }
 
-// LastSentMessage returns the channelID and message ID of last message sent
-func (t *Discord) LastSentMessage() (channelID string, messageID string, err error) {
-	if !t.config.IsEnabled {
-		return "", "", fmt.Errorf("not enabled")
+// isAdmin returns true if provided user has one of the configured admin roles
+func (t *Discord) isAdmin(s *discordgo.Session, serverID string, userID string) bool {
+	if len(t.config.AdminRoles) == 0 {
+		return false
+	}
+	if serverID == "" {
+		serverID = t.config.ServerID
+	}
+	member, err := s.GuildMember(serverID, userID)
+	if err != nil {
+		tlog.Warnf("[discord] guildMember failed for author_id %s, server_id %s: %s", userID, serverID, err)
+		return false
 	}
-	if !t.isConnected {
-		return "", "", fmt.Errorf("not connected")
+	for _, role := range member.Roles {
+		for _, adminRole := range t.config.AdminRoles {
+			if strings.TrimSpace(adminRole) == strings.TrimSpace(role) {
+				return true
+			}
+		}
 	}
-	return t.lastChannelID, t.lastMessageID, nil
+	return false
 }
 
 // EditMessage lets you edit a previously sent message
@@ -323,10 +613,14 @@ func (t *Discord) EditMessage(channelID string, messageID string, message string
 	if !t.config.IsEnabled {
 		return fmt.Errorf("not enabled")
 	}
-	if !t.isConnected {
+	if !t.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	conn := t.session()
+	if conn == nil {
 		return fmt.Errorf("not connected")
 	}
-	msg, err := t.conn.ChannelMessageEdit(channelID, messageID, message)
+	msg, err := conn.ChannelMessageEdit(channelID, messageID, message)
 	if err != nil {
 		return fmt.Errorf("edit: %w", err)
 	}
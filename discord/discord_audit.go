@@ -0,0 +1,74 @@
+package discord
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/tlog"
+	"github.com/xackery/talkeq/userdb"
+)
+
+// audit records an action taken by a discord user to the audit log and audit channel, if configured
+func (t *Discord) audit(s *discordgo.Session, serverID string, userID string, action string) {
+	if t.config.AuditLogPath == "" && t.config.AuditChannelID == "" {
+		return
+	}
+
+	name := userID
+	user, err := s.User(userID)
+	if err == nil {
+		name = user.Username
+	}
+	ign := userdb.Name(userID)
+	if ign == "" {
+		ign = t.GetIGNName(s, serverID, userID)
+	}
+
+	entry := fmt.Sprintf("%s (%s, ign: %s) %s", name, userID, ign, action)
+	tlog.Infof("[discord] audit: %s", entry)
+
+	if t.config.AuditLogPath != "" {
+		err = t.auditWrite(fmt.Sprintf("%s %s\n", time.Now().Format(time.RFC3339), entry))
+		if err != nil {
+			tlog.Warnf("[discord] audit write failed: %s", err)
+		}
+	}
+
+	if t.config.AuditChannelID != "" {
+		_, err = s.ChannelMessageSendComplex(t.config.AuditChannelID, &discordgo.MessageSend{
+			Content:         entry,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err != nil {
+			tlog.Warnf("[discord] audit channel %s send failed: %s", t.config.AuditChannelID, err)
+		}
+	}
+}
+
+// auditWrite appends a line to the audit log, rotating it when it grows past the configured size
+func (t *Discord) auditWrite(line string) error {
+	t.auditMu.Lock()
+	defer t.auditMu.Unlock()
+
+	fi, err := os.Stat(t.config.AuditLogPath)
+	if err == nil && fi.Size()+int64(len(line)) > int64(t.config.AuditLogMaxSize)*1024 {
+		err = os.Rename(t.config.AuditLogPath, t.config.AuditLogPath+".1")
+		if err != nil {
+			return fmt.Errorf("rotate: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(t.config.AuditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(line)
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// channelUse is a discord channel talkeq reads from or posts to, and what it is used for
+type channelUse struct {
+	channelID string
+	usage     string
+	isPost    bool
+}
+
+// channelUses returns every enabled discord channel in the config. Channel IDs that are not numbers are left to config validation
+func channelUses(discordConfig config.Discord, cfg *config.Config) []channelUse {
+	uses := []channelUse{}
+	add := func(channelID string, isPost bool, usage string, args ...interface{}) {
+		if !isNumericID(channelID) {
+			return
+		}
+		uses = append(uses, channelUse{channelID: channelID, usage: fmt.Sprintf(usage, args...), isPost: isPost})
+	}
+	for i, route := range discordConfig.Routes {
+		if route.IsEnabled {
+			add(route.Trigger.ChannelID, false, "discord route %d trigger", i)
+		}
+	}
+	add(discordConfig.AuditChannelID, true, "discord audit_channel_id")
+	if cfg == nil {
+		return uses
+	}
+	for _, source := range cfg.SourceRoutes() {
+		for i, route := range source.Routes {
+			if !route.IsEnabled || route.Target != "discord" {
+				continue
+			}
+			for _, channelID := range route.Destinations() {
+				add(channelID, true, "%s route %d", source.Source, i)
+			}
+		}
+	}
+	if cfg.Telnet.IsEnabled {
+		telnet := cfg.Telnet
+		notifications := []struct {
+			name      string
+			isEnabled bool
+			channelID string
+		}{
+			{"zone_change", telnet.ZoneChange.IsEnabled, telnet.ZoneChange.ChannelID},
+			{"level_up", telnet.LevelUp.IsEnabled, telnet.LevelUp.ChannelID},
+			{"returning_player", telnet.ReturningPlayer.IsEnabled, telnet.ReturningPlayer.ChannelID},
+			{"deaths", telnet.Deaths.IsEnabled, telnet.Deaths.ChannelID},
+			{"spawn_alert", telnet.SpawnAlert.IsEnabled, telnet.SpawnAlert.ChannelID},
+			{"loot", telnet.Loot.IsEnabled, telnet.Loot.ChannelID},
+			{"lfg", telnet.LFG.IsEnabled, telnet.LFG.ChannelID},
+			{"unmatched", telnet.Unmatched.IsEnabled, telnet.Unmatched.ChannelID},
+		}
+		for _, n := range notifications {
+			if n.isEnabled {
+				add(n.channelID, true, "telnet %s", n.name)
+			}
+		}
+	}
+	add(cfg.KeepAliveChannelID, true, "keep_alive_channel_id")
+	return uses
+}
+
+// isNumericID returns true if id looks like a discord snowflake
+func isNumericID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkChannels verifies the bot can see every configured channel, and post to the ones it posts to.
+// Problems are logged as one report, and kept for ChannelProblems. Must be called while holding t.mu
+func (t *Discord) checkChannels() {
+	problems := []string{}
+	checked := make(map[string]string)
+	for _, use := range channelUses(t.config, t.rootConfig) {
+		want := int64(discordgo.PermissionViewChannel)
+		if use.isPost {
+			want |= discordgo.PermissionSendMessages
+		}
+		key := fmt.Sprintf("%s:%d", use.channelID, want)
+		problem, ok := checked[key]
+		if !ok {
+			problem = t.channelProblem(use.channelID, want)
+			checked[key] = problem
+		}
+		if problem == "" {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s channel %s: %s", use.usage, use.channelID, problem))
+	}
+	t.channelProblems = problems
+	if len(problems) == 0 {
+		tlog.Debugf("[discord] all configured channels are reachable")
+		return
+	}
+	tlog.Warnf("[discord] %d configured channels are not usable, check the channel ids and the bot's permissions:\n  %s", len(problems), strings.Join(problems, "\n  "))
+}
+
+// channelProblem returns why the bot can not use a channel with the wanted permissions, or an empty string if it can
+func (t *Discord) channelProblem(channelID string, want int64) string {
+	conn := t.session()
+	if conn == nil {
+		return "discord is not connected"
+	}
+	perms, err := conn.UserChannelPermissions(t.id, channelID)
+	if err != nil {
+		return fmt.Sprintf("not found or not visible to the bot (%s)", err)
+	}
+	missing := []string{}
+	if want&discordgo.PermissionViewChannel != 0 && perms&discordgo.PermissionViewChannel == 0 {
+		missing = append(missing, "View Channel")
+	}
+	if want&discordgo.PermissionSendMessages != 0 && perms&discordgo.PermissionSendMessages == 0 {
+		missing = append(missing, "Send Messages")
+	}
+	if len(missing) > 0 {
+		return fmt.Sprintf("missing %s permission", strings.Join(missing, " and "))
+	}
+	return ""
+}
+
+// ChannelProblems returns the channels found unusable when discord last connected
+func (t *Discord) ChannelProblems() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	problems := make([]string, len(t.channelProblems))
+	copy(problems, t.channelProblems)
+	return problems
+}
@@ -0,0 +1,38 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+)
+
+func TestChannelUses(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Discord.Routes = []config.DiscordRoute{
+		{IsEnabled: true, Trigger: config.DiscordTrigger{ChannelID: "100"}},
+		{IsEnabled: false, Trigger: config.DiscordTrigger{ChannelID: "101"}},
+	}
+	cfg.Telnet.IsEnabled = true
+	cfg.Telnet.Routes = []config.Route{
+		{IsEnabled: true, Target: "discord", ChannelID: "200", ChannelIDs: []string{"201"}},
+		{IsEnabled: true, Target: "discord", ChannelID: "INSERTOOCCHANNELHERE"},
+	}
+	cfg.Telnet.Deaths.IsEnabled = true
+	cfg.Telnet.Deaths.ChannelID = "300"
+
+	uses := channelUses(cfg.Discord, cfg)
+	want := []channelUse{
+		{channelID: "100", usage: "discord route 0 trigger"},
+		{channelID: "200", usage: "telnet route 0", isPost: true},
+		{channelID: "201", usage: "telnet route 0", isPost: true},
+		{channelID: "300", usage: "telnet deaths", isPost: true},
+	}
+	if len(uses) != len(want) {
+		t.Fatalf("uses wanted %d, got %d: %+v", len(want), len(uses), uses)
+	}
+	for i := range want {
+		if uses[i] != want[i] {
+			t.Fatalf("use %d = %+v, want %+v", i, uses[i], want[i])
+		}
+	}
+}
@@ -3,39 +3,175 @@ package discord
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/xackery/talkeq/tlog"
 )
 
+// registerCommands registers all supported slash commands with discord
+func (t *Discord) registerCommands() error {
+	err := t.whoRegister()
+	if err != nil {
+		return fmt.Errorf("whoRegister: %w", err)
+	}
+	err = t.bridgeRegister()
+	if err != nil {
+		return fmt.Errorf("bridgeRegister: %w", err)
+	}
+	err = t.configRegister()
+	if err != nil {
+		return fmt.Errorf("configRegister: %w", err)
+	}
+	err = t.tellsRegister()
+	if err != nil {
+		return fmt.Errorf("tellsRegister: %w", err)
+	}
+	err = t.searchRegister()
+	if err != nil {
+		return fmt.Errorf("searchRegister: %w", err)
+	}
+	err = t.marketRegister()
+	if err != nil {
+		return fmt.Errorf("marketRegister: %w", err)
+	}
+	err = t.routesRegister()
+	if err != nil {
+		return fmt.Errorf("routesRegister: %w", err)
+	}
+	err = t.reconnectRegister()
+	if err != nil {
+		return fmt.Errorf("reconnectRegister: %w", err)
+	}
+	if t.config.IsAFKEnabled {
+		err = t.afkRegister()
+		if err != nil {
+			return fmt.Errorf("afkRegister: %w", err)
+		}
+	}
+	return nil
+}
+
+// cooldownRemaining returns how long a user must wait to use a command again, and starts a new cooldown if none is active.
+// Must be called while holding t.mu
+func (t *Discord) cooldownRemaining(cmd string, userID string) time.Duration {
+	seconds := t.config.CommandCooldowns[cmd]
+	if seconds < 1 || userID == "" {
+		return 0
+	}
+	key := cmd + ":" + userID
+	expiry, ok := t.cooldowns[key]
+	if ok && time.Now().Before(expiry) {
+		return time.Until(expiry)
+	}
+	t.cooldowns[key] = time.Now().Add(time.Duration(seconds) * time.Second)
+	return 0
+}
+
+// isEphemeral returns true if a command's response should only be visible to the user who ran it
+func (t *Discord) isEphemeral(cmd string) bool {
+	isEphemeral, ok := t.config.CommandEphemeral[cmd]
+	if !ok {
+		return true
+	}
+	return isEphemeral
+}
+
+// interactionUserID returns the id of the user who triggered an interaction
+func interactionUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
 func (t *Discord) handleCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		t.handleAutocomplete(s, i)
+		return
+	default:
+		return
+	}
 
 	cmd := i.ApplicationCommandData().Name
 	tlog.Debugf("[discord] command requested: %s", cmd)
 
-	var content string
-	var err error
+	// the lock is only held to look up the command, commands make discord API calls and call subscribers,
+	// which would block relaying or deadlock if one comes back into discord, like /reconnect discord
+	t.mu.Lock()
 	cmdFunc, ok := t.commands[strings.ToLower(cmd)]
+	var remaining time.Duration
 	if ok {
-		content, err = cmdFunc(s, i)
-	} else {
+		// unknown commands do not start a cooldown
+		remaining = t.cooldownRemaining(strings.ToLower(cmd), interactionUserID(i))
+	}
+	t.mu.Unlock()
+
+	var data *discordgo.InteractionResponseData
+	var err error
+	if !ok {
 		err = fmt.Errorf("unknown command")
+	} else if remaining > 0 {
+		data = &discordgo.InteractionResponseData{Content: fmt.Sprintf("try again in %ds", int(remaining.Seconds()+0.5))}
+	} else {
+		data, err = cmdFunc(s, i)
 	}
 
 	if err != nil {
 		tlog.Errorf("[discord] run command failed: %s", err)
 	}
 
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	}
+
+	if data == nil {
+		data = &discordgo.InteractionResponseData{}
+	}
+	if t.isEphemeral(strings.ToLower(cmd)) {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+
 	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+	if err != nil {
+		tlog.Errorf("[discord] interactionRespond failed: %s", err)
+	}
+
+	args := []string{}
+	for _, option := range i.ApplicationCommandData().Options {
+		args = append(args, fmt.Sprintf("%s=%v", option.Name, option.Value))
+	}
+	t.audit(s, i.GuildID, interactionUserID(i), fmt.Sprintf("/%s %s (%s)", cmd, strings.Join(args, " "), result))
+}
+
+// handleAutocomplete responds to a user typing in a command option with suggestions
+func (t *Discord) handleAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	cmd := strings.ToLower(i.ApplicationCommandData().Name)
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	switch cmd {
+	case "who":
+		choices = t.whoAutocomplete(s, i)
+	default:
+		tlog.Debugf("[discord] autocomplete requested for unsupported command: %s", cmd)
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
 		Data: &discordgo.InteractionResponseData{
-			Content: content,
-			Flags:   1 << 6,
+			Choices: choices,
 		},
 	})
 	if err != nil {
-		tlog.Errorf("[discord] interactionRespond failed: %s", err)
+		tlog.Errorf("[discord] autocomplete respond failed: %s", err)
 	}
 }
@@ -0,0 +1,115 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+	"github.com/xackery/talkeq/userdb"
+)
+
+// afkMessageMax is the longest away message, so the reply fits in a single in game tell
+const afkMessageMax = 200
+
+func (t *Discord) afkRegister() error {
+	tlog.Debugf("[discord] registering afk command")
+	err := t.createCommand(&discordgo.ApplicationCommand{
+		Name:        "afk",
+		Description: "set an away message that answers in game tells to your character, /afk again to clear it",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "message",
+				Description: "away message, e.g. back in 20 minutes",
+				MaxLength:   afkMessageMax,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("afkRegister commandCreate: %w", err)
+	}
+	return nil
+}
+
+func (t *Discord) afk(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error) {
+	if !t.config.IsAFKEnabled {
+		return &discordgo.InteractionResponseData{Content: "/afk is not enabled"}, nil
+	}
+	userID := interactionUserID(i)
+	name := userdb.Name(userID)
+	if name == "" {
+		return &discordgo.InteractionResponseData{Content: "you need to be registered in the users database to use /afk"}, nil
+	}
+
+	message := ""
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name == "message" {
+			message = option.StringValue()
+		}
+	}
+
+	message, isAway := t.setAway(userID, message)
+	if !isAway {
+		return &discordgo.InteractionResponseData{Content: fmt.Sprintf("%s is no longer away", name)}, nil
+	}
+	return &discordgo.InteractionResponseData{Content: fmt.Sprintf("%s is now away, in game tells will be answered with: %s", name, message)}, nil
+}
+
+// setAway sets or clears the away message of a discord user. An empty message clears it if the user is away,
+// otherwise marks them away with a default message. Returns the message set and if the user is now away
+func (t *Discord) setAway(discordID string, message string) (string, bool) {
+	message = strings.Join(strings.Fields(message), " ")
+	runes := []rune(message)
+	if len(runes) > afkMessageMax {
+		message = string(runes[:afkMessageMax])
+	}
+
+	t.tellMu.Lock()
+	defer t.tellMu.Unlock()
+	_, isAway := t.away[discordID]
+	if message == "" && isAway {
+		delete(t.away, discordID)
+		return "", false
+	}
+	if message == "" {
+		message = "AFK"
+	}
+	t.away[discordID] = message
+	return message, true
+}
+
+// awayReply answers an in game tell with the recipient's away message, if they set one with /afk
+func (t *Discord) awayReply(discordID string, req request.DiscordTell) {
+	key := strings.ToLower(req.FromName + ":" + req.ToName)
+	t.tellMu.Lock()
+	message, ok := t.away[discordID]
+	if !ok {
+		t.tellMu.Unlock()
+		return
+	}
+	if t.lastAwayReply == nil {
+		t.lastAwayReply = make(map[string]time.Time)
+	}
+	lastReply, ok := t.lastAwayReply[key]
+	if ok && time.Since(lastReply) < time.Duration(t.config.TellDMCooldown)*time.Second {
+		t.tellMu.Unlock()
+		return
+	}
+	t.lastAwayReply[key] = time.Now()
+	t.tellMu.Unlock()
+
+	reply := request.TelnetSend{
+		Ctx:     context.Background(),
+		Message: fmt.Sprintf("tell %s %s is away: %s", req.FromName, req.ToName, message),
+	}
+	for i, s := range t.subscribers {
+		err := s(reply)
+		if err != nil {
+			tlog.Warnf("[discord->telnet subscriber %d] away reply from %s to %s failed: %s", i, req.ToName, req.FromName, err)
+		}
+	}
+}
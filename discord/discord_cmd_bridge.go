@@ -0,0 +1,89 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+func (t *Discord) bridgeRegister() error {
+	tlog.Debugf("[discord] registering bridge command")
+	err := t.createCommand(&discordgo.ApplicationCommand{
+		Name:        "bridge",
+		Description: "turn relaying of a channel on or off until talkeq restarts, with /bridge <channel> on|off",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionChannel,
+				Name:        "channel",
+				Description: "channel to toggle relaying for",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "state",
+				Description: "on or off",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "on", Value: "on"},
+					{Name: "off", Value: "off"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("bridgeRegister commandCreate: %w", err)
+	}
+	return nil
+}
+
+func (t *Discord) bridge(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error) {
+	if !t.isAdmin(s, i.GuildID, interactionUserID(i)) {
+		return &discordgo.InteractionResponseData{Content: "you are not allowed to use /bridge"}, nil
+	}
+
+	channelID := ""
+	state := ""
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "channel":
+			channelID = fmt.Sprintf("%s", option.Value)
+		case "state":
+			state = fmt.Sprintf("%s", option.Value)
+		}
+	}
+	if channelID == "" || (state != "on" && state != "off") {
+		return &discordgo.InteractionResponseData{Content: "usage: /bridge <channel> on|off"}, nil
+	}
+	isEnabled := state == "on"
+
+	count := 0
+	t.mu.Lock()
+	for routeIndex := range t.config.Routes {
+		if t.config.Routes[routeIndex].Trigger.ChannelID != channelID {
+			continue
+		}
+		t.config.Routes[routeIndex].IsEnabled = isEnabled
+		// a route toggled by hand is not enabled again on reconnect
+		delete(t.unusableRoutes, routeIndex)
+		count++
+	}
+	t.mu.Unlock()
+	tlog.Infof("[discord] %d discord routes from channel %s set to enabled: %t", count, channelID, isEnabled)
+
+	req := request.RouteToggle{
+		Ctx:       context.Background(),
+		ChannelID: channelID,
+		IsEnabled: isEnabled,
+	}
+	for subIndex, s := range t.subscribers {
+		err := s(req)
+		if err != nil {
+			return nil, fmt.Errorf("subscriber %d route toggle: %w", subIndex, err)
+		}
+	}
+
+	return &discordgo.InteractionResponseData{Content: fmt.Sprintf("relaying for <#%s> is now %s", channelID, state)}, nil
+}
@@ -0,0 +1,101 @@
+package discord
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/tlog"
+)
+
+func (t *Discord) configRegister() error {
+	tlog.Debugf("[discord] registering config command")
+	err := t.createCommand(&discordgo.ApplicationCommand{
+		Name:        "config",
+		Description: "show the current talkeq settings, with secrets masked",
+	})
+	if err != nil {
+		return fmt.Errorf("configRegister commandCreate: %w", err)
+	}
+	return nil
+}
+
+func (t *Discord) configCmd(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error) {
+	if !t.isAdmin(s, i.GuildID, interactionUserID(i)) {
+		return &discordgo.InteractionResponseData{Content: "you are not allowed to use /config"}, nil
+	}
+	cfg := t.rootCfg()
+	if cfg == nil {
+		return nil, fmt.Errorf("root config not set")
+	}
+	queued, dropped := t.SendStats()
+
+	fields := []*discordgo.MessageEmbedField{
+		{
+			Name:  "talkeq",
+			Value: fmt.Sprintf("config_version: %d\nlog_level: %s\nkeep_alive: %t (%s)", cfg.ConfigVersion, cfg.LogLevelName(), cfg.IsKeepAliveEnabled, cfg.KeepAliveRetryDuration()),
+		},
+		{
+			Name:  "discord",
+			Value: fmt.Sprintf("enabled: %t\nserver_id: %s\nclient_id: %s\nbot_token: %s\ncommands_enabled: %t\nroutes: %s\nsend retry queue: %d\ndropped sends: %d", cfg.Discord.IsEnabled, cfg.Discord.ServerID, cfg.Discord.ClientID, config.MaskToken(cfg.Discord.Token), cfg.Discord.IsCommandsEnabled, discordRouteCount(cfg.Discord.Routes), queued, dropped),
+		},
+		{
+			Name:  "telnet",
+			Value: fmt.Sprintf("enabled: %t\nhost: %s\nusername: %s\npassword: %s\nroutes: %s", cfg.Telnet.IsEnabled, cfg.Telnet.Host, cfg.Telnet.Username, config.MaskToken(cfg.Telnet.Password), routeCount(cfg.Telnet.Routes)),
+		},
+		{
+			Name:  "eqlog",
+			Value: fmt.Sprintf("enabled: %t\npath: %s\nroutes: %s", cfg.EQLog.IsEnabled, cfg.EQLog.Path, routeCount(cfg.EQLog.Routes)),
+		},
+		{
+			Name:  "peq_editor",
+			Value: fmt.Sprintf("enabled: %t\nsql enabled: %t\nroutes: %s", cfg.PEQEditor.IsEnabled, cfg.PEQEditor.SQL.IsEnabled, routeCount(cfg.PEQEditor.SQL.Routes)),
+		},
+		{
+			Name:  "sql_report",
+			Value: fmt.Sprintf("enabled: %t\nhost: %s\nusername: %s\npassword: %s\nentries: %d", cfg.SQLReport.IsEnabled, cfg.SQLReport.Host, cfg.SQLReport.Username, config.MaskToken(cfg.SQLReport.Password), len(cfg.SQLReport.Entries)),
+		},
+		{
+			Name:  "api",
+			Value: fmt.Sprintf("enabled: %t\nhost: %s", cfg.API.IsEnabled, cfg.API.Host),
+		},
+		{
+			Name:  "chat_log",
+			Value: fmt.Sprintf("enabled: %t\npath: %s\nretention days: %d", cfg.ChatLog.IsEnabled, cfg.ChatLog.Path, cfg.ChatLog.RetentionDays),
+		},
+	}
+	for _, field := range fields {
+		field.Inline = true
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:  "talkeq config",
+		Fields: fields,
+	}
+	t.decorateEmbed(embed)
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+	}, nil
+}
+
+// routeCount returns a summary of how many routes are enabled
+func routeCount(routes []config.Route) string {
+	count := 0
+	for _, route := range routes {
+		if route.IsEnabled {
+			count++
+		}
+	}
+	return fmt.Sprintf("%d/%d enabled", count, len(routes))
+}
+
+// discordRouteCount returns a summary of how many discord routes are enabled
+func discordRouteCount(routes []config.DiscordRoute) string {
+	count := 0
+	for _, route := range routes {
+		if route.IsEnabled {
+			count++
+		}
+	}
+	return fmt.Sprintf("%d/%d enabled", count, len(routes))
+}
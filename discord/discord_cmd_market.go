@@ -0,0 +1,54 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/auction"
+	"github.com/xackery/talkeq/tlog"
+)
+
+func (t *Discord) marketRegister() error {
+	tlog.Debugf("[discord] registering market command")
+	err := t.createCommand(&discordgo.ApplicationCommand{
+		Name:        "market",
+		Description: "show recent auction prices of an item, with /market <item>",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "item",
+				Description: "item name or alias",
+				Required:    true,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marketRegister commandCreate: %w", err)
+	}
+	return nil
+}
+
+func (t *Discord) market(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error) {
+	item := ""
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name == "item" {
+			item = strings.TrimSpace(option.StringValue())
+		}
+	}
+	if item == "" {
+		return &discordgo.InteractionResponseData{Content: "usage: /market <item>"}, nil
+	}
+	if !auction.IsHistoryEnabled() {
+		return &discordgo.InteractionResponseData{Content: "auction history is not enabled"}, nil
+	}
+
+	stats := auction.Market(item)
+	if stats.Listings == 0 {
+		return &discordgo.InteractionResponseData{Content: fmt.Sprintf("no recent auctions found for '%s'", stats.Item)}, nil
+	}
+	if stats.Priced == 0 {
+		return &discordgo.InteractionResponseData{Content: fmt.Sprintf("'%s' was auctioned %d times, but never with a price", stats.Item, stats.Listings)}, nil
+	}
+	return &discordgo.InteractionResponseData{Content: fmt.Sprintf("**%s**: %d listings, %d priced. min %dpp, avg %dpp, max %dpp", stats.Item, stats.Listings, stats.Priced, stats.Min, stats.Avg, stats.Max)}, nil
+}
@@ -0,0 +1,65 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+func (t *Discord) reconnectRegister() error {
+	tlog.Debugf("[discord] registering reconnect command")
+	err := t.createCommand(&discordgo.ApplicationCommand{
+		Name:        "reconnect",
+		Description: "reconnect a service now, e.g. after fixing a server side issue",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "endpoint",
+				Description: "endpoint to reconnect",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "telnet", Value: "telnet"},
+					{Name: "discord", Value: "discord"},
+					{Name: "sqlreport", Value: "sqlreport"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("reconnectRegister commandCreate: %w", err)
+	}
+	return nil
+}
+
+func (t *Discord) reconnect(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error) {
+	if !t.isAdmin(s, i.GuildID, interactionUserID(i)) {
+		return &discordgo.InteractionResponseData{Content: "you are not allowed to use /reconnect"}, nil
+	}
+
+	endpoint := ""
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name == "endpoint" {
+			endpoint = fmt.Sprintf("%s", option.Value)
+		}
+	}
+	if endpoint == "" {
+		return &discordgo.InteractionResponseData{Content: "usage: /reconnect <endpoint>"}, nil
+	}
+
+	req := request.Reconnect{
+		Ctx:      context.Background(),
+		Endpoint: endpoint,
+		IsManual: true,
+	}
+	for subIndex, s := range t.subscribers {
+		err := s(req)
+		if err != nil {
+			tlog.Warnf("[discord] subscriber %d reconnect %s failed: %s", subIndex, endpoint, err)
+			return &discordgo.InteractionResponseData{Content: fmt.Sprintf("reconnect failed: %s", err)}, nil
+		}
+	}
+	return &discordgo.InteractionResponseData{Content: fmt.Sprintf("reconnecting to %s", endpoint)}, nil
+}
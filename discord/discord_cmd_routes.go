@@ -0,0 +1,96 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// maxEmbedFields is the most fields discord allows in one embed
+const maxEmbedFields = 25
+
+func (t *Discord) routesRegister() error {
+	tlog.Debugf("[discord] registering routes command")
+	err := t.createCommand(&discordgo.ApplicationCommand{
+		Name:        "routes",
+		Description: "list every route, if it is enabled and how often and when it last matched",
+	})
+	if err != nil {
+		return fmt.Errorf("routesRegister commandCreate: %w", err)
+	}
+	return nil
+}
+
+func (t *Discord) routes(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error) {
+	if !t.isAdmin(s, i.GuildID, interactionUserID(i)) {
+		return &discordgo.InteractionResponseData{Content: "you are not allowed to use /routes"}, nil
+	}
+	cfg := t.rootCfg()
+	if cfg == nil {
+		return nil, fmt.Errorf("root config not set")
+	}
+	fields := routeFields(cfg)
+	embed := &discordgo.MessageEmbed{
+		Title:  "talkeq routes",
+		Fields: fields,
+	}
+	if len(fields) == 0 {
+		embed.Description = "no routes are configured"
+	}
+	if len(fields) > maxEmbedFields {
+		embed.Fields = fields[:maxEmbedFields]
+		embed.Description = fmt.Sprintf("showing %d of %d routes", maxEmbedFields, len(fields))
+	}
+	t.decorateEmbed(embed)
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+	}, nil
+}
+
+// routeFields returns an embed field summarizing each route of every source
+func routeFields(cfg *config.Config) []*discordgo.MessageEmbedField {
+	fields := []*discordgo.MessageEmbedField{}
+	for i := range cfg.Discord.Routes {
+		route := &cfg.Discord.Routes[i]
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("discord %d", i),
+			Value:  fmt.Sprintf("trigger: <#%s>\ntarget: %s %s\nenabled: %t\nmatches: %d\nlast match: %s", route.Trigger.ChannelID, route.Target, route.ChannelID, route.IsEnabled, route.Stats().Matches(), lastMatch(route.Stats())),
+			Inline: true,
+		})
+	}
+	for _, source := range cfg.SourceRoutes() {
+		for i := range source.Routes {
+			route := &source.Routes[i]
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:   fmt.Sprintf("%s %d", source.Source, i),
+				Value:  fmt.Sprintf("trigger: %s\ntarget: %s %s\nenabled: %t\nmatches: %d\nlast match: %s", triggerSummary(route.Trigger), route.Target, strings.Join(route.Destinations(), ", "), route.IsEnabled, route.Stats().Matches(), lastMatch(route.Stats())),
+				Inline: true,
+			})
+		}
+	}
+	return fields
+}
+
+// lastMatch returns when a route last matched as a discord relative timestamp
+func lastMatch(stats *config.RouteStats) string {
+	last := stats.LastMatch()
+	if last.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("<t:%d:R>", last.Unix())
+}
+
+// triggerSummary returns a short description of what triggers a route
+func triggerSummary(trigger config.Trigger) string {
+	if trigger.Custom != "" {
+		return trigger.Custom
+	}
+	regex := trigger.Regex
+	if len(regex) > 60 {
+		regex = regex[:57] + "..."
+	}
+	return fmt.Sprintf("`%s`", regex)
+}
@@ -0,0 +1,112 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/chatlog"
+	"github.com/xackery/talkeq/tlog"
+)
+
+const (
+	// searchPageSize is how many chat log entries are shown per /search page
+	searchPageSize = 10
+	// searchMessageLength is how much of each message is shown in /search results
+	searchMessageLength = 150
+)
+
+func (t *Discord) searchRegister() error {
+	tlog.Debugf("[discord] registering search command")
+	minPage := float64(1)
+	err := t.createCommand(&discordgo.ApplicationCommand{
+		Name:        "search",
+		Description: "search relayed chat history by name or message, with /search <term> [page]",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "term",
+				Description: "text to search for",
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "page",
+				Description: "page of results, newest first",
+				MinValue:    &minPage,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("searchRegister commandCreate: %w", err)
+	}
+	return nil
+}
+
+func (t *Discord) search(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error) {
+	term := ""
+	page := 1
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "term":
+			term = strings.TrimSpace(option.StringValue())
+		case "page":
+			page = int(option.IntValue())
+		}
+	}
+	if term == "" {
+		return &discordgo.InteractionResponseData{Content: "usage: /search <term> [page]"}, nil
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	entries, total, err := chatlog.Search(term, (page-1)*searchPageSize, searchPageSize, t.searchFilter(s, i))
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	if total == 0 {
+		return &discordgo.InteractionResponseData{Content: fmt.Sprintf("no messages found matching '%s'", term)}, nil
+	}
+	pageCount := (total + searchPageSize - 1) / searchPageSize
+	if len(entries) == 0 {
+		return &discordgo.InteractionResponseData{Content: fmt.Sprintf("page %d is past the last page (%d) of results for '%s'", page, pageCount, term)}, nil
+	}
+
+	content := fmt.Sprintf("%d messages match '%s', page %d of %d:\n", total, term, page, pageCount)
+	for _, entry := range entries {
+		message := entry.Message
+		runes := []rune(message)
+		if len(runes) > searchMessageLength {
+			message = string(runes[:searchMessageLength]) + "..."
+		}
+		author := entry.Author
+		if author == "" {
+			author = entry.Source
+		}
+		content += fmt.Sprintf("`%s` **%s**: %s\n", entry.Time.Format("2006-01-02 15:04"), author, message)
+	}
+	return &discordgo.InteractionResponseData{Content: content}, nil
+}
+
+// searchFilter returns which chat log entries the user running /search may see.
+// Admins see everything, other users only see chat relayed to or from channels they can read
+func (t *Discord) searchFilter(s *discordgo.Session, i *discordgo.InteractionCreate) func(chatlog.Entry) bool {
+	userID := interactionUserID(i)
+	if t.isAdmin(s, i.GuildID, userID) {
+		return nil
+	}
+	readable := map[string]bool{}
+	return func(entry chatlog.Entry) bool {
+		if entry.ChannelID == "" {
+			return false
+		}
+		isReadable, ok := readable[entry.ChannelID]
+		if !ok {
+			perms, err := s.UserChannelPermissions(userID, entry.ChannelID)
+			isReadable = err == nil && perms&discordgo.PermissionViewChannel != 0
+			readable[entry.ChannelID] = isReadable
+		}
+		return isReadable
+	}
+}
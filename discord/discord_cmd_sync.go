@@ -0,0 +1,172 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// syncCommands registers slash commands in every configured server, or globally.
+// Must be called while holding t.mu
+func (t *Discord) syncCommands() error {
+	t.commandIDs = make(map[string]map[string]string)
+	for _, guildID := range t.commandGuildIDs() {
+		err := t.syncGuildCommands(guildID)
+		if err != nil {
+			return fmt.Errorf("server %s: %w", guildID, err)
+		}
+	}
+
+	err := t.removeOtherScope()
+	if err != nil {
+		return fmt.Errorf("removeOtherScope: %w", err)
+	}
+	if t.config.IsCommandsGlobal {
+		tlog.Infof("[discord] registered global slash commands, new or changed commands can take up to an hour to appear in every server")
+	}
+	return nil
+}
+
+// syncGuildCommands registers slash commands missing or changed in a guild, and deletes commands talkeq no longer provides.
+// Must be called while holding t.mu
+func (t *Discord) syncGuildCommands(guildID string) error {
+	conn := t.session()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	existing, err := conn.ApplicationCommands(t.config.ClientID, guildID)
+	if err != nil {
+		return fmt.Errorf("applicationCommands: %w", err)
+	}
+	t.syncGuildID = guildID
+	t.existingCommands = make(map[string]*discordgo.ApplicationCommand)
+	for _, cmd := range existing {
+		t.existingCommands[cmd.Name] = cmd
+	}
+	t.commandIDs[guildID] = make(map[string]string)
+
+	err = t.registerCommands()
+	t.existingCommands = nil
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range staleCommands(existing, t.commandIDs[guildID]) {
+		tlog.Infof("[discord] deleting removed command /%s", cmd.Name)
+		err = conn.ApplicationCommandDelete(t.config.ClientID, guildID, cmd.ID)
+		if err != nil {
+			return fmt.Errorf("delete command %s: %w", cmd.Name, err)
+		}
+	}
+	return nil
+}
+
+// commandGuildIDs returns the guilds slash commands are registered to, a single empty guild when registered globally
+func (t *Discord) commandGuildIDs() []string {
+	if t.config.IsCommandsGlobal {
+		return []string{""}
+	}
+	return t.config.Servers()
+}
+
+// removeOtherScope deletes talkeq's commands left in the scope not in use, e.g. guild commands after switching to global, so they do not show twice
+func (t *Discord) removeOtherScope() error {
+	conn := t.session()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	names := map[string]string{}
+	for _, ids := range t.commandIDs {
+		for name, id := range ids {
+			names[name] = id
+		}
+	}
+	otherGuildIDs := t.config.Servers()
+	if !t.config.IsCommandsGlobal {
+		otherGuildIDs = []string{""}
+	}
+	for _, guildID := range otherGuildIDs {
+		others, err := conn.ApplicationCommands(t.config.ClientID, guildID)
+		if err != nil {
+			return fmt.Errorf("applicationCommands: %w", err)
+		}
+		for _, cmd := range others {
+			_, ok := names[cmd.Name]
+			if !ok {
+				continue
+			}
+			tlog.Infof("[discord] deleting duplicate command /%s from previous scope", cmd.Name)
+			err = conn.ApplicationCommandDelete(t.config.ClientID, guildID, cmd.ID)
+			if err != nil {
+				return fmt.Errorf("delete command %s: %w", cmd.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// createCommand registers a slash command with the guild being synced, skipping it if an identical command already exists
+func (t *Discord) createCommand(cmd *discordgo.ApplicationCommand) error {
+	existing, ok := t.existingCommands[cmd.Name]
+	if ok && !commandChanged(existing, cmd) {
+		tlog.Debugf("[discord] command /%s is up to date", cmd.Name)
+		t.commandIDs[t.syncGuildID][cmd.Name] = existing.ID
+		return nil
+	}
+	conn := t.session()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	created, err := conn.ApplicationCommandCreate(t.config.ClientID, t.syncGuildID, cmd)
+	if err != nil {
+		return err
+	}
+	t.commandIDs[t.syncGuildID][cmd.Name] = created.ID
+	return nil
+}
+
+// deleteCommands removes every slash command talkeq registered this session
+func (t *Discord) deleteCommands(conn *discordgo.Session) {
+	for guildID, ids := range t.commandIDs {
+		for name, id := range ids {
+			err := conn.ApplicationCommandDelete(t.config.ClientID, guildID, id)
+			if err != nil {
+				tlog.Warnf("[discord] delete command /%s failed: %s", name, err)
+				continue
+			}
+			tlog.Debugf("[discord] deleted command /%s", name)
+		}
+	}
+	t.commandIDs = nil
+}
+
+// commandChanged returns true if a registered command differs from the wanted definition
+func commandChanged(existing *discordgo.ApplicationCommand, want *discordgo.ApplicationCommand) bool {
+	if existing.Description != want.Description {
+		return true
+	}
+	existingOptions, err := json.Marshal(existing.Options)
+	if err != nil {
+		return true
+	}
+	wantOptions, err := json.Marshal(want.Options)
+	if err != nil {
+		return true
+	}
+	return string(existingOptions) != string(wantOptions)
+}
+
+// staleCommands returns registered commands that were not created this session
+func staleCommands(existing []*discordgo.ApplicationCommand, created map[string]string) []*discordgo.ApplicationCommand {
+	stale := []*discordgo.ApplicationCommand{}
+	for _, cmd := range existing {
+		_, ok := created[cmd.Name]
+		if ok {
+			continue
+		}
+		stale = append(stale, cmd)
+	}
+	return stale
+}
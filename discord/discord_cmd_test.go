@@ -0,0 +1,116 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+)
+
+func TestIsEphemeral(t *testing.T) {
+	d := &Discord{config: config.Discord{CommandEphemeral: map[string]bool{"who": false, "config": true}}}
+	if d.isEphemeral("who") {
+		t.Fatalf("who wanted public")
+	}
+	if !d.isEphemeral("config") {
+		t.Fatalf("config wanted ephemeral")
+	}
+	if !d.isEphemeral("tells") {
+		t.Fatalf("unlisted command wanted ephemeral")
+	}
+}
+
+func TestRouteFields(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Telnet.Routes = []config.Route{{
+		IsEnabled: true,
+		Trigger:   config.Trigger{Regex: `(\w+) says ooc, '(.*)'`},
+		Target:    "discord",
+		ChannelID: "123",
+	}}
+	err := cfg.Telnet.Routes[0].LoadMessagePattern()
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	route := cfg.Telnet.Routes[0]
+	route.Stats().Matched()
+
+	fields := routeFields(cfg)
+	if len(fields) != 1 {
+		t.Fatalf("fields wanted 1, got %d", len(fields))
+	}
+	if fields[0].Name != "telnet 0" || !strings.Contains(fields[0].Value, "matches: 1") {
+		t.Fatalf("field = %+v", fields[0])
+	}
+}
+
+func TestCommandSync(t *testing.T) {
+	existing := []*discordgo.ApplicationCommand{
+		{ID: "1", Name: "who", Description: "get a list of players"},
+		{ID: "2", Name: "oldcmd", Description: "removed"},
+	}
+	if commandChanged(existing[0], &discordgo.ApplicationCommand{Name: "who", Description: "get a list of players"}) {
+		t.Fatalf("identical who wanted unchanged")
+	}
+	if !commandChanged(existing[0], &discordgo.ApplicationCommand{Name: "who", Description: "get a list of players", Options: []*discordgo.ApplicationCommandOption{{Name: "filter"}}}) {
+		t.Fatalf("who with new option wanted changed")
+	}
+	stale := staleCommands(existing, map[string]string{"who": "1"})
+	if len(stale) != 1 || stale[0].Name != "oldcmd" {
+		t.Fatalf("stale = %+v", stale)
+	}
+}
+
+func TestCommandGuildIDs(t *testing.T) {
+	d := &Discord{config: config.Discord{ServerID: "123", ServerIDs: []string{"456", "123"}}}
+	guildIDs := d.commandGuildIDs()
+	if len(guildIDs) != 2 || guildIDs[0] != "123" || guildIDs[1] != "456" {
+		t.Fatalf("guild commands wanted each server once, got %v", guildIDs)
+	}
+	d.config.IsCommandsGlobal = true
+	if len(d.commandGuildIDs()) != 1 || d.commandGuildIDs()[0] != "" {
+		t.Fatalf("global commands wanted empty guild")
+	}
+}
+
+func TestAway(t *testing.T) {
+	d := &Discord{
+		config: config.Discord{IsAFKEnabled: true, TellDMCooldown: 60},
+		away:   make(map[string]string),
+	}
+	sent := []string{}
+	d.subscribers = append(d.subscribers, func(rawReq interface{}) error {
+		req, ok := rawReq.(request.TelnetSend)
+		if ok {
+			sent = append(sent, req.Message)
+		}
+		return nil
+	})
+
+	tell := request.DiscordTell{FromName: "Shin", ToName: "Xackery", Message: "hi"}
+	d.awayReply("123", tell)
+	if len(sent) != 0 {
+		t.Fatalf("wanted no reply while not away, got %v", sent)
+	}
+
+	message, isAway := d.setAway("123", "  back in   20 ")
+	if !isAway || message != "back in 20" {
+		t.Fatalf("setAway got %q %t", message, isAway)
+	}
+	d.awayReply("123", tell)
+	d.awayReply("123", tell)
+	if len(sent) != 1 || sent[0] != "tell Shin Xackery is away: back in 20" {
+		t.Fatalf("wanted a single away reply, got %v", sent)
+	}
+
+	_, isAway = d.setAway("123", "")
+	if isAway {
+		t.Fatalf("wanted /afk without a message to clear away")
+	}
+	message, isAway = d.setAway("123", "")
+	if !isAway || message != "AFK" {
+		t.Fatalf("wanted default away message, got %q %t", message, isAway)
+	}
+}
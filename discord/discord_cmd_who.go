@@ -10,9 +10,25 @@ import (
 
 func (t *Discord) whoRegister() error {
 	tlog.Debugf("[discord] registering who command")
-	_, err := t.conn.ApplicationCommandCreate(t.conn.State.User.ID, t.config.ServerID, &discordgo.ApplicationCommand{
+	options := []*discordgo.ApplicationCommandOption{
+		{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         "filter",
+			Description:  "player name or zone",
+			Autocomplete: true,
+		},
+	}
+	if t.config.IsWhoAnonForAdmins {
+		options = append(options, &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "anon",
+			Description: "admin only, include anonymous and roleplay players",
+		})
+	}
+	err := t.createCommand(&discordgo.ApplicationCommand{
 		Name:        "who",
 		Description: "get a list of players on server, can filter by zone or name with /who <filter>",
+		Options:     options,
 	})
 	if err != nil {
 		return fmt.Errorf("whoRegister commandCreate: %w", err)
@@ -20,20 +36,48 @@ func (t *Discord) whoRegister() error {
 	return nil
 }
 
-func (t *Discord) who(s *discordgo.Session, i *discordgo.InteractionCreate) (content string, err error) {
+func (t *Discord) who(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error) {
 	appCmdData := i.ApplicationCommandData()
 	/*	if len(appCmdData.Options) == 0 {
 		content = "usage: /who all, /who <name>"
 		return
 	}*/
 	arg := ""
-	if len(appCmdData.Options) > 0 {
-		arg = fmt.Sprintf("%s", i.ApplicationCommandData().Options[0].Value)
-		if arg == "all" {
-			arg = ""
+	isAnon := false
+	for _, option := range appCmdData.Options {
+		switch option.Name {
+		case "filter":
+			arg = fmt.Sprintf("%s", option.Value)
+			if arg == "all" {
+				arg = ""
+			}
+		case "anon":
+			isAnon = option.BoolValue()
 		}
 	}
+	if isAnon && (!t.config.IsWhoAnonForAdmins || !t.isAdmin(s, i.GuildID, interactionUserID(i))) {
+		return &discordgo.InteractionResponseData{Content: "only admins can see anonymous players"}, nil
+	}
 
-	content = characterdb.CharactersOnline(arg)
-	return
+	data := &discordgo.InteractionResponseData{Content: characterdb.CharactersOnline(arg, isAnon)}
+	if isAnon {
+		// anonymous players are only shown to the admin who asked, even if /who is public
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+	return data, nil
+}
+
+// whoAutocomplete suggests online player names and zones for the /who filter
+func (t *Discord) whoAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) []*discordgo.ApplicationCommandOptionChoice {
+	filter := ""
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name == "filter" && option.Focused {
+			filter = option.StringValue()
+		}
+	}
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, suggestion := range characterdb.Suggestions(filter, 25) {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: suggestion, Value: suggestion})
+	}
+	return choices
 }
@@ -0,0 +1,114 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// maxEdits is how many relayed messages are remembered for relaying edits and deletes
+const maxEdits = 1000
+
+// relayedMessage is a discord message that was relayed in game
+type relayedMessage struct {
+	ign               string
+	message           string
+	isUnregisteredIGN bool
+}
+
+// trackEdit remembers a message relayed in game, so a later edit or delete of it can be relayed too
+func (t *Discord) trackEdit(messageID string, relayed relayedMessage) {
+	t.editMu.Lock()
+	defer t.editMu.Unlock()
+	_, ok := t.edits[messageID]
+	t.edits[messageID] = relayed
+	if ok {
+		return
+	}
+	t.editOrder = append(t.editOrder, messageID)
+	if len(t.editOrder) > maxEdits {
+		delete(t.edits, t.editOrder[0])
+		t.editOrder = t.editOrder[1:]
+	}
+}
+
+// relayedEdit returns a tracked relayed message
+func (t *Discord) relayedEdit(messageID string) (relayedMessage, bool) {
+	t.editMu.Lock()
+	defer t.editMu.Unlock()
+	relayed, ok := t.edits[messageID]
+	return relayed, ok
+}
+
+// forgetEdit stops tracking a relayed message, once it is deleted
+func (t *Discord) forgetEdit(messageID string) {
+	t.editMu.Lock()
+	defer t.editMu.Unlock()
+	delete(t.edits, messageID)
+	for i, id := range t.editOrder {
+		if id == messageID {
+			t.editOrder = append(t.editOrder[:i], t.editOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+func (t *Discord) handleMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	if !t.config.IsEditRelayEnabled || m.Message == nil {
+		return
+	}
+	relayed, ok := t.relayedEdit(m.ID)
+	if !ok {
+		return
+	}
+	// link previews being added also trigger an update, without content or author
+	if m.Content == "" || m.Author == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// edits go through the same checks as new messages, the author may have lost their IGN since
+	if m.Author.ID == t.id {
+		return
+	}
+	msg, ok := messageText(s, m.Message)
+	if !ok || msg == relayed.message {
+		return
+	}
+	ign := t.messageIGN(s, m.Message)
+	isUnregisteredIGN := false
+	if len(ign) == 0 {
+		isUnregisteredIGN = true
+		ign = t.anyoneAllowedIGN(s, m.Message)
+		if len(ign) == 0 {
+			tlog.Debugf("[discord] edit of message %s discarded, ign not found", m.ID)
+			return
+		}
+	}
+
+	tlog.Debugf("[discord] relaying edit of message %s by %s", m.ID, ign)
+	t.relayToTelnet(context.Background(), m.ChannelID, ign, fmt.Sprintf("edited: %s", msg), isUnregisteredIGN)
+	t.trackEdit(m.ID, relayedMessage{ign: ign, message: msg, isUnregisteredIGN: isUnregisteredIGN})
+}
+
+func (t *Discord) handleMessageDelete(s *discordgo.Session, m *discordgo.MessageDelete) {
+	if !t.config.IsEditRelayEnabled || m.Message == nil {
+		return
+	}
+	relayed, ok := t.relayedEdit(m.ID)
+	if !ok {
+		return
+	}
+	t.forgetEdit(m.ID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// the deleted text is not repeated, a moderator may have removed it for a reason
+	tlog.Debugf("[discord] relaying delete of message %s by %s", m.ID, relayed.ign)
+	t.relayToTelnet(context.Background(), m.ChannelID, relayed.ign, "deleted a message", relayed.isUnregisteredIGN)
+}
@@ -0,0 +1,47 @@
+package discord
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/request"
+)
+
+// decorateEmbed applies the configured footer branding and timestamp to an embed.
+// Footer text already on the embed is kept after the branding
+func (t *Discord) decorateEmbed(embed *discordgo.MessageEmbed) {
+	if t.config.EmbedFooter != "" || t.config.EmbedFooterIcon != "" {
+		footer := &discordgo.MessageEmbedFooter{
+			Text:    t.config.EmbedFooter,
+			IconURL: t.config.EmbedFooterIcon,
+		}
+		if embed.Footer != nil && embed.Footer.Text != "" {
+			if footer.Text != "" {
+				footer.Text += " • "
+			}
+			footer.Text += embed.Footer.Text
+		}
+		embed.Footer = footer
+	}
+	if t.config.IsEmbedTimestampEnabled && embed.Timestamp == "" {
+		embed.Timestamp = time.Now().Format(time.RFC3339)
+	}
+}
+
+// messageEmbed converts a request embed to a discord embed with provided description
+func messageEmbed(embed *request.DiscordEmbed, description string) *discordgo.MessageEmbed {
+	msgEmbed := &discordgo.MessageEmbed{
+		Title:       embed.Title,
+		URL:         embed.URL,
+		Color:       embed.Color,
+		Description: description,
+	}
+	for _, field := range embed.Fields {
+		msgEmbed.Fields = append(msgEmbed.Fields, &discordgo.MessageEmbedField{
+			Name:   field.Name,
+			Value:  field.Value,
+			Inline: field.Inline,
+		})
+	}
+	return msgEmbed
+}
@@ -0,0 +1,30 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/config"
+)
+
+func TestDecorateEmbed(t *testing.T) {
+	d := &Discord{}
+	embed := &discordgo.MessageEmbed{Title: "plain"}
+	d.decorateEmbed(embed)
+	if embed.Footer != nil || embed.Timestamp != "" {
+		t.Fatalf("decorateEmbed() with no branding changed embed: %+v", embed)
+	}
+
+	d.config = config.Discord{EmbedFooter: "My Server", EmbedFooterIcon: "https://example.com/logo.png", IsEmbedTimestampEnabled: true}
+	embed = &discordgo.MessageEmbed{Footer: &discordgo.MessageEmbedFooter{Text: "expires in 30 minutes"}}
+	d.decorateEmbed(embed)
+	if embed.Footer.Text != "My Server • expires in 30 minutes" {
+		t.Fatalf("footer text = %q", embed.Footer.Text)
+	}
+	if embed.Footer.IconURL != "https://example.com/logo.png" {
+		t.Fatalf("footer icon = %q", embed.Footer.IconURL)
+	}
+	if embed.Timestamp == "" {
+		t.Fatalf("timestamp wanted to be set")
+	}
+}
@@ -0,0 +1,128 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// lfgPost is a group finder listing posted to discord, waiting to expire
+type lfgPost struct {
+	channelID string
+	messageID string
+	embed     *discordgo.MessageEmbed
+	expires   time.Time
+}
+
+// LFG posts a group finder listing as an embed. A player's previous listing is expired when they post a new one
+func (t *Discord) LFG(req request.DiscordLFG) error {
+	if !t.config.IsEnabled {
+		return fmt.Errorf("not enabled")
+	}
+	if !t.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+
+	embed := lfgEmbed(req)
+	t.decorateEmbed(embed)
+	conn := t.session()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	msg, err := conn.ChannelMessageSendEmbed(req.ChannelID, embed)
+	if err != nil {
+		return fmt.Errorf("ChannelMessageSendEmbed: %w", err)
+	}
+
+	key := strings.ToLower(req.Name)
+	t.lfgMu.Lock()
+	previous, ok := t.lfgPosts[key]
+	t.lfgPosts[key] = &lfgPost{
+		channelID: msg.ChannelID,
+		messageID: msg.ID,
+		embed:     embed,
+		expires:   time.Now().Add(req.Expire),
+	}
+	t.lfgMu.Unlock()
+	if ok {
+		t.expireLFG(previous)
+	}
+	return nil
+}
+
+// lfgEmbed builds the embed of a group finder listing
+func lfgEmbed(req request.DiscordLFG) *discordgo.MessageEmbed {
+	title := fmt.Sprintf("%s is looking for a group", req.Name)
+	if req.Kind == "LFM" {
+		title = fmt.Sprintf("%s is looking for members", req.Name)
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: req.Message,
+		Color:       req.Color,
+		Timestamp:   time.Now().Format(time.RFC3339),
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("expires in %d minutes", int(req.Expire.Minutes()))},
+	}
+	if req.Level > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Level", Value: fmt.Sprintf("%d", req.Level), Inline: true})
+	}
+	if req.Class != "" {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Class", Value: req.Class, Inline: true})
+	}
+	return embed
+}
+
+// expireLFG edits a listing's embed to show it expired
+func (t *Discord) expireLFG(post *lfgPost) {
+	embed := *post.embed
+	embed.Title = "(expired) " + embed.Title
+	embed.Color = 0x95A5A6
+	embed.Footer = &discordgo.MessageEmbedFooter{Text: "expired"}
+	t.decorateEmbed(&embed)
+	conn := t.session()
+	if conn == nil {
+		return
+	}
+	_, err := conn.ChannelMessageEditEmbed(post.channelID, post.messageID, &embed)
+	if err != nil {
+		tlog.Warnf("[discord] expire lfg message %s failed: %s", post.messageID, err)
+	}
+}
+
+// lfgLoop expires group finder listings once their time is up
+func (t *Discord) lfgLoop(ctx context.Context) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			tlog.Debugf("[discord] lfg loop exit")
+			return
+		case <-ticker.C:
+		}
+
+		if !t.IsConnected() {
+			continue
+		}
+
+		expired := []*lfgPost{}
+		t.lfgMu.Lock()
+		for key, post := range t.lfgPosts {
+			if time.Now().Before(post.expires) {
+				continue
+			}
+			expired = append(expired, post)
+			delete(t.lfgPosts, key)
+		}
+		t.lfgMu.Unlock()
+
+		for _, post := range expired {
+			t.expireLFG(post)
+		}
+	}
+}
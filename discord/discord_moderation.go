@@ -0,0 +1,91 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// maxRelays is how many relayed messages are remembered for moderation
+const maxRelays = 1000
+
+// trackRelay remembers the original EQ sender of a relayed message
+func (t *Discord) trackRelay(messageID string, fromName string) {
+	t.relayMu.Lock()
+	defer t.relayMu.Unlock()
+	t.relays[messageID] = fromName
+	t.relayOrder = append(t.relayOrder, messageID)
+	if len(t.relayOrder) > maxRelays {
+		delete(t.relays, t.relayOrder[0])
+		t.relayOrder = t.relayOrder[1:]
+	}
+}
+
+func (t *Discord) handleReaction(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	// the lock is not held during the admin lookup and subscriber calls, so a slow one does not block relaying
+	t.mu.RLock()
+	botID := t.id
+	subscribers := t.subscribers
+	t.mu.RUnlock()
+
+	if r.UserID == botID {
+		return
+	}
+
+	moderationIndex := -1
+	for i, moderation := range t.config.Moderation {
+		if !moderation.IsEnabled {
+			continue
+		}
+		if moderation.Emoji != r.Emoji.Name && moderation.Emoji != r.Emoji.APIName() {
+			continue
+		}
+		moderationIndex = i
+		break
+	}
+	if moderationIndex == -1 {
+		return
+	}
+
+	t.relayMu.Lock()
+	fromName, ok := t.relays[r.MessageID]
+	t.relayMu.Unlock()
+	if !ok {
+		tlog.Debugf("[discord] reaction %s on message %s ignored, not a relayed message", r.Emoji.Name, r.MessageID)
+		return
+	}
+
+	if !t.isAdmin(s, r.GuildID, r.UserID) {
+		tlog.Debugf("[discord] reaction %s by %s ignored, not an admin", r.Emoji.Name, r.UserID)
+		return
+	}
+
+	moderation := t.config.Moderation[moderationIndex]
+	buf := new(bytes.Buffer)
+	if err := moderation.MessagePatternTemplate().Execute(buf, struct {
+		Name string
+	}{
+		fromName,
+	}); err != nil {
+		tlog.Warnf("[discord] execute moderation %d failed: %s", moderationIndex, err)
+		return
+	}
+
+	req := request.TelnetSend{
+		Ctx:     context.Background(),
+		Message: buf.String(),
+	}
+	for i, s := range subscribers {
+		err := s(req)
+		if err != nil {
+			tlog.Warnf("[discord->subscriber %d] moderation %d message %s failed: %s", i, moderationIndex, req.Message, err)
+			continue
+		}
+		tlog.Infof("[discord->subscriber %d] moderation by %s: %s", i, r.UserID, req.Message)
+	}
+	t.audit(s, r.GuildID, r.UserID, fmt.Sprintf("reaction %s: %s", r.Emoji.Name, req.Message))
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/xackery/talkeq/guilddb"
@@ -23,35 +24,12 @@ func (t *Discord) handleMessage(s *discordgo.Session, m *discordgo.MessageCreate
 		return
 	}
 
-	ign := ""
-
-	originalMessage, err := m.ContentWithMoreMentionsReplaced(s)
-	if err != nil {
-		tlog.Debugf("[discord] message grab failed: %s", err)
-		return
-	}
-	msg := originalMessage
-	if len(msg) < 1 {
-		tlog.Debugf("[discord] message too small, ignoring, original message: %s", originalMessage)
-		return
-	}
-	if len(msg) > 4000 {
-		msg = msg[0:4000]
-	}
-	msg = sanitize(msg)
-	if len(msg) < 1 {
-		tlog.Debugf("[discord] message after sanitize too small, ignoring, original message: %s", originalMessage)
+	msg, ok := messageText(s, m.Message)
+	if !ok {
 		return
 	}
 
-	ign = userdb.Name(m.Author.ID)
-	if ign == "" {
-		ign = t.GetIGNName(s, m.GuildID, m.Author.ID)
-		//disabled this code since it would cache results and remove dynamics
-		//if ign != "" { //update users database with newly found ign tag
-		//	t.users.Set(m.Author.ID, ign)
-		//}
-	}
+	ign := t.messageIGN(s, m.Message)
 
 	//ignore bot messages
 	if m.Author.ID == t.id {
@@ -59,8 +37,6 @@ func (t *Discord) handleMessage(s *discordgo.Session, m *discordgo.MessageCreate
 		return
 	}
 
-	ign = sanitize(ign)
-
 	if strings.Index(msg, "!") == 0 {
 		req := request.APICommand{
 			Ctx:                  ctx,
@@ -82,101 +58,239 @@ func (t *Discord) handleMessage(s *discordgo.Session, m *discordgo.MessageCreate
 	isUnregisteredIGN := false
 	if len(ign) == 0 {
 		isUnregisteredIGN = true
-		for _, route := range t.config.Routes {
-			if !route.IsEnabled {
-				continue
-			}
-			if route.Trigger.ChannelID != m.ChannelID {
-				continue
-			}
-			if !route.IsAnyoneAllowed {
-				continue
-			}
-			member, err := s.GuildMember(m.GuildID, m.Author.ID)
-			if err != nil {
-				tlog.Warnf("[discord] guildMember failed for server_id %s, author_id %s: %s", m.GuildID, m.Author, err)
-				continue
-			}
-
-			if len(ign) == 0 {
-				ign = sanitize(member.Nick)
-				if len(ign) == 0 {
-					ign = sanitize(member.User.Username)
-				}
-			}
-			tlog.Debugf("[discord] ign not found, but anyone is allowed, using %s", ign)
-		}
+		ign = t.anyoneAllowedIGN(s, m.Message)
 		if len(ign) == 0 {
 			tlog.Warn("[discord] ign not found, discarding")
 			return
 		}
 	}
-	routes := 0
-	for routeIndex, route := range t.config.Routes {
+	routes := t.relayToTelnet(ctx, m.ChannelID, ign, msg, isUnregisteredIGN)
+	if routes == 0 {
+		tlog.Debugf("[discord] message discarded, not routes match")
+		return
+	}
+	if t.config.IsEditRelayEnabled {
+		t.trackEdit(m.ID, relayedMessage{ign: ign, message: msg, isUnregisteredIGN: isUnregisteredIGN})
+	}
+}
+
+// messageText returns the content of a discord message ready to relay in game, with mentions replaced and unsupported characters removed.
+// Returns false if nothing is left to relay
+func messageText(s *discordgo.Session, m *discordgo.Message) (string, bool) {
+	originalMessage, err := m.ContentWithMoreMentionsReplaced(s)
+	if err != nil {
+		tlog.Debugf("[discord] message grab failed: %s", err)
+		return "", false
+	}
+	msg := originalMessage
+	if len(msg) < 1 {
+		tlog.Debugf("[discord] message too small, ignoring, original message: %s", originalMessage)
+		return "", false
+	}
+	if len(msg) > 4000 {
+		msg = msg[0:4000]
+	}
+	msg = sanitize(msg)
+	if len(msg) < 1 {
+		tlog.Debugf("[discord] message after sanitize too small, ignoring, original message: %s", originalMessage)
+		return "", false
+	}
+	return msg, true
+}
+
+// messageIGN returns the IGN of a message's author from the users database or their IGN: tag, or an empty string if they have none
+func (t *Discord) messageIGN(s *discordgo.Session, m *discordgo.Message) string {
+	ign := userdb.Name(m.Author.ID)
+	if ign == "" {
+		ign = t.GetIGNName(s, m.GuildID, m.Author.ID)
+		//disabled this code since it would cache results and remove dynamics
+		//if ign != "" { //update users database with newly found ign tag
+		//	t.users.Set(m.Author.ID, ign)
+		//}
+	}
+	return sanitize(ign)
+}
+
+// anyoneAllowedIGN returns the server nickname or username of a message's author, if a route in the message's channel allows anyone.
+// Returns an empty string otherwise
+func (t *Discord) anyoneAllowedIGN(s *discordgo.Session, m *discordgo.Message) string {
+	ign := ""
+	for _, route := range t.config.Routes {
 		if !route.IsEnabled {
 			continue
 		}
 		if route.Trigger.ChannelID != m.ChannelID {
 			continue
 		}
-		if isUnregisteredIGN && !route.IsAnyoneAllowed {
+		if !route.IsAnyoneAllowed {
+			continue
+		}
+		member, err := s.GuildMember(m.GuildID, m.Author.ID)
+		if err != nil {
+			tlog.Warnf("[discord] guildMember failed for server_id %s, author_id %s: %s", m.GuildID, m.Author, err)
 			continue
 		}
 
-		buf := new(bytes.Buffer)
-
-		if err := route.MessagePatternTemplate().Execute(buf, struct {
-			Name      string
-			Message   string
-			ChannelID string
-		}{
-			ign,
-			msg,
-			route.ChannelID,
-		}); err != nil {
-			tlog.Warnf("[discord] execute route %d failed: %s", routeIndex, err)
+		if len(ign) == 0 {
+			ign = sanitize(member.Nick)
+			if len(ign) == 0 {
+				ign = sanitize(member.User.Username)
+			}
+		}
+		tlog.Debugf("[discord] ign not found, but anyone is allowed, using %s", ign)
+	}
+	return ign
+}
+
+// relayToTelnet sends msg from a discord channel in game through every matching route and guild channel.
+// Returns how many routes matched. Must be called while holding t.mu
+func (t *Discord) relayToTelnet(ctx context.Context, channelID string, ign string, msg string, isUnregisteredIGN bool) int {
+	routes := 0
+	for routeIndex, route := range t.config.Routes {
+		if !route.IsEnabled {
+			continue
+		}
+		if route.Trigger.ChannelID != channelID {
+			continue
+		}
+		if isUnregisteredIGN && !route.IsAnyoneAllowed {
 			continue
 		}
+		route.Stats().Matched()
 
 		routes++
 		switch route.Target {
 		case "telnet":
-			req := request.TelnetSend{
-				Ctx:     ctx,
-				Message: buf.String(),
+			pattern := func(chunk string) (string, error) {
+				buf := new(bytes.Buffer)
+				err := route.MessagePatternTemplate().Execute(buf, struct {
+					Name      string
+					Message   string
+					ChannelID string
+				}{
+					ign,
+					chunk,
+					t.config.ChannelNumber(route.ChannelID),
+				})
+				return buf.String(), err
 			}
-			for _, s := range t.subscribers {
-				err := s(req)
+			prefix, err := pattern("")
+			if err != nil {
+				tlog.Warnf("[discord] execute route %d failed: %s", routeIndex, err)
+				break
+			}
+			for _, chunk := range splitMessage(msg, t.messageLimit(prefix)) {
+				chunk = t.bridgeTag(chunk)
+				message, err := pattern(chunk)
 				if err != nil {
-					tlog.Warnf("[discord->telnet] route %d message '%s' failed: %s", routeIndex, req.Message, err)
-					continue
+					tlog.Warnf("[discord] execute route %d failed: %s", routeIndex, err)
+					break
 				}
-				tlog.Infof("[discord->telnet] route %d: %s", routeIndex, req.Message)
-			}
 
+				req := request.TelnetSend{
+					Ctx:              ctx,
+					Message:          message,
+					FromName:         ign,
+					DiscordChannelID: channelID,
+					Text:             chunk,
+				}
+				for _, s := range t.subscribers {
+					err := s(req)
+					if err != nil {
+						tlog.Warnf("[discord->telnet] route %d message '%s' failed: %s", routeIndex, req.Message, err)
+						continue
+					}
+					tlog.Infof("[discord->telnet] route %d: %s", routeIndex, req.Message)
+				}
+			}
 		default:
 			tlog.Warnf("[discord] route %d failed: target %s is invalid", routeIndex, route.Target)
 		}
 	}
 	//check if channel is a guild one
-	guildID := guilddb.GuildID(m.ChannelID)
+	guildID := guilddb.GuildID(channelID)
 	if guildID > 0 {
 		routes++
 
-		req := request.TelnetSend{
-			Ctx:     ctx,
-			Message: fmt.Sprintf("guildsay %s %d %s", ign, guildID, msg),
+		prefix := fmt.Sprintf("guildsay %s %d ", ign, guildID)
+		for _, chunk := range splitMessage(msg, t.messageLimit(prefix)) {
+			chunk = t.bridgeTag(chunk)
+			req := request.TelnetSend{
+				Ctx:              ctx,
+				Message:          fmt.Sprintf("guildsay %s %d %s", ign, guildID, chunk),
+				FromName:         ign,
+				DiscordChannelID: channelID,
+				Text:             chunk,
+			}
+			for i, s := range t.subscribers {
+				err := s(req)
+				if err != nil {
+					tlog.Warnf("[discord->subscriber %d] guildID %d message %s failed: %s", i, guildID, req.Message, err)
+					continue
+				}
+				tlog.Infof("[discord->subscriber %d] guildID %d message: %s", i, guildID, req.Message)
+			}
 		}
-		for i, s := range t.subscribers {
-			err := s(req)
-			if err != nil {
-				tlog.Warnf("[discord->subscriber %d] guildID %d message %s failed: %s", i, guildID, req.Message, err)
-				continue
+	}
+	return routes
+}
+
+// bridgeTag prefixes a message relayed in game with the configured bridge tag, if set
+func (t *Discord) bridgeTag(msg string) string {
+	if t.config.BridgeTag == "" {
+		return msg
+	}
+	return t.config.BridgeTag + " " + msg
+}
+
+// minMessageLimit is the shortest chunk a message is split into, even if a long route pattern leaves less room
+const minMessageLimit = 20
+
+// messageLimit returns how long the text of each chunk can be, so max_message_length still fits
+// once prefix, the route pattern around the text, and the bridge tag are added
+func (t *Discord) messageLimit(prefix string) int {
+	if t.config.MaxMessageLength < 1 {
+		return 0
+	}
+	limit := t.config.MaxMessageLength - utf8.RuneCountInString(prefix) - utf8.RuneCountInString(t.bridgeTag(""))
+	if limit < minMessageLimit {
+		return minMessageLimit
+	}
+	return limit
+}
+
+// splitMessage breaks msg into chunks no longer than limit characters, preferring word boundaries.
+// If more than one chunk is needed, each chunk is suffixed with a (1/3) style marker
+func splitMessage(msg string, limit int) []string {
+	runes := []rune(msg)
+	if limit < 1 || len(runes) <= limit {
+		return []string{msg}
+	}
+
+	// reserve room for the continuation marker
+	size := limit - len(" (99/99)")
+	if size < 1 {
+		size = limit
+	}
+
+	chunks := []string{}
+	for len(runes) > size {
+		cut := size
+		for i := size; i > 0; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
 			}
-			tlog.Infof("[discord->subscriber %d] guildID %d message: %s", i, guildID, req.Message)
 		}
+		chunks = append(chunks, strings.TrimSpace(string(runes[:cut])))
+		runes = []rune(strings.TrimSpace(string(runes[cut:])))
 	}
-	if routes == 0 {
-		tlog.Debugf("[discord] message discarded, not routes match")
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+
+	for i := range chunks {
+		chunks[i] = fmt.Sprintf("%s (%d/%d)", chunks[i], i+1, len(chunks))
 	}
+	return chunks
 }
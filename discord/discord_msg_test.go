@@ -0,0 +1,118 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		msg   string
+		limit int
+		want  []string
+	}{
+		{name: "short", msg: "hello world", limit: 400, want: []string{"hello world"}},
+		{name: "disabled", msg: "hello world", limit: 0, want: []string{"hello world"}},
+		{name: "word boundary", msg: "the quick brown fox jumps over the lazy dog", limit: 24, want: []string{
+			"the quick brown (1/3)",
+			"fox jumps over (2/3)",
+			"the lazy dog (3/3)",
+		}},
+		{name: "long word", msg: strings.Repeat("a", 30), limit: 20, want: []string{
+			"aaaaaaaaaaaa (1/3)",
+			"aaaaaaaaaaaa (2/3)",
+			"aaaaaa (3/3)",
+		}},
+		{name: "multibyte", msg: strings.Repeat("é", 30), limit: 20, want: []string{
+			strings.Repeat("é", 12) + " (1/3)",
+			strings.Repeat("é", 12) + " (2/3)",
+			strings.Repeat("é", 6) + " (3/3)",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMessage(tt.msg, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitMessage() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("splitMessage()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+				if utf8.RuneCountInString(got[i]) > tt.limit && tt.limit > 0 {
+					t.Fatalf("splitMessage()[%d] length %d exceeds limit %d", i, len(got[i]), tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestMessageLimit(t *testing.T) {
+	d := &Discord{}
+	d.config.MaxMessageLength = 100
+	d.config.BridgeTag = "[Discord]"
+	if got := d.messageLimit("guildsay Shin 1 "); got != 100-16-10 {
+		t.Fatalf("messageLimit() = %d, want %d", got, 100-16-10)
+	}
+	if got := d.messageLimit(strings.Repeat("a", 100)); got != minMessageLimit {
+		t.Fatalf("messageLimit() with a long prefix = %d, want %d", got, minMessageLimit)
+	}
+	d.config.MaxMessageLength = 0
+	if got := d.messageLimit("guildsay Shin 1 "); got != 0 {
+		t.Fatalf("messageLimit() with splitting disabled = %d, want 0", got)
+	}
+}
+
+func TestBridgeTag(t *testing.T) {
+	d := &Discord{}
+	if got := d.bridgeTag("hello"); got != "hello" {
+		t.Fatalf("bridgeTag() with no tag = %q", got)
+	}
+	d.config.BridgeTag = "[Discord]"
+	if got := d.bridgeTag("hello"); got != "[Discord] hello" {
+		t.Fatalf("bridgeTag() = %q", got)
+	}
+}
+
+func TestRelayDelete(t *testing.T) {
+	d := &Discord{
+		config: config.Discord{
+			IsEditRelayEnabled: true,
+			Routes: []config.DiscordRoute{{
+				IsEnabled:      true,
+				Trigger:        config.DiscordTrigger{ChannelID: "123"},
+				Target:         "telnet",
+				ChannelID:      "260",
+				MessagePattern: "emote world {{.ChannelID}} {{.Name}} says from discord, '{{.Message}}'",
+			}},
+		},
+		edits: make(map[string]relayedMessage),
+	}
+	err := d.config.Routes[0].LoadMessagePattern()
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	sent := []string{}
+	d.subscribers = append(d.subscribers, func(rawReq interface{}) error {
+		sent = append(sent, rawReq.(request.TelnetSend).Message)
+		return nil
+	})
+
+	d.trackEdit("1", relayedMessage{ign: "Xackery", message: "hello"})
+	deleted := &discordgo.MessageDelete{Message: &discordgo.Message{ID: "1", ChannelID: "123"}}
+	d.handleMessageDelete(nil, deleted)
+	d.handleMessageDelete(nil, deleted)
+	d.handleMessageDelete(nil, &discordgo.MessageDelete{Message: &discordgo.Message{ID: "2", ChannelID: "123"}})
+	if len(sent) != 1 || sent[0] != "emote world 260 Xackery says from discord, 'deleted a message'" {
+		t.Fatalf("wanted a single delete relayed, got %q", sent)
+	}
+	if len(d.editOrder) != 0 {
+		t.Fatalf("wanted deleted message forgotten, got %v", d.editOrder)
+	}
+}
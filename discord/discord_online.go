@@ -0,0 +1,45 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// OnlineCountUpdate renames the online count channel to show how many players are online.
+// The channel is only renamed when the name changes, since discord heavily rate limits channel edits
+func (t *Discord) OnlineCountUpdate(online int) error {
+	if t.config.OnlineCountChannelID == "" {
+		return nil
+	}
+	tmpl, err := template.New("online").Parse(t.config.OnlineCountName)
+	if err != nil {
+		return fmt.Errorf("parse online_count_name: %w", err)
+	}
+	buf := new(bytes.Buffer)
+	err = tmpl.Execute(buf, struct {
+		PlayerCount int
+	}{
+		online,
+	})
+	if err != nil {
+		return fmt.Errorf("execute online_count_name: %w", err)
+	}
+
+	name := buf.String()
+	t.mu.RLock()
+	isChanged := name != t.lastOnlineCountName
+	t.mu.RUnlock()
+	if !isChanged {
+		return nil
+	}
+
+	err = t.SetChannelName(t.config.OnlineCountChannelID, name)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.lastOnlineCountName = name
+	t.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,125 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/tlog"
+)
+
+const (
+	// channelRenameLimit is how many times discord allows a channel to be renamed per channelRenameWindow
+	channelRenameLimit = 2
+	// channelRenameWindow is the period channelRenameLimit applies to
+	channelRenameWindow = 10 * time.Minute
+)
+
+// channelRename tracks recent renames of a channel, and the latest name waiting for the rate limit to allow it
+type channelRename struct {
+	edits   []time.Time
+	current string
+	pending string
+}
+
+// isAllowed forgets edits outside of the rate limit window, and returns true if another rename can be made now
+func (r *channelRename) isAllowed(now time.Time) bool {
+	edits := []time.Time{}
+	for _, edit := range r.edits {
+		if now.Sub(edit) < channelRenameWindow {
+			edits = append(edits, edit)
+		}
+	}
+	r.edits = edits
+	return len(r.edits) < channelRenameLimit
+}
+
+// nextAllowed returns when the next rename can be made
+func (r *channelRename) nextAllowed() time.Time {
+	if len(r.edits) < channelRenameLimit {
+		return time.Now()
+	}
+	return r.edits[len(r.edits)-channelRenameLimit].Add(channelRenameWindow)
+}
+
+// SetChannelName renames a channel, used for voice channel setting via SQLReport and the online count.
+// Discord only allows a couple renames per channel every 10 minutes, so renames past that are deferred,
+// and only the latest deferred name is applied once the limit allows it
+func (t *Discord) SetChannelName(channelID string, name string) error {
+	if !t.IsConnected() {
+		return fmt.Errorf("discord not connected")
+	}
+
+	t.renameMu.Lock()
+	if t.renames == nil {
+		t.renames = make(map[string]*channelRename)
+	}
+	rename, ok := t.renames[channelID]
+	if !ok {
+		rename = &channelRename{}
+		t.renames[channelID] = rename
+	}
+	if name == rename.current {
+		rename.pending = ""
+		t.renameMu.Unlock()
+		return nil
+	}
+	if !rename.isAllowed(time.Now()) {
+		rename.pending = name
+		tlog.Debugf("[discord] rename of channel %s to %s deferred until %s due to rate limit", channelID, name, rename.nextAllowed().Format(time.Kitchen))
+		t.renameMu.Unlock()
+		return nil
+	}
+	rename.edits = append(rename.edits, time.Now())
+	rename.pending = ""
+	t.renameMu.Unlock()
+
+	conn := t.session()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	if _, err := conn.ChannelEdit(channelID, &discordgo.ChannelEdit{Name: name}); err != nil {
+		return fmt.Errorf("edit channel failed: %w", err)
+	}
+	t.renameMu.Lock()
+	rename.current = name
+	t.renameMu.Unlock()
+	tlog.Debugf("[discord] setting channel to %s", name)
+	return nil
+}
+
+// renameLoop applies deferred channel renames once the rate limit allows them
+func (t *Discord) renameLoop(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			tlog.Debugf("[discord] rename loop exit")
+			return
+		case <-ticker.C:
+		}
+
+		if !t.IsConnected() {
+			continue
+		}
+
+		due := map[string]string{}
+		t.renameMu.Lock()
+		for channelID, rename := range t.renames {
+			if rename.pending == "" || !rename.isAllowed(time.Now()) {
+				continue
+			}
+			due[channelID] = rename.pending
+		}
+		t.renameMu.Unlock()
+
+		for channelID, name := range due {
+			err := t.SetChannelName(channelID, name)
+			if err != nil {
+				tlog.Warnf("[discord] deferred rename of channel %s to %s failed: %s", channelID, name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package discord
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChannelRename_isAllowed(t *testing.T) {
+	now := time.Now()
+	rename := &channelRename{}
+	if !rename.isAllowed(now) {
+		t.Fatalf("no edits wanted allowed")
+	}
+	rename.edits = []time.Time{now.Add(-11 * time.Minute), now.Add(-5 * time.Minute)}
+	if !rename.isAllowed(now) {
+		t.Fatalf("one edit in window wanted allowed")
+	}
+	if len(rename.edits) != 1 {
+		t.Fatalf("wanted old edit forgotten, got %d edits", len(rename.edits))
+	}
+	rename.edits = append(rename.edits, now.Add(-time.Minute))
+	if rename.isAllowed(now) {
+		t.Fatalf("two edits in window wanted not allowed")
+	}
+	if want := now.Add(5 * time.Minute); !rename.nextAllowed().Equal(want) {
+		t.Fatalf("nextAllowed wanted %s, got %s", want, rename.nextAllowed())
+	}
+}
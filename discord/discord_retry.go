@@ -0,0 +1,116 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+const (
+	// maxSendRetries is how many times a failed send is retried before it is dropped
+	maxSendRetries = 3
+	// maxSendQueue is how many failed sends are kept for retrying before new failures are dropped
+	maxSendQueue = 100
+)
+
+// pendingSend is a failed send waiting to be retried
+type pendingSend struct {
+	req      request.DiscordSend
+	attempts int
+	next     time.Time
+}
+
+// isRetryable returns true if a send error is likely transient, e.g. a network error, rate limit or discord 5xx
+func isRetryable(err error) bool {
+	restErr := &discordgo.RESTError{}
+	if !errors.As(err, &restErr) || restErr.Response == nil {
+		return true
+	}
+	return restErr.Response.StatusCode == 429 || restErr.Response.StatusCode >= 500
+}
+
+// queueRetry adds a failed send to the retry queue, returning false if the queue is full and the send was dropped
+func (t *Discord) queueRetry(req request.DiscordSend) bool {
+	t.retryMu.Lock()
+	defer t.retryMu.Unlock()
+	if len(t.retryQueue) >= maxSendQueue {
+		atomic.AddInt64(&t.droppedSends, 1)
+		return false
+	}
+	t.retryQueue = append(t.retryQueue, pendingSend{
+		req:  req,
+		next: time.Now().Add(time.Second),
+	})
+	return true
+}
+
+// retryLoop re-attempts queued sends with exponential backoff, dropping them after maxSendRetries
+func (t *Discord) retryLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			tlog.Debugf("[discord] retry loop exit")
+			return
+		case <-ticker.C:
+		}
+
+		if !t.IsConnected() {
+			continue
+		}
+		t.flushRetries()
+	}
+}
+
+// flushRetries sends queued sends in the order they were queued, stopping at the first one that is
+// not due yet or fails again. Returns true if the queue is empty
+func (t *Discord) flushRetries() bool {
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+	for {
+		t.retryMu.Lock()
+		if len(t.retryQueue) == 0 {
+			t.retryMu.Unlock()
+			return true
+		}
+		// only flushRetries removes sends, so the first send stays first while it is sent
+		pending := t.retryQueue[0]
+		t.retryMu.Unlock()
+		if time.Now().Before(pending.next) {
+			return false
+		}
+
+		pending.attempts++
+		_, err := t.send(pending.req)
+		t.retryMu.Lock()
+		switch {
+		case err == nil:
+			tlog.Infof("[discord] retry %d of send to channel %s succeeded", pending.attempts, pending.req.ChannelID)
+			t.retryQueue = t.retryQueue[1:]
+		case pending.attempts >= maxSendRetries || !isRetryable(err):
+			atomic.AddInt64(&t.droppedSends, 1)
+			tlog.Warnf("[discord] dropped send to channel %s after %d retries: %s", pending.req.ChannelID, pending.attempts, err)
+			t.retryQueue = t.retryQueue[1:]
+		default:
+			pending.next = time.Now().Add(time.Duration(1<<pending.attempts) * time.Second)
+			t.retryQueue[0] = pending
+			t.retryMu.Unlock()
+			return false
+		}
+		t.retryMu.Unlock()
+	}
+}
+
+// SendStats returns how many failed sends are waiting to be retried, and how many were dropped since talkeq started
+func (t *Discord) SendStats() (queued int, dropped int64) {
+	t.retryMu.Lock()
+	queued = len(t.retryQueue)
+	t.retryMu.Unlock()
+	return queued, atomic.LoadInt64(&t.droppedSends)
+}
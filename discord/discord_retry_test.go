@@ -0,0 +1,87 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/request"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network", fmt.Errorf("dial tcp: connection refused"), true},
+		{"server error", &discordgo.RESTError{Response: &http.Response{StatusCode: 502}}, true},
+		{"rate limited", &discordgo.RESTError{Response: &http.Response{StatusCode: 429}}, true},
+		{"unknown channel", fmt.Errorf("send: %w", &discordgo.RESTError{Response: &http.Response{StatusCode: 404}}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	d := &Discord{}
+	for i := 0; i < maxSendQueue; i++ {
+		if !d.queueRetry(request.DiscordSend{ChannelID: "1"}) {
+			t.Fatalf("queueRetry %d wanted true", i)
+		}
+	}
+	if d.queueRetry(request.DiscordSend{ChannelID: "1"}) {
+		t.Fatalf("queueRetry on full queue wanted false")
+	}
+	queued, dropped := d.SendStats()
+	if queued != maxSendQueue || dropped != 1 {
+		t.Fatalf("SendStats wanted %d queued 1 dropped, got %d queued %d dropped", maxSendQueue, queued, dropped)
+	}
+}
+
+// TestSendDuringDisconnect sends while disconnecting, like /reconnect discord does. Run with -race
+func TestSendDuringDisconnect(t *testing.T) {
+	d := &Discord{isConnected: true}
+	d.config.IsEnabled = true
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			result, err := d.SendWithResult(request.DiscordSend{ChannelID: "123", Message: "hello"})
+			if !result.IsQueued || err != nil {
+				t.Errorf("send %d without a session wanted queued without error, got %v", i, err)
+				return
+			}
+		}
+	}()
+	err := d.Disconnect(context.Background())
+	if err != nil {
+		t.Fatalf("disconnect: %s", err)
+	}
+	<-done
+	if d.IsConnected() {
+		t.Fatalf("wanted disconnected")
+	}
+}
+
+func TestFlushRetries_order(t *testing.T) {
+	d := &Discord{}
+	for _, message := range []string{"first", "second"} {
+		d.queueRetry(request.DiscordSend{ChannelID: "123", Message: message})
+	}
+	d.retryQueue[0].next = time.Now()
+	if d.flushRetries() {
+		t.Fatalf("flushRetries without a session wanted sends left in the queue")
+	}
+	if d.retryQueue[0].req.Message != "first" || d.retryQueue[0].attempts != 1 || d.retryQueue[1].attempts != 0 {
+		t.Fatalf("wanted first send retried and kept first, second send left alone, got %+v", d.retryQueue)
+	}
+}
@@ -0,0 +1,134 @@
+package discord
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/characterdb"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+	"github.com/xackery/talkeq/userdb"
+)
+
+// Tell relays an in game tell as a DM to the recipient's discord user,
+// if they are registered, opted in, and not currently online in game.
+// Returns request.ErrNotHandled if the tell is not for a DM, so it can be routed like other chat
+func (t *Discord) Tell(req request.DiscordTell) error {
+	if !t.config.IsEnabled {
+		return fmt.Errorf("not enabled")
+	}
+
+	if !t.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+
+	discordID := userdb.DiscordID(req.ToName)
+	if discordID == "" {
+		return request.ErrNotHandled
+	}
+	t.awayReply(discordID, req)
+	if !userdb.IsTellDMEnabled(discordID) {
+		return request.ErrNotHandled
+	}
+	if characterdb.IsOnline(req.ToName) {
+		return request.ErrNotHandled
+	}
+
+	t.tellMu.Lock()
+	lastTell, ok := t.lastTellDM[discordID]
+	if ok && time.Since(lastTell) < time.Duration(t.config.TellDMCooldown)*time.Second {
+		t.tellMu.Unlock()
+		tlog.Debugf("[discord] tell from %s to %s dropped, cooldown active", req.FromName, req.ToName)
+		return nil
+	}
+	t.lastTellDM[discordID] = time.Now()
+	t.tellMu.Unlock()
+
+	err := t.sendDM(discordID, fmt.Sprintf("%s tells %s, '%s'", req.FromName, req.ToName, req.Message))
+	if err != nil {
+		return err
+	}
+	tlog.Infof("[discord] relayed tell from %s to %s as a DM", req.FromName, req.ToName)
+	return nil
+}
+
+// DirectMessage sends message to a discord user as a DM
+func (t *Discord) DirectMessage(discordID string, message string) error {
+	if !t.config.IsEnabled {
+		return fmt.Errorf("not enabled")
+	}
+
+	if !t.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return t.sendDM(discordID, message)
+}
+
+func (t *Discord) sendDM(discordID string, message string) error {
+	conn := t.session()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	channel, err := conn.UserChannelCreate(discordID)
+	if err != nil {
+		return fmt.Errorf("UserChannelCreate: %w", err)
+	}
+	_, err = conn.ChannelMessageSendComplex(channel.ID, &discordgo.MessageSend{
+		Content:         message,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		return fmt.Errorf("ChannelMessageSend: %w", err)
+	}
+	return nil
+}
+
+func (t *Discord) tellsRegister() error {
+	tlog.Debugf("[discord] registering tells command")
+	err := t.createCommand(&discordgo.ApplicationCommand{
+		Name:        "tells",
+		Description: "receive in game tells as DMs while you are offline, with /tells on|off",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "state",
+				Description: "on or off",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "on", Value: "on"},
+					{Name: "off", Value: "off"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("tellsRegister commandCreate: %w", err)
+	}
+	return nil
+}
+
+func (t *Discord) tells(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.InteractionResponseData, error) {
+	state := ""
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Name == "state" {
+			state = fmt.Sprintf("%s", option.Value)
+		}
+	}
+	if state != "on" && state != "off" {
+		return &discordgo.InteractionResponseData{Content: "usage: /tells on|off"}, nil
+	}
+
+	userID := interactionUserID(i)
+	if userdb.Name(userID) == "" {
+		return &discordgo.InteractionResponseData{Content: "you need to be registered in the users database to receive tells"}, nil
+	}
+	err := userdb.SetTellDM(userID, state == "on")
+	if err != nil {
+		return nil, fmt.Errorf("setTellDM: %w", err)
+	}
+	if state == "off" {
+		return &discordgo.InteractionResponseData{Content: fmt.Sprintf("in game tells to %s will no longer be sent to you as DMs", userdb.Name(userID))}, nil
+	}
+	return &discordgo.InteractionResponseData{Content: fmt.Sprintf("in game tells to %s will be sent to you as DMs while you are offline", userdb.Name(userID))}, nil
+}
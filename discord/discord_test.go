@@ -0,0 +1,77 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/config"
+)
+
+func TestDiscord_Disconnect_stopsLoop(t *testing.T) {
+	d := &Discord{config: config.Discord{IsEnabled: true}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	loopCtx, loopCancel := context.WithCancel(ctx)
+	d.connCancel = loopCancel
+	done := make(chan struct{})
+	go func() {
+		d.loop(loopCtx)
+		close(done)
+	}()
+
+	err := d.Disconnect(ctx)
+	if err != nil {
+		t.Fatalf("disconnect: %s", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("loop wanted to exit on disconnect")
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("disconnect wanted only the connection ctx cancelled")
+	}
+}
+
+func TestIsChannelUnusable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network", fmt.Errorf("dial tcp: connection refused"), false},
+		{"server error", &discordgo.RESTError{Response: &http.Response{StatusCode: 502}}, false},
+		{"rate limited", &discordgo.RESTError{Response: &http.Response{StatusCode: 429}}, false},
+		{"missing access", &discordgo.RESTError{Response: &http.Response{StatusCode: 403}}, true},
+		{"unknown channel", fmt.Errorf("channel: %w", &discordgo.RESTError{Response: &http.Response{StatusCode: 404}}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isChannelUnusable(tt.err); got != tt.want {
+				t.Errorf("isChannelUnusable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscord_resumeUnusableRoutes(t *testing.T) {
+	d := &Discord{unusableRoutes: map[int]bool{1: true}}
+	d.config.Routes = []config.DiscordRoute{
+		{IsEnabled: false},
+		{IsEnabled: false},
+	}
+	d.resumeUnusableRoutes()
+	if d.config.Routes[0].IsEnabled {
+		t.Fatalf("route disabled in config wanted to stay disabled")
+	}
+	if !d.config.Routes[1].IsEnabled {
+		t.Fatalf("unusable route wanted to be enabled for checking again")
+	}
+	if len(d.unusableRoutes) != 0 {
+		t.Fatalf("unusable routes wanted cleared, got %d", len(d.unusableRoutes))
+	}
+}
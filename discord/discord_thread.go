@@ -0,0 +1,136 @@
+package discord
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// threadArchiveMinutes is how long a thread created by talkeq stays open without messages
+const threadArchiveMinutes = 10080
+
+// threadID returns the ID of a thread in channelID with provided name.
+// Active threads are reused, otherwise a new public thread is started
+func (t *Discord) threadID(channelID string, name string) (string, error) {
+	key := channelID + "/" + strings.ToLower(name)
+	t.threadMu.Lock()
+	defer t.threadMu.Unlock()
+	id, ok := t.threads[key]
+	if ok {
+		return id, nil
+	}
+
+	conn := t.session()
+	if conn == nil {
+		return "", fmt.Errorf("not connected")
+	}
+	active, err := conn.ThreadsActive(channelID)
+	if err != nil {
+		tlog.Debugf("[discord] list active threads of %s failed, starting a new one: %s", channelID, err)
+	}
+	if active != nil {
+		for _, thread := range active.Threads {
+			if strings.EqualFold(thread.Name, name) {
+				t.threads[key] = thread.ID
+				return thread.ID, nil
+			}
+		}
+	}
+
+	thread, err := conn.ThreadStart(channelID, name, discordgo.ChannelTypeGuildPublicThread, threadArchiveMinutes)
+	if err != nil {
+		return "", fmt.Errorf("threadStart: %w", err)
+	}
+	tlog.Infof("[discord] started thread %s in channel %s", name, channelID)
+	t.threads[key] = thread.ID
+	return thread.ID, nil
+}
+
+// forgetThread removes a cached thread, so it is looked up again on the next send
+func (t *Discord) forgetThread(channelID string, name string) {
+	t.threadMu.Lock()
+	defer t.threadMu.Unlock()
+	delete(t.threads, channelID+"/"+strings.ToLower(name))
+}
+
+// forumTitleMax is the longest name discord allows for a forum post
+const forumTitleMax = 100
+
+// isForum returns true if channelID is a forum channel, which only accepts new posts
+func (t *Discord) isForum(channelID string) bool {
+	t.threadMu.Lock()
+	defer t.threadMu.Unlock()
+	isForum, ok := t.forums[channelID]
+	if ok {
+		return isForum
+	}
+	conn := t.session()
+	if conn == nil {
+		return false
+	}
+	channel, err := conn.State.Channel(channelID)
+	if err != nil {
+		channel, err = conn.Channel(channelID)
+		if err != nil {
+			tlog.Debugf("[discord] channel %s lookup failed, assuming a text channel: %s", channelID, err)
+			return false
+		}
+	}
+	isForum = channel.Type == discordgo.ChannelTypeGuildForum
+	t.forums[channelID] = isForum
+	return isForum
+}
+
+// sendForumPost creates a forum post for a message, since forum channels do not accept plain messages
+func (t *Discord) sendForumPost(req request.DiscordSend, send *discordgo.MessageSend) (string, error) {
+	conn := t.session()
+	if conn == nil {
+		return "", fmt.Errorf("not connected")
+	}
+	thread, err := conn.ForumThreadStartComplex(req.ChannelID, &discordgo.ThreadStart{
+		Name:                forumPostTitle(req),
+		AutoArchiveDuration: threadArchiveMinutes,
+	}, send)
+	if err != nil {
+		return "", fmt.Errorf("ForumThreadStart: %w", err)
+	}
+	if req.FromName != "" {
+		t.trackRelay(thread.ID, req.FromName)
+	}
+	// the first message of a forum post has the same ID as the post
+	return thread.ID, nil
+}
+
+// forumPostTitle returns the title of a forum post: the listed items of an auction, otherwise the start of the message
+func forumPostTitle(req request.DiscordSend) string {
+	title := ""
+	if req.Embed != nil {
+		names := []string{}
+		for _, field := range req.Embed.Fields {
+			// auction items are inline fields, the summary field below them is not
+			if !field.Inline {
+				continue
+			}
+			names = append(names, field.Name)
+		}
+		title = strings.Join(names, ", ")
+		if title == "" {
+			title = req.Embed.Title
+		}
+	}
+	if title == "" {
+		title = req.Message
+	}
+	if title == "" {
+		title = "talkeq"
+	}
+	title = strings.Join(strings.Fields(title), " ")
+	runes := []rune(title)
+	if len(runes) > forumTitleMax {
+		title = strings.TrimSpace(string(runes[:forumTitleMax-3])) + "..."
+	}
+	return title
+}
@@ -0,0 +1,31 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xackery/talkeq/request"
+)
+
+func TestForumPostTitle(t *testing.T) {
+	req := request.DiscordSend{
+		Message: "Shin auctions, 'WTS Cloak of Flames 5k, Fungus Covered Scale Tunic'",
+		Embed: &request.DiscordEmbed{
+			Title: "Shin's auction",
+			Fields: []request.DiscordEmbedField{
+				{Name: "WTS Cloak of Flames", Value: "5000pp", Inline: true},
+				{Name: "WTS Fungus Covered Scale Tunic", Value: "offer", Inline: true},
+				{Name: "Summary", Value: "2 items"},
+			},
+		},
+	}
+	if got := forumPostTitle(req); got != "WTS Cloak of Flames, WTS Fungus Covered Scale Tunic" {
+		t.Fatalf("auction title = %q", got)
+	}
+
+	req = request.DiscordSend{Message: strings.Repeat("word ", 40)}
+	got := forumPostTitle(req)
+	if len([]rune(got)) > forumTitleMax || !strings.HasSuffix(got, "...") {
+		t.Fatalf("long title = %q", got)
+	}
+}
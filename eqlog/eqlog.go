@@ -5,9 +5,10 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"regexp"
+	"strings"
 	"sync"
 
+	"github.com/xackery/talkeq/auction"
 	"github.com/xackery/talkeq/request"
 	"github.com/xackery/talkeq/tlog"
 
@@ -115,59 +116,130 @@ func (t *EQLog) loop(ctx context.Context) {
 		default:
 		}
 
-		for routeIndex, route := range t.config.Routes {
-			if !route.IsEnabled {
-				continue
-			}
-			pattern, err := regexp.Compile(route.Trigger.Regex)
-			if err != nil {
-				tlog.Debugf("[eqlog] route %d compile failed: %s", routeIndex, err)
-				continue
-			}
-			matches := pattern.FindAllStringSubmatch(line.Text, -1)
-			if len(matches) == 0 {
-				continue
-			}
+		t.parseLine(ctx, line.Text)
+	}
+}
 
-			name := ""
-			message := ""
-			if route.Trigger.MessageIndex >= len(matches[0]) {
-				message = matches[0][route.Trigger.MessageIndex]
-			}
-			if route.Trigger.NameIndex >= len(matches[0]) {
-				name = matches[0][route.Trigger.NameIndex]
-			}
+func (t *EQLog) parseLine(ctx context.Context, text string) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	isMatched := false
+	isAuctionSaved := false
+	for routeIndex, route := range t.config.Routes {
+		if !route.IsEnabled {
+			continue
+		}
+		pattern, err := route.TriggerRegex()
+		if err != nil {
+			tlog.Debugf("[eqlog] route %d compile failed: %s", routeIndex, err)
+			continue
+		}
+		matches := pattern.FindAllStringSubmatch(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		isMatched = true
+		route.Stats().Matched()
+
+		name := ""
+		message := ""
+		if route.Trigger.MessageIndex < len(matches[0]) {
+			message = matches[0][route.Trigger.MessageIndex]
+		}
+		if route.Trigger.NameIndex < len(matches[0]) {
+			name = matches[0][route.Trigger.NameIndex]
+		}
+		if route.Trigger.NameIndex > 0 && t.config.IsSelf(name) {
+			tlog.Debugf("[eqlog] route %d skipped, %s is in self_names", routeIndex, name)
+			continue
+		}
+		if !route.IsMatch(message) {
+			continue
+		}
+		name = route.DisplayName(name)
 
-			buf := new(bytes.Buffer)
-			if err := route.MessagePatternTemplate().Execute(buf, struct {
-				Name    string
-				Message string
-			}{
-				name,
-				message,
-			}); err != nil {
-				tlog.Warnf("[eqlog] execute route %d: %s", routeIndex, err)
-				continue
+		buf := new(bytes.Buffer)
+		if err := route.MessagePatternTemplate().Execute(buf, struct {
+			Name    string
+			Message string
+		}{
+			name,
+			message,
+		}); err != nil {
+			tlog.Warnf("[eqlog] execute route %d: %s", routeIndex, err)
+			continue
+		}
+		switch route.Target {
+		case "discord":
+			// a line relayed to several channels or by several routes is still one listing
+			if route.IsAuctionEmbed && !isAuctionSaved {
+				isAuctionSaved = true
+				auction.SaveMessage(name, message)
 			}
-			switch route.Target {
-			case "discord":
+			for _, channelID := range route.Destinations() {
+				// typing goes first, discord clears the indicator once the message arrives
+				if route.IsTypingEnabled {
+					typingReq := request.DiscordTyping{
+						Ctx:       ctx,
+						ChannelID: channelID,
+					}
+					for i, s := range t.subscribers {
+						err = s(typingReq)
+						if err != nil {
+							tlog.Debugf("[eqlog->discord subscriber %d] typing channelID %s failed: %s", i, channelID, err)
+						}
+					}
+				}
 				req := request.DiscordSend{
 					Ctx:       ctx,
-					ChannelID: route.ChannelID,
+					ChannelID: channelID,
 					Message:   buf.String(),
+					FromName:  name,
+					Text:      message,
+					IsAuction: route.IsAuctionEmbed,
+					UseEmbed:  route.UseEmbed,
 				}
-				for _, s := range t.subscribers {
+				for i, s := range t.subscribers {
 					err = s(req)
 					if err != nil {
-						tlog.Warnf("[eqlog->discord subscriber %d] discordSend channelID %s message %s failed: %s", route.ChannelID, req.Message, err)
+						tlog.Warnf("[eqlog->discord subscriber %d] discordSend channelID %s message %s failed: %s", i, channelID, req.Message, err)
 						continue
 					}
-					tlog.Infof("[eqlog->discord subscriber %d] message: %s", route.ChannelID, req.Message)
+					tlog.Infof("[eqlog->discord subscriber %d] channelID %s message: %s", i, channelID, req.Message)
 				}
-			default:
-				tlog.Warnf("[eqlog] unsupported target type: %s", route.Target)
-				continue
 			}
+		default:
+			tlog.Warnf("[eqlog] unsupported target type: %s", route.Target)
+			continue
+		}
+	}
+	if !isMatched {
+		t.sendUnmatched(ctx, text)
+	}
+}
+
+// sendUnmatched relays a line that matched no enabled route, if enabled. Must be called while holding t.mutex
+func (t *EQLog) sendUnmatched(ctx context.Context, text string) {
+	if !t.config.Unmatched.IsEnabled {
+		return
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	tlog.Debugf("[eqlog] unmatched: %s", text)
+	if t.config.Unmatched.ChannelID == "" {
+		return
+	}
+	req := request.DiscordSend{
+		Ctx:       ctx,
+		ChannelID: t.config.Unmatched.ChannelID,
+		Message:   fmt.Sprintf("unmatched: %s", text),
+	}
+	for i, s := range t.subscribers {
+		err := s(req)
+		if err != nil {
+			tlog.Debugf("[eqlog->discord subscriber %d] unmatched channelID %s failed: %s", i, req.ChannelID, err)
 		}
 	}
 }
@@ -193,6 +265,21 @@ func (t *EQLog) Send(ctx context.Context, source string, author string, channelI
 	return fmt.Errorf("not supported")
 }
 
+// SetRouteEnabled enables or disables all routes relaying to provided channel, returning how many changed
+func (t *EQLog) SetRouteEnabled(channelID string, isEnabled bool) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	count := 0
+	for i := range t.config.Routes {
+		if !t.config.Routes[i].HasDestination(channelID) {
+			continue
+		}
+		t.config.Routes[i].IsEnabled = isEnabled
+		count++
+	}
+	return count
+}
+
 // Subscribe listens for new events on eqlog
 func (t *EQLog) Subscribe(ctx context.Context, onMessage func(interface{}) error) error {
 	t.mutex.Lock()
@@ -0,0 +1,48 @@
+package eqlog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+)
+
+func TestEQLog_parseLine(t *testing.T) {
+	route := config.Route{
+		IsEnabled: true,
+		Trigger: config.Trigger{
+			Regex:        `(\w+) says out of character, '(.*)'`,
+			NameIndex:    1,
+			MessageIndex: 2,
+		},
+		Target:         "discord",
+		ChannelID:      "123",
+		MessagePattern: "{{.Name}} **OOC**: {{.Message}}",
+	}
+	err := route.LoadMessagePattern()
+	if err != nil {
+		t.Fatalf("loadMessagePattern: %s", err)
+	}
+	e, err := New(context.Background(), config.EQLog{
+		Routes:    []config.Route{route},
+		SelfNames: []string{"Xackery"},
+	})
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	sent := []request.DiscordSend{}
+	e.subscribers = append(e.subscribers, func(rawReq interface{}) error {
+		sent = append(sent, rawReq.(request.DiscordSend))
+		return nil
+	})
+
+	e.parseLine(context.Background(), "[Mon Oct 16 12:00:00 2026] Xackery says out of character, 'echo'")
+	if len(sent) != 0 {
+		t.Fatalf("self message wanted skipped, got %+v", sent)
+	}
+	e.parseLine(context.Background(), "[Mon Oct 16 12:00:00 2026] Shin says out of character, 'hello'")
+	if len(sent) != 1 || sent[0].Message != "Shin **OOC**: hello" {
+		t.Fatalf("wanted Shin relayed, got %+v", sent)
+	}
+}
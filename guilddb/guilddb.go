@@ -16,6 +16,7 @@ import (
 var (
 	isStarted          bool
 	guilds             map[int]string
+	guildNames         map[int]string
 	mu                 sync.RWMutex
 	guildsDatabasePath string
 )
@@ -30,7 +31,7 @@ func New(config *config.Config) error {
 	tlog.Debugf("[guilddb] initializing")
 	_, err := os.Stat(guildsDatabasePath)
 	if os.IsNotExist(err) {
-		err = ioutil.WriteFile(guildsDatabasePath, []byte(`# guildid:channelid #comment`), 0644)
+		err = ioutil.WriteFile(guildsDatabasePath, []byte(`# guildid:channelid:optional guild name #comment`), 0644)
 		if err != nil {
 			return fmt.Errorf("guilds database create %w", err)
 		}
@@ -96,6 +97,7 @@ func reload() error {
 	}
 
 	ng := make(map[int]string)
+	nn := make(map[int]string)
 	lines := strings.Split(string(data), "\n")
 	for lineNumber, line := range lines {
 		lineNumber++
@@ -133,15 +135,21 @@ func reload() error {
 		if p > 0 {
 			name = name[0:p]
 		}
+		name, guildName, _ := strings.Cut(name, ":")
 		name = strings.TrimSpace(name)
 		_, ok := ng[id]
 		if ok {
 			tlog.Debugf("[guilddb] line %d skipped, guildID %d is a duplicate entry", lineNumber, id)
 		}
 		ng[id] = name
+		guildName = strings.TrimSpace(guildName)
+		if guildName != "" {
+			nn[id] = guildName
+		}
 	}
 
 	guilds = ng
+	guildNames = nn
 	return nil
 }
 
@@ -159,6 +167,13 @@ func ChannelID(guildID int) string {
 	return guilds[guildID]
 }
 
+// Name returns the name of a guild set in the guilds database, or an empty string
+func Name(guildID int) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return guildNames[guildID]
+}
+
 // GuildID returns the EQ guildID of a guild based on a provided discord channelID, returns 0 if no results
 func GuildID(channelID string) int {
 	mu.RLock()
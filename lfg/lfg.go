@@ -0,0 +1,114 @@
+// Package lfg finds players looking for a group, or groups looking for members, in chat messages
+package lfg
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	kindPattern  = regexp.MustCompile(`(?i)\b(LFG|LFM)\b`)
+	levelPattern = regexp.MustCompile(`(?i)\b(?:lvl|level|lv)\s?(\d{1,3})\b`)
+	// classLevelPattern is a class and level written as one token, e.g. 52war or war52
+	classLevelPattern = regexp.MustCompile(`^(?:(\d{1,3})([a-z]+)|([a-z]+)(\d{1,3}))$`)
+
+	// ambiguousClasses are abbreviations that are also ordinary words, only matched next to a level, e.g. 52 war
+	ambiguousClasses = map[string]bool{"war": true, "pal": true, "ran": true, "ber": true, "sham": true}
+
+	// classes maps lowercase class names and common abbreviations to a class name
+	classes = map[string]string{
+		"warrior": "Warrior", "war": "Warrior", "warr": "Warrior",
+		"cleric": "Cleric", "clr": "Cleric", "cle": "Cleric",
+		"paladin": "Paladin", "pal": "Paladin", "pally": "Paladin",
+		"ranger": "Ranger", "rng": "Ranger", "ran": "Ranger",
+		"shadowknight": "Shadow Knight", "shd": "Shadow Knight", "sk": "Shadow Knight",
+		"druid": "Druid", "dru": "Druid",
+		"monk": "Monk", "mnk": "Monk",
+		"bard": "Bard", "brd": "Bard",
+		"rogue": "Rogue", "rog": "Rogue",
+		"shaman": "Shaman", "shm": "Shaman", "sham": "Shaman",
+		"necromancer": "Necromancer", "nec": "Necromancer", "necro": "Necromancer",
+		"wizard": "Wizard", "wiz": "Wizard",
+		"magician": "Magician", "mag": "Magician", "mage": "Magician",
+		"enchanter": "Enchanter", "enc": "Enchanter", "chanter": "Enchanter",
+		"beastlord": "Beastlord", "bst": "Beastlord",
+		"berserker": "Berserker", "ber": "Berserker", "zerker": "Berserker",
+		"tank": "Tank", "healer": "Healer", "puller": "Puller",
+	}
+)
+
+// Listing is a parsed LFG or LFM message
+type Listing struct {
+	Name string
+	// Kind is LFG when a player is looking for a group, or LFM when a group is looking for members
+	Kind    string
+	Level   int
+	Class   string
+	Message string
+}
+
+// Parse returns a listing if message looks for a group or members, with level and class filled in when found
+func Parse(name string, message string) (*Listing, bool) {
+	kind := kindPattern.FindStringSubmatch(message)
+	if len(kind) == 0 {
+		return nil, false
+	}
+	listing := &Listing{
+		Name:    name,
+		Kind:    strings.ToUpper(kind[1]),
+		Message: message,
+	}
+	for _, match := range levelPattern.FindAllStringSubmatch(message, -1) {
+		level := parseLevel(match[1])
+		if level == 0 {
+			continue
+		}
+		listing.Level = level
+		break
+	}
+
+	// classes are matched as whole tokens, and a level only counts next to a class when it has no lvl prefix
+	tokens := []string{}
+	for _, token := range strings.Fields(strings.ToLower(message)) {
+		token = strings.Trim(token, `.,!?;:()[]'"+`)
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	for i, token := range tokens {
+		word, level := token, 0
+		match := classLevelPattern.FindStringSubmatch(token)
+		if len(match) > 0 {
+			word, level = match[2]+match[3], parseLevel(match[1]+match[4])
+		}
+		class, ok := classes[word]
+		if !ok {
+			continue
+		}
+		if level == 0 && i > 0 {
+			level = parseLevel(tokens[i-1])
+		}
+		if level == 0 && i+1 < len(tokens) {
+			level = parseLevel(tokens[i+1])
+		}
+		if level == 0 && ambiguousClasses[word] {
+			continue
+		}
+		listing.Class = class
+		if listing.Level == 0 {
+			listing.Level = level
+		}
+		break
+	}
+	return listing, true
+}
+
+// parseLevel returns value as a character level, or 0 if it is not one
+func parseLevel(value string) int {
+	level, err := strconv.Atoi(value)
+	if err != nil || level < 1 || level > 125 {
+		return 0
+	}
+	return level
+}
@@ -0,0 +1,36 @@
+package lfg
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		message   string
+		wantOK    bool
+		wantKind  string
+		wantLevel int
+		wantClass string
+	}{
+		{"LFG 52 war, can tank", true, "LFG", 52, "Warrior"},
+		{"lfm sebilis, need a cleric lvl 60+", true, "LFM", 60, "Cleric"},
+		{"anyone lfg?", true, "LFG", 0, ""},
+		{"lfg 52war", true, "LFG", 52, "Warrior"},
+		{"LFM necro 55 for guk", true, "LFM", 55, "Necromancer"},
+		{"LFG, ran out of groups after 30 minutes", true, "LFG", 0, ""},
+		{"lfm need a pal for 6 hours", true, "LFM", 0, ""},
+		{"LFG wizard, need 2 more", true, "LFG", 0, "Wizard"},
+		{"selling a flowing black silk sash", false, "", 0, ""},
+		{"WTS self-help", false, "", 0, ""},
+	}
+	for _, tt := range tests {
+		listing, ok := Parse("Shin", tt.message)
+		if ok != tt.wantOK {
+			t.Fatalf("Parse(%q) ok = %v, want %v", tt.message, ok, tt.wantOK)
+		}
+		if !ok {
+			continue
+		}
+		if listing.Kind != tt.wantKind || listing.Level != tt.wantLevel || listing.Class != tt.wantClass {
+			t.Fatalf("Parse(%q) = %s %d %q, want %s %d %q", tt.message, listing.Kind, listing.Level, listing.Class, tt.wantKind, tt.wantLevel, tt.wantClass)
+		}
+	}
+}
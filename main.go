@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
@@ -15,6 +16,30 @@ import (
 var Version string
 
 func main() {
+	isValidate := flag.Bool("validate", false, "load and validate talkeq.conf, print a report, then exit without starting any services")
+	isConnectCheck := flag.Bool("connect", false, "with -validate, also check telnet and sql hosts can be reached")
+	testLine := flag.String("test-route", "", "run a sample telnet or eqlog line through all enabled routes, print what matches, then exit")
+	flag.Parse()
+
+	if *testLine != "" {
+		err := testRoute("talkeq.conf", *testLine)
+		if err != nil {
+			fmt.Printf("test-route failed: %s\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *isValidate {
+		err := validateConfig("talkeq.conf", *isConnectCheck)
+		if err != nil {
+			fmt.Printf("validate failed: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("talkeq.conf is valid")
+		os.Exit(0)
+	}
+
 	w, err := os.Create("talkeq.log")
 	if err != nil {
 		fmt.Println(err)
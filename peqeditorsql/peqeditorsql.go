@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"regexp"
 	"sync"
 	"time"
 
@@ -185,7 +184,7 @@ func (t *PEQEditorSQL) handleMessage(ctx context.Context, line string) {
 		if !route.IsEnabled {
 			continue
 		}
-		pattern, err := regexp.Compile(route.Trigger.Regex)
+		pattern, err := route.TriggerRegex()
 		if err != nil {
 			tlog.Debugf("[peqeditorsql] compile route %d skipped: %s", routeIndex, err)
 			continue
@@ -194,6 +193,7 @@ func (t *PEQEditorSQL) handleMessage(ctx context.Context, line string) {
 		if len(matches) == 0 {
 			continue
 		}
+		route.Stats().Matched()
 
 		name := ""
 		message := ""
@@ -203,6 +203,9 @@ func (t *PEQEditorSQL) handleMessage(ctx context.Context, line string) {
 		if route.Trigger.NameIndex > 0 && route.Trigger.NameIndex <= len(matches[0]) {
 			name = matches[0][route.Trigger.NameIndex]
 		}
+		if !route.IsMatch(message) {
+			continue
+		}
 
 		buf := new(bytes.Buffer)
 		if err := route.MessagePatternTemplate().Execute(buf, struct {
@@ -217,18 +220,20 @@ func (t *PEQEditorSQL) handleMessage(ctx context.Context, line string) {
 		}
 		switch route.Target {
 		case "discord":
-			req := request.DiscordSend{
-				Ctx:       ctx,
-				ChannelID: route.ChannelID,
-				Message:   buf.String(),
-			}
-			for i, s := range t.subscribers {
-				err = s(req)
-				if err != nil {
-					tlog.Warnf("[peqeditorsql->discord subscriber %d] channel %s message %s failed: %s", i, route.ChannelID, req.Message)
-					continue
+			for _, channelID := range route.Destinations() {
+				req := request.DiscordSend{
+					Ctx:       ctx,
+					ChannelID: channelID,
+					Message:   buf.String(),
+				}
+				for i, s := range t.subscribers {
+					err = s(req)
+					if err != nil {
+						tlog.Warnf("[peqeditorsql->discord subscriber %d] channel %s message %s failed: %s", i, channelID, req.Message, err)
+						continue
+					}
+					tlog.Infof("[peqeditorsql->discord subscribe %d] channel %s message: %s", i, channelID, req.Message)
 				}
-				tlog.Infof("[peqeditorsql->discord subscribe %d] channel %s message: %s", i, route.ChannelID, req.Message)
 			}
 			isSent = true
 		default:
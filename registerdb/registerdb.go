@@ -1,7 +1,9 @@
 package registerdb
 
 import (
+	"crypto/rand"
 	"fmt"
+	"math/big"
 	"os"
 	"sync"
 	"time"
@@ -145,8 +147,28 @@ func save() error {
 	return nil
 }
 
-// Set updates or adds an entry for a specified register id
-func Set(discordID string, discordName string, characterName string, channelID string, messageID string, status string, timeout int64) {
+// codeAlphabet leaves out characters that are easily mistaken for each other, like O and 0
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// newCode returns a random 6 character registration code
+func newCode() (string, error) {
+	code := make([]byte, 6)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(codeAlphabet))))
+		if err != nil {
+			return "", fmt.Errorf("rand: %w", err)
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// Set updates or adds an entry for a specified register id, and returns the entry's new confirmation code
+func Set(discordID string, discordName string, characterName string, channelID string, messageID string, status string, timeout int64) (string, error) {
+	code, err := newCode()
+	if err != nil {
+		return "", fmt.Errorf("newCode: %w", err)
+	}
 	mu.Lock()
 	defer mu.Unlock()
 	re := RegisterEntry{
@@ -157,13 +179,14 @@ func Set(discordID string, discordName string, characterName string, channelID s
 		MessageID:     messageID,
 		ChannelID:     channelID,
 		Timeout:       timeout,
-		Code:          "1234",
+		Code:          code,
 	}
 	db.Registrations[discordID] = re
-	err := save()
+	err = save()
 	if err != nil {
 		tlog.Warnf("[registerdb] save failed: %s", err)
 	}
+	return code, nil
 }
 
 // FindByCode returns an entry if code matches and is valid
@@ -2,6 +2,7 @@ package request
 
 import (
 	"context"
+	"time"
 )
 
 // DiscordSend Request
@@ -9,6 +10,86 @@ type DiscordSend struct {
 	Ctx       context.Context
 	ChannelID string
 	Message   string
+	FromName  string
+	// Embed is optional, if set the message is sent as the description of an embed
+	Embed *DiscordEmbed
+	// ExtraEmbeds is optional, more embeds sent after Embed in the same message, e.g. the rest of a long auction
+	ExtraEmbeds []*DiscordEmbed
+	// RoleID is optional, a discord role to mention with the message
+	RoleID string
+	// Text is optional, the relayed chat message without route formatting, used to detect echoes
+	Text string
+	// IsAuction is set when the route allows auction messages to be sent as an auction embed
+	IsAuction bool
+	// UseEmbed is optional, embed or plain forces how the message is sent
+	UseEmbed string
+	// ThreadName is optional, the message is sent to a thread of ChannelID with this name, created if needed
+	ThreadName string
+}
+
+// DiscordEmbed styles a DiscordSend as an embed
+type DiscordEmbed struct {
+	Title  string
+	URL    string
+	Color  int
+	Fields []DiscordEmbedField
+}
+
+// DiscordEmbedField is a name and value shown in a DiscordEmbed
+type DiscordEmbedField struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+// DiscordTyping Request
+type DiscordTyping struct {
+	Ctx       context.Context
+	ChannelID string
+}
+
+// DiscordTell Request, an in game tell to be relayed as a DM to the recipient's discord user
+type DiscordTell struct {
+	Ctx      context.Context
+	FromName string
+	ToName   string
+	Message  string
+}
+
+// DiscordLFG Request, a group finder listing to post to an LFG channel
+type DiscordLFG struct {
+	Ctx       context.Context
+	ChannelID string
+	Name      string
+	Kind      string
+	Level     int
+	Class     string
+	Message   string
+	Color     int
+	// Expire is how long before the listing is marked as expired
+	Expire time.Duration
+}
+
+// RouteToggle Request, enables or disables routes relaying to or from a discord channel
+type RouteToggle struct {
+	Ctx       context.Context
+	ChannelID string
+	IsEnabled bool
+}
+
+// Reconnect Request, sent by an endpoint that lost its connection so it is reconnected without waiting for keep alive
+type Reconnect struct {
+	Ctx      context.Context
+	Endpoint string
+	// IsManual is true when an admin asked to reconnect, which reconnects even if connected, and retries an endpoint keep alive gave up on
+	IsManual bool
+}
+
+// RegisterCode Request, a character said what may be a registration code in game
+type RegisterCode struct {
+	Ctx           context.Context
+	CharacterName string
+	Code          string
 }
 
 // DiscordEdit Request
@@ -43,6 +124,12 @@ type EQLog struct {
 type TelnetSend struct {
 	Ctx     context.Context
 	Message string
+	// FromName is optional, the IGN of the discord user the message is relayed from
+	FromName string
+	// DiscordChannelID is optional, the discord channel the message was said in
+	DiscordChannelID string
+	// Text is optional, the relayed chat message without route formatting, used to detect echoes
+	Text string
 }
 
 // PEQEditorSQL originated from PEQ Editor
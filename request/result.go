@@ -0,0 +1,15 @@
+package request
+
+import "errors"
+
+// ErrNotHandled is returned by an endpoint that chose not to handle a request, so the sender can fall back to its default handling
+var ErrNotHandled = errors.New("not handled")
+
+// SendResult is what an endpoint reports after handling a request
+type SendResult struct {
+	Endpoint  string
+	ChannelID string
+	MessageID string
+	// IsQueued is true when the request was queued to send later, so MessageID is not known yet
+	IsQueued bool
+}
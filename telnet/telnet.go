@@ -32,10 +32,41 @@ type Telnet struct {
 	subscribers    []func(interface{}) error
 	isNewTelnet    bool
 	isInitialState bool
+	// dumpMu guards the who list being parsed, isPlayerDump, lastPlayerDump, characters and detectedWhoFormat,
+	// since lines are processed by the read loop and by InjectLine
+	dumpMu         sync.Mutex
 	isPlayerDump   bool
 	lastPlayerDump time.Time
 	characters     map[string]*characterdb.Character
 	itemLinkCustom *regexp.Regexp
+	tellRegex      *regexp.Regexp
+	deathRegex     *regexp.Regexp
+	spawnRegex     *regexp.Regexp
+	lootRegex      *regexp.Regexp
+	commandRegex   *regexp.Regexp
+	lfgRegex       *regexp.Regexp
+	registerRegex  *regexp.Regexp
+	commands       map[string]func(name string, args string) string
+	// commandReplies are replies recently sent in game, so they are not processed as commands again
+	commandReplies map[string]time.Time
+	sentMu         sync.Mutex
+	// writeMu serializes writes to conn, and guards replacing conn, so lines sent at once are not interleaved
+	writeMu sync.Mutex
+	// sentLines are recently sent commands, so their echo is not relayed
+	sentLines []sentLine
+	// lastSpawnAlert is when each watched mob was last alerted on, only used by the read loop
+	lastSpawnAlert map[string]time.Time
+	// detectedWhoFormat is the who format last seen, used to log changes
+	detectedWhoFormat string
+	whoMu             sync.Mutex
+	// whoDone is closed and replaced each time a who list finishes parsing
+	whoDone chan struct{}
+	// whoPending is set while a who list is requested or being parsed
+	whoPending bool
+	// commandCooldowns are when each player may use each command again, keyed by command and lowercase name
+	commandCooldowns map[string]time.Time
+	// processMu serializes processLine, so lines injected for debugging do not race the read loop
+	processMu sync.Mutex
 }
 
 // New creates a new telnet connect
@@ -47,6 +78,14 @@ func New(ctx context.Context, config config.Telnet) (*Telnet, error) {
 		cancel:         cancel,
 		isInitialState: true,
 		isNewTelnet:    true,
+		lastSpawnAlert: make(map[string]time.Time),
+		commandReplies: make(map[string]time.Time),
+		whoDone:        make(chan struct{}),
+	}
+	t.commandCooldowns = make(map[string]time.Time)
+	t.commands = map[string]func(name string, args string) string{
+		"who":    t.whoCommand,
+		"uptime": t.uptimeCommand,
 	}
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -72,6 +111,62 @@ func New(ctx context.Context, config config.Telnet) (*Telnet, error) {
 
 	}
 
+	if config.TellDM.IsEnabled {
+		var err error
+		t.tellRegex, err = regexp.Compile(config.TellDM.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("tell_dm: %w", err)
+		}
+	}
+
+	if config.Deaths.IsEnabled {
+		var err error
+		t.deathRegex, err = regexp.Compile(config.Deaths.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("deaths: %w", err)
+		}
+	}
+
+	if config.SpawnAlert.IsEnabled {
+		var err error
+		t.spawnRegex, err = regexp.Compile(config.SpawnAlert.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("spawn_alert: %w", err)
+		}
+	}
+
+	if config.Loot.IsEnabled {
+		var err error
+		t.lootRegex, err = regexp.Compile(config.Loot.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("loot: %w", err)
+		}
+	}
+
+	if config.LFG.IsEnabled {
+		var err error
+		t.lfgRegex, err = regexp.Compile(config.LFG.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("lfg: %w", err)
+		}
+	}
+
+	if config.RegisterCode.IsEnabled {
+		var err error
+		t.registerRegex, err = regexp.Compile(config.RegisterCode.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("register code: %w", err)
+		}
+	}
+
+	if config.Commands.IsEnabled {
+		var err error
+		t.commandRegex, err = regexp.Compile(config.Commands.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("commands: %w", err)
+		}
+	}
+
 	return t, nil
 }
 
@@ -99,14 +194,16 @@ func (t *Telnet) Connect(ctx context.Context) error {
 	t.isInitialState = false
 	if t.conn != nil {
 		t.conn.Close()
-		t.conn = nil
+		t.setConn(nil)
 	}
+	t.resetPlayerDump()
 	t.ctx, t.cancel = context.WithCancel(ctx)
 
-	t.conn, err = telnet.Dial("tcp", t.config.Host)
+	conn, err := telnet.Dial("tcp", t.config.Host)
 	if err != nil {
 		return fmt.Errorf("dial: %w", err)
 	}
+	t.setConn(conn)
 	err = t.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
 	if err != nil {
 		return fmt.Errorf("set read deadline: %w", err)
@@ -145,6 +242,11 @@ func (t *Telnet) Connect(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("send password: %w", err)
 		}
+
+		err = t.checkLogin()
+		if err != nil {
+			return err
+		}
 	}
 
 	err = t.sendLn("echo off")
@@ -159,7 +261,10 @@ func (t *Telnet) Connect(ctx context.Context) error {
 
 	t.conn.SetReadDeadline(time.Time{})
 	t.conn.SetWriteDeadline(time.Time{})
-	go t.loop(ctx)
+	go t.loop(t.ctx)
+	if t.config.Heartbeat.IsEnabled {
+		go t.heartbeatLoop(t.ctx)
+	}
 	t.isConnected = true
 
 	if !isInitialState && t.config.IsServerAnnounceEnabled && len(t.subscribers) > 0 {
@@ -203,30 +308,73 @@ func (t *Telnet) Connect(ctx context.Context) error {
 	return nil
 }
 
+// loginReplyWait is how long to watch for a login failure after sending the password
+const loginReplyWait = 2 * time.Second
+
+// checkLogin reads the reply to a sent password, and returns an error if the server rejected the login
+func (t *Telnet) checkLogin() error {
+	err := t.conn.SetReadDeadline(time.Now().Add(loginReplyWait))
+	if err != nil {
+		return fmt.Errorf("set read deadline: %w", err)
+	}
+	defer t.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		data, err := t.conn.ReadUntil("\n", ":")
+		if err != nil {
+			// no rejection before the deadline means the login was accepted
+			return nil
+		}
+		if isLoginFailure(string(data)) {
+			return fmt.Errorf("login failed for username %s, check the telnet username and password", t.config.Username)
+		}
+	}
+}
+
+// isLoginFailure returns true if a telnet line rejects a login
+func isLoginFailure(line string) bool {
+	line = strings.ToLower(line)
+	for _, marker := range []string{"login failed", "access denied", "not authorized", "username:"} {
+		if strings.Contains(line, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *Telnet) loop(ctx context.Context) {
 	var data []byte
 	var err error
 	var msg string
+	var isKept bool
 
+	conn := t.conn
 	for {
 		select {
-		case <-t.ctx.Done():
+		case <-ctx.Done():
 			tlog.Debugf("[telnet] exiting telnet loop")
 			return
 		default:
 		}
 
-		data, err = t.conn.ReadUntil("\n")
+		data, err = conn.ReadUntil("\n")
 		if err != nil {
 			if strings.Contains(err.Error(), "unknown command:") {
 				tlog.Debugf("[telnet] received unknown command, ignoring: %s", data)
 				continue
 			}
-			tlog.Warnf("[telnet] read failed: %s", err)
-			t.Disconnect(context.Background())
+			if ctx.Err() != nil {
+				// the connection was closed by Disconnect
+				tlog.Debugf("[telnet] exiting telnet loop")
+				return
+			}
+			t.lostConnection(ctx, fmt.Errorf("read: %w", err))
 			return
 		}
-		msg = string(data)
+		msg, isKept = t.filterLine(string(data))
+		if !isKept {
+			tlog.Debugf("[telnet] dropped echo of sent command: %s", strings.TrimSpace(msg))
+			continue
+		}
 
 		if len(msg) < 3 { //ignore small messages
 			continue
@@ -234,18 +382,43 @@ func (t *Telnet) loop(ctx context.Context) {
 
 		tlog.Debugf("[telnet] raw echo: %s", strings.ReplaceAll(strings.ReplaceAll(msg, "\r", ""), "\n", ""))
 
-		if t.parsePlayerEntries(msg) {
-			continue
-		}
-		if t.parsePlayersOnline(msg) {
-			continue
-		}
+		t.processLine(msg)
+	}
+}
 
-		if t.parseMessage(msg) {
-			continue
-		}
+// processLine runs a line read from the server through every parser, and returns true if it reached the routes.
+// Lines like who entries, tells and deaths are consumed by their parser and are not routed
+func (t *Telnet) processLine(msg string) bool {
+	t.processMu.Lock()
+	defer t.processMu.Unlock()
+	if t.parsePlayerEntries(msg) {
+		return false
+	}
+	if t.parsePlayersOnline(msg) {
+		return false
+	}
+
+	if t.parseTell(msg) {
+		return false
+	}
+
+	if t.parseDeath(msg) {
+		return false
+	}
+
+	if t.parseSpawn(msg) {
+		return false
+	}
 
+	if t.parseLoot(msg) {
+		return false
 	}
+
+	t.parseCommand(msg)
+	t.parseLFG(msg)
+	t.parseRegisterCode(msg)
+
+	return t.parseMessage(msg)
 }
 
 // Disconnect stops a previously started connection with Telnet.
@@ -259,14 +432,16 @@ func (t *Telnet) Disconnect(ctx context.Context) error {
 		tlog.Debugf("[telnet] already disconnected, skipping disconnect")
 		return nil
 	}
+	// cancel before closing, so the read loop knows the close was intended
+	t.cancel()
 	err := t.conn.Close()
 	if err != nil {
 		tlog.Warnf("[telnet] disconnect failed, ignoring: %s", err)
 	}
-	t.cancel()
-	t.conn = nil
+	t.setConn(nil)
 	t.isConnected = false
-	if !t.isInitialState && t.config.IsServerAnnounceEnabled && len(t.subscribers) > 0 {
+	// a cancelled ctx means talkeq is shutting down, not that the server went down
+	if !t.isInitialState && t.config.IsServerAnnounceEnabled && len(t.subscribers) > 0 && ctx.Err() == nil {
 		for routeIndex, route := range t.config.Routes {
 			buf := new(bytes.Buffer)
 			if err := route.MessagePatternTemplate().Execute(buf, struct {
@@ -301,21 +476,63 @@ func (t *Telnet) Disconnect(ctx context.Context) error {
 	return nil
 }
 
+// lostConnection disconnects after the connection to the server failed, and asks subscribers to reconnect right away.
+// ctx is the context of the connection that failed, so a failure seen after a reconnect does not close the new connection
+func (t *Telnet) lostConnection(ctx context.Context, reason error) {
+	if ctx.Err() != nil {
+		return
+	}
+	tlog.Warnf("[telnet] connection lost: %s", reason)
+	t.Disconnect(context.Background())
+	req := request.Reconnect{
+		Ctx:      context.Background(),
+		Endpoint: "telnet",
+	}
+	for i, s := range t.subscribers {
+		err := s(req)
+		if err != nil {
+			tlog.Warnf("[telnet subscriber %d] reconnect failed: %s", i, err)
+		}
+	}
+}
+
 // Send attempts to send a message through Telnet.
 func (t *Telnet) Send(req request.TelnetSend) error {
+	_, err := t.SendWithResult(req)
+	return err
+}
+
+// SendWithResult attempts to send a message through Telnet. Telnet has no message IDs, so a nil error is the confirmation
+func (t *Telnet) SendWithResult(req request.TelnetSend) (request.SendResult, error) {
+	result := request.SendResult{Endpoint: "telnet"}
 	if !t.config.IsEnabled {
-		return fmt.Errorf("telnet is not enabled")
+		return result, fmt.Errorf("telnet is not enabled")
 	}
 
 	if !t.isConnected {
-		return fmt.Errorf("telnet is not connected")
+		return result, fmt.Errorf("telnet is not connected")
 	}
 
 	err := t.sendLn(req.Message)
 	if err != nil {
-		return fmt.Errorf("send: %w", err)
+		return result, fmt.Errorf("send: %w", err)
 	}
-	return nil
+	return result, nil
+}
+
+// SetRouteEnabled enables or disables all routes relaying to provided channel, returning how many changed
+func (t *Telnet) SetRouteEnabled(channelID string, isEnabled bool) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	count := 0
+	for i := range t.config.Routes {
+		if !t.config.Routes[i].HasDestination(channelID) {
+			continue
+		}
+		t.config.Routes[i].IsEnabled = isEnabled
+		count++
+	}
+	return count
 }
 
 // Subscribe listens for new events on telnet
@@ -326,7 +543,16 @@ func (t *Telnet) Subscribe(ctx context.Context, onMessage func(interface{}) erro
 	return nil
 }
 
+// setConn replaces conn, waiting for any line being sent to finish
+func (t *Telnet) setConn(conn *telnet.Conn) {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	t.conn = conn
+}
+
 func (t *Telnet) sendLn(s string) (err error) {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
 	if t.conn == nil {
 		return fmt.Errorf("no connection created")
 	}
@@ -338,5 +564,6 @@ func (t *Telnet) sendLn(s string) (err error) {
 	if err != nil {
 		return fmt.Errorf("sendLn: %s: %w", s, err)
 	}
+	t.rememberSent(s)
 	return
 }
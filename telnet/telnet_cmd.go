@@ -0,0 +1,120 @@
+package telnet
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xackery/talkeq/characterdb"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// startTime is when talkeq started, used by the uptime command
+var startTime = time.Now()
+
+// parseCommand runs an in game command found in msg and replies to the player who sent it.
+// The message is still relayed by routes afterwards, so it returns nothing
+func (t *Telnet) parseCommand(msg string) {
+	if t.commandRegex == nil {
+		return
+	}
+	matches := t.commandRegex.FindStringSubmatch(strings.TrimSpace(strings.ReplaceAll(msg, "\r", "")))
+	if len(matches) == 0 {
+		return
+	}
+	commands := &t.config.Commands
+	if commands.NameIndex >= len(matches) || commands.MessageIndex >= len(matches) {
+		tlog.Warnf("[telnet] commands index greater than matches %d", len(matches))
+		return
+	}
+	name := matches[commands.NameIndex]
+	message := matches[commands.MessageIndex]
+
+	for reply, sent := range t.commandReplies {
+		if time.Since(sent) > time.Minute {
+			delete(t.commandReplies, reply)
+		}
+	}
+	if _, ok := t.commandReplies[message]; ok {
+		tlog.Debugf("[telnet] ignoring own command reply: %s", message)
+		return
+	}
+
+	reply, ok := t.runCommand(name, message)
+	if !ok {
+		return
+	}
+	t.commandReplies[reply] = time.Now()
+
+	buf := new(bytes.Buffer)
+	err := commands.ReplyPatternTemplate().Execute(buf, struct {
+		Name    string
+		Message string
+	}{
+		name,
+		reply,
+	})
+	if err != nil {
+		tlog.Warnf("[telnet] commands reply execute: %s", err)
+		return
+	}
+	err = t.sendLn(buf.String())
+	if err != nil {
+		tlog.Warnf("[telnet] commands reply to %s failed: %s", name, err)
+		return
+	}
+	tlog.Infof("[telnet] command from %s: %s, replied: %s", name, message, reply)
+}
+
+// runCommand returns the reply to a command sent by name, or false if message is not a known command
+func (t *Telnet) runCommand(name string, message string) (string, bool) {
+	if !strings.HasPrefix(message, t.config.Commands.Prefix) {
+		return "", false
+	}
+	message = strings.TrimPrefix(message, t.config.Commands.Prefix)
+	cmd, args, _ := strings.Cut(message, " ")
+	cmd = strings.ToLower(cmd)
+	cmdFunc, ok := t.commands[cmd]
+	if !ok {
+		return "", false
+	}
+	if t.isCommandCoolingDown(cmd, name) {
+		tlog.Debugf("[telnet] ignoring command %s from %s, cooldown active", cmd, name)
+		return "", false
+	}
+	reply := cmdFunc(name, strings.TrimSpace(args))
+	// a reply starting with the prefix could trigger another command
+	for t.config.Commands.Prefix != "" && strings.HasPrefix(reply, t.config.Commands.Prefix) {
+		reply = strings.TrimPrefix(reply, t.config.Commands.Prefix)
+	}
+	return reply, true
+}
+
+// isCommandCoolingDown returns true if name used cmd too recently, otherwise starts a new cooldown
+func (t *Telnet) isCommandCoolingDown(cmd string, name string) bool {
+	seconds := t.config.Commands.Cooldowns[cmd]
+	if seconds < 1 {
+		return false
+	}
+	now := time.Now()
+	for key, expiry := range t.commandCooldowns {
+		if now.After(expiry) {
+			delete(t.commandCooldowns, key)
+		}
+	}
+	key := cmd + ":" + strings.ToLower(name)
+	if _, ok := t.commandCooldowns[key]; ok {
+		return true
+	}
+	t.commandCooldowns[key] = now.Add(time.Duration(seconds) * time.Second)
+	return false
+}
+
+func (t *Telnet) whoCommand(name string, args string) string {
+	return fmt.Sprintf("There are %d players online", characterdb.CharactersOnlineCount())
+}
+
+func (t *Telnet) uptimeCommand(name string, args string) string {
+	return fmt.Sprintf("TalkEQ has been up for %s", time.Since(startTime).Round(time.Second))
+}
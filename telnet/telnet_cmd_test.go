@@ -0,0 +1,68 @@
+package telnet
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+)
+
+func TestTelnet_runCommand(t *testing.T) {
+	tr, err := New(context.Background(), config.Telnet{Commands: config.TelnetCommands{Prefix: "!"}})
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	tests := []struct {
+		message   string
+		wantOK    bool
+		wantReply string
+	}{
+		{"!who", true, "There are"},
+		{"!UPTIME please", true, "TalkEQ has been up for"},
+		{"!unknown", false, ""},
+		{"who", false, ""},
+	}
+	for _, tt := range tests {
+		reply, ok := tr.runCommand("Shin", tt.message)
+		if ok != tt.wantOK {
+			t.Fatalf("runCommand(%q) ok = %v, want %v", tt.message, ok, tt.wantOK)
+		}
+		if !strings.HasPrefix(reply, tt.wantReply) {
+			t.Fatalf("runCommand(%q) reply = %q, want prefix %q", tt.message, reply, tt.wantReply)
+		}
+	}
+}
+
+func TestTelnet_runCommand_prefixNotCharacterSet(t *testing.T) {
+	tr, err := New(context.Background(), config.Telnet{Commands: config.TelnetCommands{Prefix: "#T"}})
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	reply, ok := tr.runCommand("Shin", "#Tuptime")
+	if !ok {
+		t.Fatalf("runCommand wanted uptime to run")
+	}
+	if !strings.HasPrefix(reply, "TalkEQ has been up for") {
+		t.Fatalf("reply wanted only the whole prefix trimmed, got %q", reply)
+	}
+}
+
+func TestTelnet_runCommand_cooldown(t *testing.T) {
+	tr, err := New(context.Background(), config.Telnet{Commands: config.TelnetCommands{Prefix: "!", Cooldowns: map[string]int{"who": 10}}})
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	if _, ok := tr.runCommand("Shin", "!who"); !ok {
+		t.Fatalf("first !who wanted a reply")
+	}
+	if _, ok := tr.runCommand("shin", "!WHO"); ok {
+		t.Fatalf("second !who from the same player wanted ignored")
+	}
+	if _, ok := tr.runCommand("Xackery", "!who"); !ok {
+		t.Fatalf("!who from another player wanted a reply")
+	}
+	if _, ok := tr.runCommand("Shin", "!uptime"); !ok {
+		t.Fatalf("!uptime without a cooldown wanted a reply")
+	}
+}
@@ -0,0 +1,65 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// parseDeath posts a player death to the deaths channel, returning true if msg was a death
+func (t *Telnet) parseDeath(msg string) bool {
+	if t.deathRegex == nil {
+		return false
+	}
+	matches := t.deathRegex.FindStringSubmatch(strings.TrimSpace(strings.ReplaceAll(msg, "\r", "")))
+	if len(matches) == 0 || t.isChatLine(msg) {
+		return false
+	}
+	deaths := &t.config.Deaths
+	if deaths.NameIndex >= len(matches) || deaths.KillerIndex >= len(matches) || deaths.ZoneIndex >= len(matches) {
+		tlog.Warnf("[telnet] deaths index greater than matches %d", len(matches))
+		return false
+	}
+	killer := ""
+	if deaths.KillerIndex > 0 {
+		killer = matches[deaths.KillerIndex]
+	}
+	zone := ""
+	if deaths.ZoneIndex > 0 {
+		zone = matches[deaths.ZoneIndex]
+	}
+
+	buf := new(bytes.Buffer)
+	err := deaths.MessagePatternTemplate().Execute(buf, struct {
+		Name   string
+		Killer string
+		Zone   string
+	}{
+		matches[deaths.NameIndex],
+		killer,
+		zone,
+	})
+	if err != nil {
+		tlog.Warnf("[telnet] deaths execute: %s", err)
+		return true
+	}
+
+	req := request.DiscordSend{
+		Ctx:       context.Background(),
+		ChannelID: deaths.ChannelID,
+		Message:   buf.String(),
+		Embed:     &request.DiscordEmbed{Color: deaths.Color},
+	}
+	for i, s := range t.subscribers {
+		err = s(req)
+		if err != nil {
+			tlog.Warnf("[telnet->discord subscriber %d] deaths channelID %s message %s failed: %s", i, req.ChannelID, req.Message, err)
+			continue
+		}
+		tlog.Infof("[telnet->discord subscriber %d] deaths channelID %s message: %s", i, req.ChannelID, req.Message)
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package telnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+)
+
+func TestTelnet_parseDeath(t *testing.T) {
+	cfg := config.Telnet{
+		IsEnabled: true,
+		Deaths: config.TelnetDeaths{
+			IsEnabled:      true,
+			ChannelID:      "123",
+			Regex:          `^(\w+) (?:has been|was) slain by (.+) in (.+?)[.!]?$`,
+			NameIndex:      1,
+			KillerIndex:    2,
+			ZoneIndex:      3,
+			MessagePattern: "💀 {{.Name}} was slain by {{.Killer}} in {{.Zone}}",
+			Color:          0x8B0000,
+		},
+	}
+	tr, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	var got request.DiscordSend
+	tr.subscribers = append(tr.subscribers, func(rawReq interface{}) error {
+		got = rawReq.(request.DiscordSend)
+		return nil
+	})
+
+	if tr.parseDeath("Shin says ooc, 'hello'") {
+		t.Fatalf("ooc message wanted not a death")
+	}
+	if tr.parseDeath("Shin says ooc, 'Bob was slain by a dragon in fear'") {
+		t.Fatalf("ooc message quoting a death wanted not a death")
+	}
+	if !tr.parseDeath("Xackery was slain by a Sand Giant in Oasis!\r\n") {
+		t.Fatalf("death message wanted a death")
+	}
+	if got.Message != "💀 Xackery was slain by a Sand Giant in Oasis" {
+		t.Fatalf("wanted death message, got %q", got.Message)
+	}
+	if got.ChannelID != "123" || got.Embed == nil || got.Embed.Color != 0x8B0000 {
+		t.Fatalf("wanted embed to channel 123, got %+v", got)
+	}
+}
+
+func TestTelnet_parseDeath_chatRoute(t *testing.T) {
+	cfg := config.Telnet{
+		IsEnabled: true,
+		Routes: []config.Route{{
+			IsEnabled:      true,
+			Trigger:        config.Trigger{Regex: `(\w+) says ooc, '(.*)'`, NameIndex: 1, MessageIndex: 2},
+			Target:         "discord",
+			ChannelID:      "456",
+			MessagePattern: "{{.Name}}: {{.Message}}",
+		}},
+		Deaths: config.TelnetDeaths{
+			IsEnabled:      true,
+			ChannelID:      "123",
+			Regex:          `(\w+) was slain by (.+) in (.+?)'?$`,
+			NameIndex:      1,
+			MessagePattern: "{{.Name}} died",
+		},
+	}
+	for i := range cfg.Routes {
+		err := cfg.Routes[i].LoadMessagePattern()
+		if err != nil {
+			t.Fatalf("load route %d: %s", i, err)
+		}
+	}
+	tr, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	sent := []request.DiscordSend{}
+	tr.subscribers = append(tr.subscribers, func(rawReq interface{}) error {
+		sent = append(sent, rawReq.(request.DiscordSend))
+		return nil
+	})
+
+	if !tr.processLine("Shin says ooc, 'Bob was slain by a dragon in fear'") {
+		t.Fatalf("ooc message quoting a death wanted to reach the routes")
+	}
+	if len(sent) != 1 || sent[0].ChannelID != "456" {
+		t.Fatalf("wanted only the ooc route relayed, got %+v", sent)
+	}
+}
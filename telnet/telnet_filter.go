@@ -0,0 +1,67 @@
+package telnet
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// sentLineWindow is how long a sent command is remembered to drop its echo
+	sentLineWindow = 5 * time.Second
+	// sentLineMax is how many sent commands are remembered
+	sentLineMax = 20
+)
+
+var (
+	// promptPattern matches console prompts and terminal control codes left at the start of a line
+	promptPattern = regexp.MustCompile(`^(?:\x00|\x1b\[[0-9;]*[A-Za-z]|\s*>\s?)+`)
+)
+
+// sentLine is a command written to telnet
+type sentLine struct {
+	text string
+	sent time.Time
+}
+
+// rememberSent records a sent command, so its echo can be dropped
+func (t *Telnet) rememberSent(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	t.sentMu.Lock()
+	defer t.sentMu.Unlock()
+	t.sentLines = append(t.sentLines, sentLine{text: text, sent: time.Now()})
+	if len(t.sentLines) > sentLineMax {
+		t.sentLines = t.sentLines[len(t.sentLines)-sentLineMax:]
+	}
+}
+
+// isEchoOfSent returns true if line is a command talkeq recently sent, and forgets it
+func (t *Telnet) isEchoOfSent(line string) bool {
+	line = strings.TrimSpace(line)
+	t.sentMu.Lock()
+	defer t.sentMu.Unlock()
+	now := time.Now()
+	for i, sent := range t.sentLines {
+		if now.Sub(sent.sent) > sentLineWindow {
+			continue
+		}
+		if !strings.EqualFold(sent.text, line) {
+			continue
+		}
+		t.sentLines = append(t.sentLines[:i], t.sentLines[i+1:]...)
+		return true
+	}
+	return false
+}
+
+// filterLine strips prompt artifacts from a telnet line, and returns false if the line is an echo of a sent command
+func (t *Telnet) filterLine(msg string) (string, bool) {
+	msg = promptPattern.ReplaceAllString(msg, "")
+	if t.isEchoOfSent(msg) {
+		return msg, false
+	}
+	return msg, true
+}
@@ -0,0 +1,34 @@
+package telnet
+
+import (
+	"testing"
+)
+
+func TestTelnet_filterLine(t *testing.T) {
+	tr := &Telnet{}
+	tr.rememberSent("emote world 260 Shin says from discord, 'hello'")
+	tr.rememberSent("who")
+
+	// captured from an EQEmu console with echo left on
+	transcript := []struct {
+		line     string
+		want     string
+		wantKept bool
+	}{
+		{"> emote world 260 Shin says from discord, 'hello'\r\n", "emote world 260 Shin says from discord, 'hello'\r\n", false},
+		{"who\r\n", "who\r\n", false},
+		{"\x1b[0m> Xackery says ooc, 'hi all'\r\n", "Xackery says ooc, 'hi all'\r\n", true},
+		{"Players on server:\r\n", "Players on server:\r\n", true},
+		{"  [65 Enchanter] Shin (High Elf) Zone: poknowledge\r\n", "  [65 Enchanter] Shin (High Elf) Zone: poknowledge\r\n", true},
+		{"who\r\n", "who\r\n", true},
+	}
+	for i, tt := range transcript {
+		got, kept := tr.filterLine(tt.line)
+		if kept != tt.wantKept {
+			t.Fatalf("line %d %q kept = %v, want %v", i, tt.line, kept, tt.wantKept)
+		}
+		if got != tt.want {
+			t.Fatalf("line %d = %q, want %q", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package telnet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xackery/talkeq/tlog"
+)
+
+// heartbeatLoop sends the heartbeat command every interval, and reports a lost connection if it can not be written
+func (t *Telnet) heartbeatLoop(ctx context.Context) {
+	ticker := time.NewTicker(t.config.Heartbeat.IntervalDuration())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			tlog.Debugf("[telnet] heartbeat loop exit")
+			return
+		case <-ticker.C:
+		}
+		err := t.heartbeat()
+		if err != nil {
+			t.lostConnection(ctx, fmt.Errorf("heartbeat: %w", err))
+			return
+		}
+	}
+}
+
+// heartbeat writes the heartbeat command, failing if the write does not finish in time
+func (t *Telnet) heartbeat() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.isConnected || t.conn == nil {
+		return nil
+	}
+	err := t.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err != nil {
+		return fmt.Errorf("set write deadline: %w", err)
+	}
+	defer t.conn.SetWriteDeadline(time.Time{})
+	err = t.sendLn(t.config.Heartbeat.Command)
+	if err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+	tlog.Debugf("[telnet] heartbeat sent")
+	return nil
+}
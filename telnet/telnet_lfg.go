@@ -0,0 +1,52 @@
+package telnet
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/xackery/talkeq/lfg"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// parseLFG posts LFG and LFM messages to the group finder channel.
+// The message is still relayed by routes afterwards, so it returns nothing
+func (t *Telnet) parseLFG(msg string) {
+	if t.lfgRegex == nil {
+		return
+	}
+	matches := t.lfgRegex.FindStringSubmatch(strings.TrimSpace(strings.ReplaceAll(msg, "\r", "")))
+	if len(matches) == 0 {
+		return
+	}
+	config := &t.config.LFG
+	if config.NameIndex >= len(matches) || config.MessageIndex >= len(matches) {
+		tlog.Warnf("[telnet] lfg index greater than matches %d", len(matches))
+		return
+	}
+	listing, ok := lfg.Parse(matches[config.NameIndex], t.convertLinks(matches[config.MessageIndex]))
+	if !ok {
+		return
+	}
+
+	req := request.DiscordLFG{
+		Ctx:       context.Background(),
+		ChannelID: config.ChannelID,
+		Name:      listing.Name,
+		Kind:      listing.Kind,
+		Level:     listing.Level,
+		Class:     listing.Class,
+		Message:   listing.Message,
+		Color:     config.Color,
+		Expire:    time.Duration(config.ExpireMinutes) * time.Minute,
+	}
+	for i, s := range t.subscribers {
+		err := s(req)
+		if err != nil {
+			tlog.Warnf("[telnet->discord subscriber %d] lfg channelID %s from %s failed: %s", i, req.ChannelID, req.Name, err)
+			continue
+		}
+		tlog.Infof("[telnet->discord subscriber %d] lfg channelID %s %s from %s", i, req.ChannelID, req.Kind, req.Name)
+	}
+}
@@ -0,0 +1,83 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// parseLoot posts a loot broadcast to the loot channel, returning true if msg was loot
+func (t *Telnet) parseLoot(msg string) bool {
+	if t.lootRegex == nil {
+		return false
+	}
+	matches := t.lootRegex.FindStringSubmatch(strings.TrimSpace(strings.ReplaceAll(msg, "\r", "")))
+	if len(matches) == 0 {
+		return false
+	}
+	loot := &t.config.Loot
+	if loot.NameIndex >= len(matches) || loot.ItemIndex >= len(matches) || loot.MobIndex >= len(matches) || loot.RarityIndex >= len(matches) {
+		tlog.Warnf("[telnet] loot index greater than matches %d", len(matches))
+		return false
+	}
+	mob := ""
+	if loot.MobIndex > 0 {
+		mob = matches[loot.MobIndex]
+	}
+	rarity := ""
+	if loot.RarityIndex > 0 {
+		rarity = matches[loot.RarityIndex]
+	}
+	if !loot.IsRarityAllowed(rarity) {
+		tlog.Debugf("[telnet] loot of rarity %q below min_rarity %q skipped", rarity, loot.MinRarity)
+		return true
+	}
+
+	item := matches[loot.ItemIndex]
+	embed := &request.DiscordEmbed{Color: loot.Color}
+	itemID, itemName, ok := t.findItemLink(item)
+	if ok {
+		item = itemName
+		embed.Title = itemName
+		if t.config.ItemURL != "" {
+			embed.URL = fmt.Sprintf("%s%d", t.config.ItemURL, itemID)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	err := loot.MessagePatternTemplate().Execute(buf, struct {
+		Name   string
+		Item   string
+		Mob    string
+		Rarity string
+	}{
+		matches[loot.NameIndex],
+		item,
+		mob,
+		rarity,
+	})
+	if err != nil {
+		tlog.Warnf("[telnet] loot execute: %s", err)
+		return true
+	}
+
+	req := request.DiscordSend{
+		Ctx:       context.Background(),
+		ChannelID: loot.ChannelID,
+		Message:   buf.String(),
+		Embed:     embed,
+	}
+	for i, s := range t.subscribers {
+		err = s(req)
+		if err != nil {
+			tlog.Warnf("[telnet->discord subscriber %d] loot channelID %s message %s failed: %s", i, req.ChannelID, req.Message, err)
+			continue
+		}
+		tlog.Infof("[telnet->discord subscriber %d] loot channelID %s message: %s", i, req.ChannelID, req.Message)
+	}
+	return true
+}
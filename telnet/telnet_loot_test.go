@@ -0,0 +1,56 @@
+package telnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+)
+
+func TestTelnet_parseLoot(t *testing.T) {
+	cfg := config.Telnet{
+		IsEnabled: true,
+		ItemURL:   "http://test.com?itemid=",
+		Loot: config.TelnetLoot{
+			IsEnabled:      true,
+			ChannelID:      "123",
+			Regex:          `(\w+) has looted (.+) from (.+) \((\w+)\)$`,
+			NameIndex:      1,
+			ItemIndex:      2,
+			MobIndex:       3,
+			RarityIndex:    4,
+			Rarities:       []string{"common", "rare", "legendary"},
+			MinRarity:      "rare",
+			MessagePattern: "{{.Name}} looted {{.Item}} from {{.Mob}}",
+		},
+	}
+	tr, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	sent := []request.DiscordSend{}
+	tr.subscribers = append(tr.subscribers, func(rawReq interface{}) error {
+		sent = append(sent, rawReq.(request.DiscordSend))
+		return nil
+	})
+
+	if !tr.parseLoot("Shin has looted a Rusty Dagger from a rat (common)") {
+		t.Fatalf("common loot wanted true")
+	}
+	if len(sent) != 0 {
+		t.Fatalf("common loot wanted skipped by min_rarity, got %+v", sent)
+	}
+	if !tr.parseLoot("Xackery has looted \x1207A50C000000000000000000000000000000000000000000CC2F1766Infused 2 Handed Damage\x12 from Lord Nagafen (legendary)") {
+		t.Fatalf("legendary loot wanted true")
+	}
+	if len(sent) != 1 {
+		t.Fatalf("wanted 1 loot sent, got %d", len(sent))
+	}
+	if sent[0].Message != "Xackery looted Infused 2 Handed Damage from Lord Nagafen" {
+		t.Fatalf("unexpected message %q", sent[0].Message)
+	}
+	if sent[0].Embed == nil || sent[0].Embed.URL != "http://test.com?itemid=501004" {
+		t.Fatalf("wanted embed linking item, got %+v", sent[0].Embed)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/xackery/talkeq/auction"
+	"github.com/xackery/talkeq/config"
 	"github.com/xackery/talkeq/guilddb"
 	"github.com/xackery/talkeq/request"
 	"github.com/xackery/talkeq/tlog"
@@ -22,8 +24,8 @@ var (
 	itemLink71 = regexp.MustCompile(`\x12([0-9A-Z]{9})[0-9A-Z]{68}([\+()0-9A-Za-z-'` + "`" + `:.,!?* ]+)\x12`)
 )
 
-func (t *Telnet) convertLinks(message string) string {
-
+// itemLinkMatches returns submatch indexes of item links found in message, trying each known link size
+func (t *Telnet) itemLinkMatches(message string) [][]int {
 	matches := itemLink71.FindAllStringSubmatchIndex(message, -1)
 	if len(matches) == 0 {
 		matches = itemLink50.FindAllStringSubmatchIndex(message, -1)
@@ -34,6 +36,27 @@ func (t *Telnet) convertLinks(message string) string {
 	if t.itemLinkCustom != nil && len(matches) == 0 {
 		matches = t.itemLinkCustom.FindAllStringSubmatchIndex(message, -1)
 	}
+	return matches
+}
+
+// findItemLink returns the id and name of the first item link in message
+func (t *Telnet) findItemLink(message string) (int64, string, bool) {
+	for _, submatches := range t.itemLinkMatches(message) {
+		if len(submatches) < 6 {
+			continue
+		}
+		itemID, err := strconv.ParseInt(message[submatches[2]:submatches[3]], 16, 64)
+		if err != nil {
+			continue
+		}
+		return itemID, message[submatches[4]:submatches[5]], true
+	}
+	return 0, "", false
+}
+
+func (t *Telnet) convertLinks(message string) string {
+
+	matches := t.itemLinkMatches(message)
 
 	out := message
 	for _, submatches := range matches {
@@ -77,16 +100,42 @@ func (t *Telnet) convertLinks(message string) string {
 	return out
 }
 
+// isChatLine returns true if msg matches an enabled route, so parsers of server announcements
+// like deaths do not consume chat that only quotes one
+func (t *Telnet) isChatLine(msg string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, route := range t.config.Routes {
+		if !route.IsEnabled || route.Trigger.Custom != "" {
+			continue
+		}
+		pattern, err := route.TriggerRegex()
+		if err != nil {
+			continue
+		}
+		if pattern.MatchString(msg) {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *Telnet) parseMessage(msg string) bool {
 	msg = t.convertLinks(msg)
 	msg = strings.ReplaceAll(msg, "&PCT;", `%`)
 
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	isMatched := false
+	isAuctionSaved := false
 	for routeIndex, route := range t.config.Routes {
+		if !route.IsEnabled {
+			continue
+		}
 		if route.Trigger.Custom != "" {
 			continue
 		}
-		pattern, err := regexp.Compile(route.Trigger.Regex)
-
+		pattern, err := route.TriggerRegex()
 		if err != nil {
 			tlog.Debugf("[telnet] compile route %d failed: %s", routeIndex, err)
 			continue
@@ -95,6 +144,8 @@ func (t *Telnet) parseMessage(msg string) bool {
 		if len(matches) == 0 {
 			continue
 		}
+		isMatched = true
+		route.Stats().Matched()
 
 		name := ""
 		message := ""
@@ -108,6 +159,10 @@ func (t *Telnet) parseMessage(msg string) bool {
 			continue
 		}
 		name = matches[0][route.Trigger.NameIndex]
+		if !route.IsMatch(message) {
+			continue
+		}
+		threadName := ""
 		if route.Trigger.GuildIndex > 0 && route.Trigger.GuildIndex <= len(matches[0]) {
 			route.GuildID = matches[0][route.Trigger.GuildIndex]
 			iGuildID, err := strconv.Atoi(route.GuildID)
@@ -124,10 +179,13 @@ func (t *Telnet) parseMessage(msg string) bool {
 			} else {
 				route.ChannelID = tmpChannelID
 			}
+			threadName = guildThreadName(route, iGuildID)
 		}
 
+		name = route.DisplayName(name)
+		fromName := name
 		buf := new(bytes.Buffer)
-		if t.config.ProfileURL != "" {
+		if t.config.ProfileURL != "" && route.Anonymize == "" {
 			name = fmt.Sprintf("[%s](<%s%s>)", name, t.config.ProfileURL, name)
 		}
 		if err := route.MessagePatternTemplate().Execute(buf, struct {
@@ -142,23 +200,140 @@ func (t *Telnet) parseMessage(msg string) bool {
 		}
 		switch route.Target {
 		case "discord":
-			req := request.DiscordSend{
-				Ctx:       context.Background(),
-				ChannelID: route.ChannelID,
-				Message:   buf.String(),
+			// a line relayed to several channels or by several routes is still one listing
+			if route.IsAuctionEmbed && !isAuctionSaved {
+				isAuctionSaved = true
+				auction.SaveMessage(fromName, message)
 			}
-			for i, s := range t.subscribers {
-				err = s(req)
-				if err != nil {
-					tlog.Warnf("[telnet->discord subscriber %d] channelID %s message %s failed: %s", i, route.ChannelID, req.Message, err)
-					continue
+			for _, channelID := range route.Destinations() {
+				// typing goes first, discord clears the indicator once the message arrives
+				if route.IsTypingEnabled {
+					typingReq := request.DiscordTyping{
+						Ctx:       context.Background(),
+						ChannelID: channelID,
+					}
+					for i, s := range t.subscribers {
+						err = s(typingReq)
+						if err != nil {
+							tlog.Debugf("[telnet->discord subscriber %d] typing channelID %s failed: %s", i, channelID, err)
+						}
+					}
+				}
+				req := request.DiscordSend{
+					Ctx:        context.Background(),
+					ChannelID:  channelID,
+					Message:    buf.String(),
+					FromName:   fromName,
+					Text:       message,
+					IsAuction:  route.IsAuctionEmbed,
+					UseEmbed:   route.UseEmbed,
+					ThreadName: threadName,
+				}
+				for i, s := range t.subscribers {
+					err = s(req)
+					if err != nil {
+						tlog.Warnf("[telnet->discord subscriber %d] channelID %s message %s failed: %s", i, channelID, req.Message, err)
+						continue
+					}
+					tlog.Infof("[telnet->discord subscribe %d] channelID %s message: %s", i, channelID, req.Message)
 				}
-				tlog.Infof("[telnet->discord subscribe %d] channelID %s message: %s", i, route.ChannelID, req.Message)
 			}
 		default:
 			tlog.Warnf("[telnet] unsupported target type: %s", route.Target)
 			continue
 		}
 	}
+	if !isMatched {
+		t.sendUnmatched(msg)
+	}
 	return true
 }
+
+// guildThreadName returns the thread a guild's chat is posted to, or an empty string if the route does not use threads
+func guildThreadName(route config.Route, guildID int) string {
+	tmpl := route.GuildThreadNameTemplate()
+	if tmpl == nil {
+		return ""
+	}
+	guildName := guilddb.Name(guildID)
+	if guildName == "" {
+		guildName = fmt.Sprintf("Guild %d", guildID)
+	}
+	buf := new(bytes.Buffer)
+	err := tmpl.Execute(buf, struct {
+		GuildID   int
+		GuildName string
+	}{
+		guildID,
+		guildName,
+	})
+	if err != nil {
+		tlog.Warnf("[telnet] guild thread name execute: %s", err)
+		return ""
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// sendUnmatched relays a line that matched no enabled route, if enabled. Must be called while holding t.mu
+func (t *Telnet) sendUnmatched(msg string) {
+	if !t.config.Unmatched.IsEnabled {
+		return
+	}
+	msg = strings.TrimSpace(strings.ReplaceAll(msg, "\r", ""))
+	if msg == "" {
+		return
+	}
+	tlog.Debugf("[telnet] unmatched: %s", msg)
+	if t.config.Unmatched.ChannelID == "" {
+		return
+	}
+	req := request.DiscordSend{
+		Ctx:       context.Background(),
+		ChannelID: t.config.Unmatched.ChannelID,
+		Message:   fmt.Sprintf("unmatched: %s", msg),
+	}
+	for i, s := range t.subscribers {
+		err := s(req)
+		if err != nil {
+			tlog.Debugf("[telnet->discord subscriber %d] unmatched channelID %s failed: %s", i, req.ChannelID, err)
+		}
+	}
+}
+
+// matchingRoutes returns the indexes of enabled routes whose trigger and conditions match msg
+func (t *Telnet) matchingRoutes(msg string) []int {
+	msg = t.convertLinks(msg)
+	msg = strings.ReplaceAll(msg, "&PCT;", `%`)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	indexes := []int{}
+	for routeIndex, route := range t.config.Routes {
+		if !route.IsEnabled || route.Trigger.Custom != "" {
+			continue
+		}
+		pattern, err := route.TriggerRegex()
+		if err != nil {
+			continue
+		}
+		matches := pattern.FindStringSubmatch(msg)
+		if len(matches) == 0 || route.Trigger.MessageIndex >= len(matches) {
+			continue
+		}
+		if !route.IsMatch(matches[route.Trigger.MessageIndex]) {
+			continue
+		}
+		indexes = append(indexes, routeIndex)
+	}
+	return indexes
+}
+
+// InjectLine processes line as if it was read from the server, for debugging routes.
+// It returns the indexes of the routes that matched, and false if the line was consumed before reaching routes
+func (t *Telnet) InjectLine(line string) ([]int, bool) {
+	tlog.Infof("[telnet] injected line: %s", line)
+	if !t.processLine(line) {
+		return []int{}, false
+	}
+	return t.matchingRoutes(line), true
+}
@@ -2,6 +2,7 @@ package telnet
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -153,3 +154,87 @@ func TestTelnet_parseMessage(t *testing.T) {
 		})
 	}
 }
+
+func Test_guildThreadName(t *testing.T) {
+	route := config.Route{IsEnabled: true, MessagePattern: "{{.Message}}", Trigger: config.Trigger{Regex: `(\w+)`}}
+	err := route.LoadMessagePattern()
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if got := guildThreadName(route, 5); got != "" {
+		t.Fatalf("guildThreadName() without guild_thread_name = %q, want empty", got)
+	}
+
+	route.GuildThreadName = "{{.GuildName}} chat"
+	err = route.LoadMessagePattern()
+	if err != nil {
+		t.Fatalf("load: %s", err)
+	}
+	if got := guildThreadName(route, 5); got != "Guild 5 chat" {
+		t.Fatalf("guildThreadName() = %q, want Guild 5 chat", got)
+	}
+}
+
+func TestTelnet_InjectLine(t *testing.T) {
+	cfg := config.Telnet{
+		IsEnabled: true,
+		Routes: []config.Route{
+			{IsEnabled: true, Trigger: config.Trigger{Regex: `(\w+) says ooc, '(.*)'`, NameIndex: 1, MessageIndex: 2}, Target: "discord", ChannelID: "123", MessagePattern: "{{.Name}}: {{.Message}}"},
+			{IsEnabled: true, Trigger: config.Trigger{Regex: `(\w+) auctions, '(.*)'`, NameIndex: 1, MessageIndex: 2}, Target: "discord", ChannelID: "456", MessagePattern: "{{.Name}}: {{.Message}}"},
+		},
+	}
+	for i := range cfg.Routes {
+		err := cfg.Routes[i].LoadMessagePattern()
+		if err != nil {
+			t.Fatalf("load route %d: %s", i, err)
+		}
+	}
+	tr, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	sent := 0
+	tr.subscribers = append(tr.subscribers, func(rawReq interface{}) error {
+		sent++
+		return nil
+	})
+
+	routes, isRouted := tr.InjectLine("Shin auctions, 'WTS Cloak 500p'")
+	if !isRouted || len(routes) != 1 || routes[0] != 1 {
+		t.Fatalf("InjectLine() = %v, %t, wanted [1], true", routes, isRouted)
+	}
+	if sent != 1 {
+		t.Fatalf("sent wanted 1, got %d", sent)
+	}
+}
+
+// TestTelnet_InjectLine_concurrent injects lines while the read loop processes others, like /api/debug does. Run with -race
+func TestTelnet_InjectLine_concurrent(t *testing.T) {
+	cfg := config.Telnet{
+		IsEnabled: true,
+		Commands: config.TelnetCommands{
+			IsEnabled:    true,
+			Prefix:       "!",
+			Regex:        `(\w+) says ooc, '(.*)'`,
+			NameIndex:    1,
+			MessageIndex: 2,
+			ReplyPattern: "tell {{.Name}} {{.Message}}",
+			Cooldowns:    map[string]int{"uptime": 10},
+		},
+	}
+	tr, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			tr.InjectLine(fmt.Sprintf("Injected%d says ooc, '!uptime'", i))
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		tr.processLine(fmt.Sprintf("Player%d says ooc, '!uptime'", i))
+	}
+	<-done
+}
@@ -0,0 +1,83 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/xackery/talkeq/characterdb"
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// sendPlayerNotifications announces changes found between who lists
+func (t *Telnet) sendPlayerNotifications(changes []characterdb.PlayerChange) {
+	for _, change := range changes {
+		if change.IsZoneChange {
+			t.sendPlayerNotification("zone_change", &t.config.ZoneChange, change)
+		}
+		if change.IsLevelUp {
+			t.sendPlayerNotification("level_up", &t.config.LevelUp, change)
+		}
+		if change.IsLogin && !change.LastSeen.IsZero() && time.Since(change.LastSeen) >= time.Duration(t.config.ReturningPlayerDays)*24*time.Hour {
+			t.sendPlayerNotification("returning_player", &t.config.ReturningPlayer, change)
+		}
+	}
+}
+
+// sendPlayerNotification announces a single change, if the notification is enabled and the player's zone is allowed
+func (t *Telnet) sendPlayerNotification(name string, notify *config.PlayerNotification, change characterdb.PlayerChange) {
+	if !notify.IsEnabled {
+		return
+	}
+	character := change.Character
+	if !notify.IsZoneAllowed(character.Zone) {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	err := notify.MessagePatternTemplate().Execute(buf, struct {
+		Name     string
+		Zone     string
+		OldZone  string
+		Level    int
+		OldLevel int
+		Class    string
+		DaysAway int
+	}{
+		character.Name,
+		character.Zone,
+		change.OldZone,
+		character.Level,
+		change.OldLevel,
+		character.Class,
+		daysAway(change.LastSeen),
+	})
+	if err != nil {
+		tlog.Warnf("[telnet] %s execute: %s", name, err)
+		return
+	}
+
+	req := request.DiscordSend{
+		Ctx:       context.Background(),
+		ChannelID: notify.ChannelID,
+		Message:   buf.String(),
+	}
+	for i, s := range t.subscribers {
+		err = s(req)
+		if err != nil {
+			tlog.Warnf("[telnet->discord subscriber %d] %s channelID %s message %s failed: %s", i, name, req.ChannelID, req.Message, err)
+			continue
+		}
+		tlog.Infof("[telnet->discord subscriber %d] %s channelID %s message: %s", i, name, req.ChannelID, req.Message)
+	}
+}
+
+// daysAway returns whole days since lastSeen, or 0 if never seen
+func daysAway(lastSeen time.Time) int {
+	if lastSeen.IsZero() {
+		return 0
+	}
+	return int(time.Since(lastSeen).Hours() / 24)
+}
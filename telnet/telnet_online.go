@@ -13,96 +13,163 @@ import (
 )
 
 var (
-	playersOnlineRegex = regexp.MustCompile("([0-9]+) players online")
-	playerEntryRegex   = regexp.MustCompile(`(.*) \[([a-zA-Z]+)? ?([0-9]+) (.*)\] (.*) \((.*)\) .* zone\: (.*) AccID: (.*) AccName: (.*) LSID: (.*) Status: (.*)`)
+	// e.g. There are 12 players online. or There are no players online.
+	playersOnlineRegex = regexp.MustCompile(`^\s*(?:There (?:are|is) )?([0-9]+|no) players? online\.?\s*$`)
+	// whoFormats are the supported formats of a who entry line, by config who_format name
+	whoFormats = []struct {
+		name  string
+		regex *regexp.Regexp
+	}{
+		// e.g. * GM-Impossible * [ANON 60 Grave Lord] Xackery (Dark Elf) <XackGuild> zone: arena AccID: 2 AccName: xackery LSID: 103621 Status: 300
+		{"legacy", regexp.MustCompile(`(?P<identity>.*) \[(?P<state>[a-zA-Z]+)? ?(?P<level>[0-9]+) (?P<class>.*)\] (?P<name>.*) \((?P<race>.*)\) .* zone\: (?P<zone>.*) AccID: (?P<acctid>.*) AccName: (?P<acctname>.*) LSID: (?P<lsid>.*) Status: (?P<status>.*)`)},
+		// e.g. [60 Grave Lord] Xackery (Dark Elf) <XackGuild> Zone: The Arena (arena) AccID: 2 AccName: xackery LSID: 103621 Status: 300
+		// identity and guild are optional, and zone may include the long name with the short name in parenthesis
+		{"v2", regexp.MustCompile(`^\s*(?:\*\s*(?P<identity>[^*]*?)\s*\*\s*)?\[(?:(?P<state>[a-zA-Z]+) )?(?P<level>[0-9]+) (?P<class>[^\]]+)\] (?P<name>\S+) \((?P<race>[^)]+)\)(?: <[^>]*>)? [zZ]one: (?P<zone>.*?)(?: \((?P<zoneshort>[a-z0-9_]+)\))?(?: LFG)? AccID: (?P<acctid>[0-9]+) AccName: (?P<acctname>\S+) LSID: (?P<lsid>[0-9]+) Status: (?P<status>[0-9]+)`)},
+	}
 )
 
+// matchPlayerEntry parses a who entry line with the configured who_format.
+// With auto, each format is tried in order until one matches
+func (t *Telnet) matchPlayerEntry(msg string) (map[string]string, bool) {
+	for i, format := range whoFormats {
+		if t.config.WhoFormat != "" && t.config.WhoFormat != "auto" && t.config.WhoFormat != format.name {
+			continue
+		}
+		matches := format.regex.FindStringSubmatch(msg)
+		if len(matches) == 0 {
+			continue
+		}
+		if t.detectedWhoFormat != format.name {
+			tlog.Infof("[telnet] detected who format %s", format.name)
+			t.detectedWhoFormat = format.name
+		}
+		entry := map[string]string{}
+		for j, name := range whoFormats[i].regex.SubexpNames() {
+			if name == "" {
+				continue
+			}
+			entry[name] = strings.TrimSpace(matches[j])
+		}
+		return entry, true
+	}
+	return nil, false
+}
+
+// parsePlayerEntries collects who entry lines into a who list, and sets the character db once the list ends.
+// A list ends at its players online line, or if that is missed, at the first line after who_dump_window passes with no new entry.
+// Returns true if msg was part of the who list
 func (t *Telnet) parsePlayerEntries(msg string) bool {
+	characters, isTimedOut, isConsumed := t.parsePlayerDump(msg)
+	if characters == nil {
+		return isConsumed
+	}
+	changes, err := characterdb.SetCharacters(characters)
+	if err != nil {
+		tlog.Warnf("[telnet] setcharacters failed: %s", err)
+		return true
+	}
+	t.sendPlayerNotifications(changes)
+	// a list ending with a players online line is finished by parsePlayersOnline
+	if isTimedOut {
+		t.whoFinished()
+	}
+	return isConsumed
+}
+
+// resetPlayerDump drops a partly parsed who list, e.g. when the connection was lost in the middle of one
+func (t *Telnet) resetPlayerDump() {
+	t.dumpMu.Lock()
+	defer t.dumpMu.Unlock()
+	t.isPlayerDump = false
+	t.characters = nil
+}
+
+// parsePlayerDump adds a who entry line to the who list being parsed.
+// When the list ends, it is returned, with isTimedOut set if it ended without a players online line
+func (t *Telnet) parsePlayerDump(msg string) (characters map[string]*characterdb.Character, isTimedOut bool, isConsumed bool) {
 	var err error
+	t.dumpMu.Lock()
+	defer t.dumpMu.Unlock()
 	if t.isPlayerDump && time.Now().After(t.lastPlayerDump) {
-		err = characterdb.SetCharacters(t.characters)
-		if err != nil {
-			tlog.Warnf("[telnet] setcharacters failed: %s", err)
-			return true
-		}
 		t.isPlayerDump = false
-		return false
+		return t.characters, true, false
 	}
 	if !t.isPlayerDump && strings.Contains(msg, "Players on server:") {
+		// a list may also be started by someone else asking for it, and its players online line is still expected
+		t.whoRequested()
 		t.isPlayerDump = true
-		t.lastPlayerDump = time.Now().Add(1 * time.Second)
+		t.lastPlayerDump = time.Now().Add(t.config.WhoDumpWindowDuration())
 		t.characters = make(map[string]*characterdb.Character)
-		return true
+		return nil, false, true
 	}
 	if !t.isPlayerDump {
-		return false
+		return nil, false, false
 	}
 
-	if t.isPlayerDump && strings.Contains(msg, "players online") {
-		err = characterdb.SetCharacters(t.characters)
-		if err != nil {
-			tlog.Warnf("[telnet] setcharacters playersOnline failed: %s", err)
-			return true
-		}
+	if t.isPlayerDump && playersOnlineRegex.MatchString(msg) {
 		t.isPlayerDump = false
-		return false
+		return t.characters, false, false
 	}
 
-	matches := playerEntryRegex.FindAllStringSubmatch(strings.ReplaceAll(msg, "\r", ""), -1)
-	if len(matches) == 0 {
-		return false
+	entry, ok := t.matchPlayerEntry(strings.ReplaceAll(msg, "\r", ""))
+	if !ok {
+		return nil, false, false
 	}
 
-	for _, submatches := range matches {
-		if len(submatches) < 6 {
-			continue
-		}
-
-		level, err := strconv.Atoi(submatches[3])
-		if err != nil {
-			tlog.Debugf("[telnet] failed to parse %s level (%s): %s", msg, submatches[3], err)
-			level = 0
-		}
+	level, err := strconv.Atoi(entry["level"])
+	if err != nil {
+		tlog.Debugf("[telnet] failed to parse %s level (%s): %s", msg, entry["level"], err)
+		level = 0
+	}
 
-		acctID, err := strconv.Atoi(submatches[8])
-		if err != nil {
-			tlog.Debugf("[telnet] failed to parse %s acctID (%s): %s", msg, submatches[8], err)
-			acctID = 0
-		}
+	acctID, err := strconv.Atoi(entry["acctid"])
+	if err != nil {
+		tlog.Debugf("[telnet] failed to parse %s acctID (%s): %s", msg, entry["acctid"], err)
+		acctID = 0
+	}
 
-		lsID, err := strconv.Atoi(submatches[10])
-		if err != nil {
-			tlog.Debugf("[telnet] failed to parse %s lsID (%s): %s", msg, submatches[10], err)
-			lsID = 0
-		}
+	lsID, err := strconv.Atoi(entry["lsid"])
+	if err != nil {
+		tlog.Debugf("[telnet] failed to parse %s lsID (%s): %s", msg, entry["lsid"], err)
+		lsID = 0
+	}
 
-		status, err := strconv.Atoi(submatches[11])
-		if err != nil {
-			tlog.Debugf("[telnet] failed to parse %s status (%s): %s", msg, submatches[11], err)
-			status = 0
-		}
-		t.characters[submatches[5]] = &characterdb.Character{
-			IsOnline: true,
-			Identity: submatches[1],
-			State:    submatches[2],
-			Level:    level,
-			Class:    submatches[4],
-			Name:     submatches[5],
-			Race:     submatches[6],
-			Zone:     submatches[7],
-			AcctID:   acctID,
-			AcctName: submatches[9],
-			LSID:     lsID,
-			Status:   status,
-		}
+	status, err := strconv.Atoi(entry["status"])
+	if err != nil {
+		tlog.Debugf("[telnet] failed to parse %s status (%s): %s", msg, entry["status"], err)
+		status = 0
+	}
+	zone := entry["zone"]
+	if entry["zoneshort"] != "" {
+		zone = entry["zoneshort"]
 	}
+	t.characters[entry["name"]] = &characterdb.Character{
+		IsOnline: true,
+		Identity: entry["identity"],
+		State:    entry["state"],
+		Level:    level,
+		Class:    entry["class"],
+		Name:     entry["name"],
+		Race:     entry["race"],
+		Zone:     zone,
+		AcctID:   acctID,
+		AcctName: entry["acctname"],
+		LSID:     lsID,
+		Status:   status,
+	}
+	// the window restarts with each entry, so a slowly sent list is not cut short
+	t.lastPlayerDump = time.Now().Add(t.config.WhoDumpWindowDuration())
 
-	return true
+	return nil, false, true
 }
 
+// parsePlayersOnline sets the online count from the players online line ending a who list.
+// The line is only trusted while a who list is pending, so chat that looks like it is left for the routes
 func (t *Telnet) parsePlayersOnline(msg string) bool {
-
-	matches := playersOnlineRegex.FindAllStringSubmatch(msg, -1)
+	if !t.isWhoPending() {
+		return false
+	}
+	matches := playersOnlineRegex.FindAllStringSubmatch(strings.ReplaceAll(msg, "\r", ""), -1)
 	if len(matches) == 0 { //pattern has no match, unsupported emote
 		return false
 	}
@@ -112,26 +179,78 @@ func (t *Telnet) parsePlayersOnline(msg string) bool {
 		return false
 	}
 
-	online, err := strconv.Atoi(matches[0][1])
-	if err != nil {
-		tlog.Debugf("[telnet] ignored '%s' parse, online count not valid", msg)
-		return false
+	online := 0
+	if matches[0][1] != "no" {
+		var err error
+		online, err = strconv.Atoi(matches[0][1])
+		if err != nil {
+			tlog.Debugf("[telnet] ignored '%s' parse, online count not valid", msg)
+			return false
+		}
 	}
 
+	if online == 0 {
+		// an empty server may not send a who list, so the last one would stay
+		characterdb.ClearCharacters()
+	}
 	characterdb.SetCharactersOnlineCount(online)
+	t.whoFinished()
 
 	return true
 }
 
-// Who returns number of online players
+// whoTimeout is how long Who waits for the who list before using the last known count
+const whoTimeout = 5 * time.Second
+
+// whoRequested marks a who list as pending, until whoFinished
+func (t *Telnet) whoRequested() {
+	t.whoMu.Lock()
+	defer t.whoMu.Unlock()
+	t.whoPending = true
+}
+
+// isWhoPending returns true if a who list was requested or started and has not finished
+func (t *Telnet) isWhoPending() bool {
+	t.whoMu.Lock()
+	defer t.whoMu.Unlock()
+	return t.whoPending
+}
+
+// whoFinished wakes anything waiting on the who list being parsed
+func (t *Telnet) whoFinished() {
+	t.whoMu.Lock()
+	defer t.whoMu.Unlock()
+	t.whoPending = false
+	if t.whoDone != nil {
+		close(t.whoDone)
+	}
+	t.whoDone = make(chan struct{})
+}
+
+// whoWaiter returns a channel closed when the next who list finishes parsing
+func (t *Telnet) whoWaiter() chan struct{} {
+	t.whoMu.Lock()
+	defer t.whoMu.Unlock()
+	return t.whoDone
+}
+
+// Who requests a who list and returns the number of online players once it is parsed.
+// If the list takes longer than whoTimeout, the last known count is returned with an error
 func (t *Telnet) Who(ctx context.Context) (int, error) {
+	done := t.whoWaiter()
+	t.whoRequested()
 	err := t.sendLn("who")
 	if err != nil {
 		return 0, fmt.Errorf("who request: %w", err)
 	}
-	time.Sleep(100 * time.Millisecond)
-	t.mu.RLock()
-	online := characterdb.CharactersOnlineCount()
-	t.mu.RUnlock()
-	return online, nil
+	timeout := time.NewTimer(whoTimeout)
+	defer timeout.Stop()
+	select {
+	case <-ctx.Done():
+		return characterdb.CharactersOnlineCount(), fmt.Errorf("who response: %w", ctx.Err())
+	case <-timeout.C:
+		return characterdb.CharactersOnlineCount(), fmt.Errorf("who response: timed out after %s, using last known count", whoTimeout)
+	case <-done:
+		return characterdb.CharactersOnlineCount(), nil
+	}
 }
@@ -2,6 +2,10 @@ package telnet
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
 	"testing"
 	"time"
 
@@ -62,6 +66,48 @@ func TestOnline(t *testing.T) {
 	}
 }
 
+func TestTelnet_matchPlayerEntry(t *testing.T) {
+	tests := []struct {
+		name      string
+		whoFormat string
+		msg       string
+		wantOK    bool
+		wantName  string
+		wantZone  string
+		wantLevel string
+	}{
+		{"legacy", "auto", "* GM-Impossible * [ANON 60 Grave Lord] Xackery (Dark Elf) <XackGuild> zone: arena AccID: 2 AccName: xackery LSID: 103621 Status: 300", true, "Xackery", "arena", "60"},
+		{"legacy roleplay lfg", "legacy", "* GM-Impossible * [RolePlay 60 Grave Lord] Xackery (Dark Elf) <XackGuild> zone: arena LFG AccID: 2 AccName: xackery LSID: 103621 Status: 300", true, "Xackery", "arena LFG", "60"},
+		{"v2", "auto", "  [65 Enchanter] Shin (High Elf) <Guild Of Shin> Zone: The Plane of Knowledge (poknowledge) AccID: 5 AccName: shin LSID: 1234 Status: 0", true, "Shin", "poknowledge", "65"},
+		{"v2 no guild", "v2", "  [1 Warrior] Newbie (Human) Zone: qeynos AccID: 7 AccName: newbie LSID: 99 Status: 0", true, "Newbie", "qeynos", "1"},
+		{"v2 identity", "v2", "  * GM-Impossible * [ANON 60 Grave Lord] Xackery (Dark Elf) zone: The Arena (arena) AccID: 2 AccName: xackery LSID: 103621 Status: 300", true, "Xackery", "arena", "60"},
+		{"legacy only", "legacy", "  [1 Warrior] Newbie (Human) Zone: qeynos AccID: 7 AccName: newbie LSID: 99 Status: 0", false, "", "", ""},
+		{"not an entry", "auto", "1 players online", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &Telnet{config: config.Telnet{WhoFormat: tt.whoFormat}}
+			entry, ok := tr.matchPlayerEntry(tt.msg)
+			if ok != tt.wantOK {
+				t.Fatalf("matchPlayerEntry() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if entry["name"] != tt.wantName || entry["level"] != tt.wantLevel {
+				t.Fatalf("matchPlayerEntry() name %q level %q, want %q %q", entry["name"], entry["level"], tt.wantName, tt.wantLevel)
+			}
+			zone := entry["zone"]
+			if entry["zoneshort"] != "" {
+				zone = entry["zoneshort"]
+			}
+			if zone != tt.wantZone {
+				t.Fatalf("matchPlayerEntry() zone %q, want %q", zone, tt.wantZone)
+			}
+		})
+	}
+}
+
 func TestTelnet_parsePlayerEntries(t *testing.T) {
 	type fields struct {
 		ctx            context.Context
@@ -163,3 +209,123 @@ func TestTelnet_Who(t *testing.T) {
 		})
 	}
 }
+
+func TestTelnet_whoWaiter(t *testing.T) {
+	tr := &Telnet{whoDone: make(chan struct{})}
+	done := tr.whoWaiter()
+	tr.whoRequested()
+	if !tr.parsePlayersOnline("There are 12 players online.") {
+		t.Fatalf("parsePlayersOnline wanted true")
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("who waiter wanted closed after players online")
+	}
+	if characterdb.CharactersOnlineCount() != 12 {
+		t.Fatalf("online wanted 12, got %d", characterdb.CharactersOnlineCount())
+	}
+}
+
+// TestTelnet_WhoDuringDumps parses who lists from two goroutines, like the read loop and InjectLine, while Who is called. Run with -race
+func TestTelnet_WhoDuringDumps(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go io.Copy(io.Discard, server)
+	conn, err := telnet.NewConn(client)
+	if err != nil {
+		t.Fatalf("newConn: %s", err)
+	}
+	tr := &Telnet{conn: conn, whoDone: make(chan struct{})}
+
+	dump := []string{
+		"Players on server:",
+		"[60 Grave Lord] Xackery (Dark Elf) <XackGuild> Zone: The Arena (arena) AccID: 2 AccName: xackery LSID: 103621 Status: 300",
+		"[1 Enchanter] Shin (Erudite) Zone: qeynos AccID: 3 AccName: shin LSID: 103622 Status: 0",
+		"There are 2 players online.",
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				for _, line := range dump {
+					tr.parsePlayerEntries(line)
+					tr.parsePlayersOnline(line)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				_, err := tr.Who(ctx)
+				cancel()
+				if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("who: %s", err)
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 20; j++ {
+			tr.resetPlayerDump()
+		}
+	}()
+	wg.Wait()
+}
+
+func TestTelnet_whoDumpWindow(t *testing.T) {
+	tr := &Telnet{config: config.Telnet{WhoDumpWindow: 5}, whoDone: make(chan struct{})}
+	tr.processLine("Players on server:")
+	tr.lastPlayerDump = time.Now().Add(time.Second)
+	tr.processLine("[1 Enchanter] Shin (Erudite) Zone: qeynos AccID: 3 AccName: shin LSID: 103622 Status: 0")
+	if time.Until(tr.lastPlayerDump) < 4*time.Second {
+		t.Fatalf("who entry wanted to restart the dump window, %s left", time.Until(tr.lastPlayerDump))
+	}
+	tr.processLine("There are 1 players online.")
+	if tr.isPlayerDump {
+		t.Fatalf("players online line wanted to end the dump")
+	}
+	if characterdb.CharactersOnlineCount() != 1 {
+		t.Fatalf("online wanted 1, got %d", characterdb.CharactersOnlineCount())
+	}
+}
+
+func TestTelnet_emptyServer(t *testing.T) {
+	tr := &Telnet{whoDone: make(chan struct{})}
+	for _, line := range []string{
+		"Players on server:",
+		"[1 Enchanter] Shin (Erudite) Zone: qeynos AccID: 3 AccName: shin LSID: 103622 Status: 0",
+		"There is 1 player online.",
+	} {
+		tr.processLine(line)
+	}
+	if tr.isPlayerDump || !characterdb.IsOnline("Shin") {
+		t.Fatalf("1 player online wanted Shin online and the dump ended")
+	}
+
+	if tr.parsePlayersOnline("There are no players online.") || !characterdb.IsOnline("Shin") {
+		t.Fatalf("no players online without a pending who wanted the roster kept")
+	}
+	tr.whoRequested()
+	if tr.parsePlayersOnline("Shin says ooc, 'why are there no players online'") || !characterdb.IsOnline("Shin") {
+		t.Fatalf("chat about no players online wanted the roster kept")
+	}
+	tr.processLine("There are no players online.")
+	if characterdb.IsOnline("Shin") || characterdb.CharactersOnlineCount() != 0 {
+		t.Fatalf("no players online wanted an empty roster, got %d online", characterdb.CharactersOnlineCount())
+	}
+
+	tr.processLine("Players on server:")
+	tr.processLine("0 players online")
+	if tr.isPlayerDump || characterdb.CharactersOnlineCount() != 0 {
+		t.Fatalf("empty who list wanted 0 online")
+	}
+}
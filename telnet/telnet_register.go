@@ -0,0 +1,46 @@
+package telnet
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// registerCodePattern matches messages that look like a registration code
+var registerCodePattern = regexp.MustCompile(`^[A-Za-z0-9]{6}$`)
+
+// parseRegisterCode passes a code said in game to subscribers, so a pending registration for the character can be confirmed.
+// The message is still relayed by routes afterwards, so it returns nothing
+func (t *Telnet) parseRegisterCode(msg string) {
+	if t.registerRegex == nil {
+		return
+	}
+	matches := t.registerRegex.FindStringSubmatch(strings.TrimSpace(strings.ReplaceAll(msg, "\r", "")))
+	if len(matches) == 0 {
+		return
+	}
+	config := &t.config.RegisterCode
+	if config.NameIndex >= len(matches) || config.MessageIndex >= len(matches) {
+		tlog.Warnf("[telnet] register code index greater than matches %d", len(matches))
+		return
+	}
+	code := strings.TrimSpace(matches[config.MessageIndex])
+	if !registerCodePattern.MatchString(code) {
+		return
+	}
+
+	req := request.RegisterCode{
+		Ctx:           context.Background(),
+		CharacterName: matches[config.NameIndex],
+		Code:          strings.ToUpper(code),
+	}
+	for i, s := range t.subscribers {
+		err := s(req)
+		if err != nil {
+			tlog.Warnf("[telnet->api subscriber %d] register code from %s failed: %s", i, req.CharacterName, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package telnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+)
+
+func TestTelnet_parseRegisterCode(t *testing.T) {
+	cfg := config.Telnet{
+		IsEnabled: true,
+		RegisterCode: config.TelnetRegisterCode{
+			IsEnabled:    true,
+			Regex:        `(\w+) says ooc, '(.*)'`,
+			NameIndex:    1,
+			MessageIndex: 2,
+		},
+	}
+	tr, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	var got []request.RegisterCode
+	tr.subscribers = append(tr.subscribers, func(rawReq interface{}) error {
+		got = append(got, rawReq.(request.RegisterCode))
+		return nil
+	})
+
+	tr.parseRegisterCode("Shin says ooc, 'hello there'\r\n")
+	tr.parseRegisterCode("Shin says, 'ab3k9z'\r\n")
+	if len(got) != 0 {
+		t.Fatalf("wanted no codes, got %+v", got)
+	}
+	tr.parseRegisterCode("Shin says ooc, 'ab3k9z'\r\n")
+	if len(got) != 1 {
+		t.Fatalf("wanted 1 code, got %d", len(got))
+	}
+	if got[0].CharacterName != "Shin" || got[0].Code != "AB3K9Z" {
+		t.Fatalf("code = %+v", got[0])
+	}
+}
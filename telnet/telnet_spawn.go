@@ -0,0 +1,73 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// parseSpawn alerts the spawn alert channel when a watched mob spawns, returning true if msg was a watched spawn
+func (t *Telnet) parseSpawn(msg string) bool {
+	if t.spawnRegex == nil {
+		return false
+	}
+	matches := t.spawnRegex.FindStringSubmatch(strings.TrimSpace(strings.ReplaceAll(msg, "\r", "")))
+	if len(matches) == 0 || t.isChatLine(msg) {
+		return false
+	}
+	alert := &t.config.SpawnAlert
+	if alert.MobIndex >= len(matches) || alert.ZoneIndex >= len(matches) {
+		tlog.Warnf("[telnet] spawn_alert index greater than matches %d", len(matches))
+		return false
+	}
+	mob := matches[alert.MobIndex]
+	watched := alert.WatchedMob(mob)
+	if watched == "" {
+		return false
+	}
+	zone := ""
+	if alert.ZoneIndex > 0 {
+		zone = matches[alert.ZoneIndex]
+	}
+
+	key := strings.ToLower(watched)
+	if time.Since(t.lastSpawnAlert[key]) < time.Duration(alert.Cooldown)*time.Second {
+		tlog.Debugf("[telnet] spawn_alert for %s skipped, on cooldown", mob)
+		return true
+	}
+	t.lastSpawnAlert[key] = time.Now()
+
+	buf := new(bytes.Buffer)
+	err := alert.MessagePatternTemplate().Execute(buf, struct {
+		Mob  string
+		Zone string
+	}{
+		mob,
+		zone,
+	})
+	if err != nil {
+		tlog.Warnf("[telnet] spawn_alert execute: %s", err)
+		return true
+	}
+
+	req := request.DiscordSend{
+		Ctx:       context.Background(),
+		ChannelID: alert.ChannelID,
+		Message:   buf.String(),
+		Embed:     &request.DiscordEmbed{Color: alert.Color},
+		RoleID:    alert.RoleID,
+	}
+	for i, s := range t.subscribers {
+		err = s(req)
+		if err != nil {
+			tlog.Warnf("[telnet->discord subscriber %d] spawn_alert channelID %s message %s failed: %s", i, req.ChannelID, req.Message, err)
+			continue
+		}
+		tlog.Infof("[telnet->discord subscriber %d] spawn_alert channelID %s message: %s", i, req.ChannelID, req.Message)
+	}
+	return true
+}
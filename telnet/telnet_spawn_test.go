@@ -0,0 +1,57 @@
+package telnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+)
+
+func TestTelnet_parseSpawn(t *testing.T) {
+	cfg := config.Telnet{
+		IsEnabled: true,
+		SpawnAlert: config.TelnetSpawnAlert{
+			IsEnabled:      true,
+			ChannelID:      "123",
+			RoleID:         "456",
+			Regex:          `^(.+) has spawned in (.+?)[.!]?$`,
+			MobIndex:       1,
+			ZoneIndex:      2,
+			Mobs:           []string{"lord nagafen"},
+			Cooldown:       300,
+			MessagePattern: "{{.Mob}} spawned in {{.Zone}}",
+		},
+	}
+	tr, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	sent := []request.DiscordSend{}
+	tr.subscribers = append(tr.subscribers, func(rawReq interface{}) error {
+		sent = append(sent, rawReq.(request.DiscordSend))
+		return nil
+	})
+
+	if tr.parseSpawn("a rat has spawned in Qeynos Hills") {
+		t.Fatalf("unwatched mob wanted false")
+	}
+	if tr.parseSpawn("Shin says ooc, 'Lord Nagafen has spawned in soldungb'") {
+		t.Fatalf("ooc message quoting a spawn wanted false")
+	}
+	if tr.parseSpawn("a Lord Nagafen impersonator has spawned in soldungb") {
+		t.Fatalf("mob only containing a watched name wanted false")
+	}
+	if !tr.parseSpawn("Lord Nagafen has spawned in Nagafen's Lair!") {
+		t.Fatalf("watched mob wanted true")
+	}
+	if !tr.parseSpawn("Lord Nagafen has spawned in Nagafen's Lair!") {
+		t.Fatalf("watched mob on cooldown wanted true")
+	}
+	if len(sent) != 1 {
+		t.Fatalf("wanted 1 alert due to cooldown, got %d", len(sent))
+	}
+	if sent[0].Message != "Lord Nagafen spawned in Nagafen's Lair" || sent[0].RoleID != "456" {
+		t.Fatalf("unexpected alert %+v", sent[0])
+	}
+}
@@ -0,0 +1,46 @@
+package telnet
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/xackery/talkeq/request"
+	"github.com/xackery/talkeq/tlog"
+)
+
+// parseTell relays an in game tell to subscribers, returning true if a subscriber handled it as a DM.
+// Other tells are left for the routes
+func (t *Telnet) parseTell(msg string) bool {
+	if t.tellRegex == nil {
+		return false
+	}
+	matches := t.tellRegex.FindStringSubmatch(strings.ReplaceAll(msg, "\r", ""))
+	if len(matches) == 0 {
+		return false
+	}
+	if t.config.TellDM.FromIndex >= len(matches) || t.config.TellDM.ToIndex >= len(matches) || t.config.TellDM.MessageIndex >= len(matches) {
+		tlog.Warnf("[telnet] tell_dm index greater than matches %d", len(matches))
+		return false
+	}
+
+	req := request.DiscordTell{
+		Ctx:      context.Background(),
+		FromName: matches[t.config.TellDM.FromIndex],
+		ToName:   matches[t.config.TellDM.ToIndex],
+		Message:  t.convertLinks(matches[t.config.TellDM.MessageIndex]),
+	}
+	isHandled := false
+	for i, s := range t.subscribers {
+		err := s(req)
+		if errors.Is(err, request.ErrNotHandled) {
+			continue
+		}
+		if err != nil {
+			tlog.Debugf("[telnet->discord subscriber %d] tell from %s to %s failed: %s", i, req.FromName, req.ToName, err)
+			continue
+		}
+		isHandled = true
+	}
+	return isHandled
+}
@@ -0,0 +1,61 @@
+package telnet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xackery/talkeq/config"
+	"github.com/xackery/talkeq/request"
+)
+
+func TestTelnet_parseTell(t *testing.T) {
+	cfg := config.Telnet{
+		IsEnabled: true,
+		Routes: []config.Route{{
+			IsEnabled:      true,
+			Trigger:        config.Trigger{Regex: `(\w+) tells (\w+), '(.*)'`, NameIndex: 1, MessageIndex: 3},
+			Target:         "discord",
+			ChannelID:      "456",
+			MessagePattern: "{{.Name}}: {{.Message}}",
+		}},
+		TellDM: config.TelnetTellDM{
+			IsEnabled:    true,
+			Regex:        `(\w+) tells (\w+), '(.*)'`,
+			FromIndex:    1,
+			ToIndex:      2,
+			MessageIndex: 3,
+		},
+	}
+	for i := range cfg.Routes {
+		err := cfg.Routes[i].LoadMessagePattern()
+		if err != nil {
+			t.Fatalf("load route %d: %s", i, err)
+		}
+	}
+	tr, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new: %s", err)
+	}
+	sent := []request.DiscordSend{}
+	tr.subscribers = append(tr.subscribers, func(rawReq interface{}) error {
+		switch req := rawReq.(type) {
+		case request.DiscordTell:
+			if req.ToName != "Shin" {
+				return request.ErrNotHandled
+			}
+		case request.DiscordSend:
+			sent = append(sent, req)
+		}
+		return nil
+	})
+
+	if tr.processLine("Xackery tells Shin, 'hello'") {
+		t.Fatalf("tell sent as a DM wanted not routed")
+	}
+	if !tr.processLine("Xackery tells Bob, 'hello'") {
+		t.Fatalf("tell to an unregistered character wanted routed")
+	}
+	if len(sent) != 1 || sent[0].ChannelID != "456" {
+		t.Fatalf("wanted tell to Bob routed to channel 456, got %+v", sent)
+	}
+}
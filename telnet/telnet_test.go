@@ -99,3 +99,21 @@ func TestTelnet_Connect(t *testing.T) {
 		})
 	}
 }
+
+func Test_isLoginFailure(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"Login failed.\r\n", true},
+		{"Access denied\r\n", true},
+		{"Username:", true},
+		{"Login accepted.\r\n", false},
+		{"Password:", false},
+	}
+	for _, tt := range tests {
+		if got := isLoginFailure(tt.line); got != tt.want {
+			t.Errorf("isLoginFailure(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/xackery/talkeq/config"
+)
+
+// testRoute runs line through every enabled telnet and eqlog route, and prints which routes match and what they would send
+func testRoute(path string, line string) error {
+	cfg, err := config.Check(path)
+	if err != nil {
+		return fmt.Errorf("check (run with -validate for a full report): %w", err)
+	}
+
+	matchCount := 0
+	sources := []struct {
+		name      string
+		isEnabled bool
+		routes    []config.Route
+	}{
+		{"telnet", cfg.Telnet.IsEnabled, cfg.Telnet.Routes},
+		{"eqlog", cfg.EQLog.IsEnabled, cfg.EQLog.Routes},
+	}
+	for _, source := range sources {
+		if !source.isEnabled {
+			continue
+		}
+		for routeIndex, route := range source.routes {
+			if !route.IsEnabled || route.Trigger.Custom != "" {
+				continue
+			}
+			pattern, err := route.TriggerRegex()
+			if err != nil {
+				fmt.Printf("[%s] route %d: compile failed: %s\n", source.name, routeIndex, err)
+				continue
+			}
+			matches := pattern.FindStringSubmatch(line)
+			if len(matches) == 0 {
+				continue
+			}
+			matchCount++
+
+			fmt.Printf("[%s] route %d matched pattern %s\n", source.name, routeIndex, route.Trigger.Regex)
+			for i, match := range matches {
+				fmt.Printf("  group %d: %q\n", i, match)
+			}
+
+			name := ""
+			message := ""
+			if route.Trigger.NameIndex < len(matches) {
+				name = matches[route.Trigger.NameIndex]
+			}
+			if route.Trigger.MessageIndex < len(matches) {
+				message = matches[route.Trigger.MessageIndex]
+			}
+			fmt.Printf("  name (index %d): %q\n", route.Trigger.NameIndex, name)
+			fmt.Printf("  message (index %d): %q\n", route.Trigger.MessageIndex, message)
+			if !route.IsMatch(message) {
+				fmt.Printf("  skipped, message did not pass the route's conditions\n")
+				continue
+			}
+			if route.Trigger.GuildIndex > 0 && route.Trigger.GuildIndex < len(matches) {
+				fmt.Printf("  guild (index %d): %q\n", route.Trigger.GuildIndex, matches[route.Trigger.GuildIndex])
+			}
+
+			buf := new(bytes.Buffer)
+			err = route.MessagePatternTemplate().Execute(buf, struct {
+				Name    string
+				Message string
+			}{
+				name,
+				message,
+			})
+			if err != nil {
+				fmt.Printf("  execute failed: %s\n", err)
+				continue
+			}
+			for _, channelID := range route.Destinations() {
+				fmt.Printf("  %s channel %s: %s\n", route.Target, channelID, buf.String())
+			}
+		}
+	}
+	if matchCount == 0 {
+		fmt.Println("no enabled routes matched")
+	}
+	return nil
+}
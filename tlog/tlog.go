@@ -5,6 +5,8 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"strings"
+	"sync"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -16,6 +18,14 @@ var (
 	Sugar *zap.SugaredLogger
 	// SugarFile represents a zap logger file
 	SugarFile *zap.SugaredLogger
+	// level is the lowest level any component logs at, and is what the zap cores filter on
+	level = zap.NewAtomicLevelAt(zap.DebugLevel)
+
+	levelMu sync.RWMutex
+	// globalLevel is the minimum level logged, changed via SetLevel
+	globalLevel = zap.DebugLevel
+	// componentLevels overrides globalLevel for messages prefixed with [component], changed via SetComponentLevels
+	componentLevels = map[string]zapcore.Level{}
 )
 
 // Init creates and initializes the logging
@@ -36,7 +46,6 @@ func Init(fileWriter io.Writer, consoleWriter io.Writer) {
 	consoleConfig.EncoderConfig.TimeKey = ""
 	consoleEncoder := zapcore.NewConsoleEncoder(consoleConfig.EncoderConfig)
 
-	level := zap.DebugLevel
 	if consoleWriter == nil {
 		consoleWriter = os.Stdout
 	}
@@ -62,9 +71,93 @@ func Init(fileWriter io.Writer, consoleWriter io.Writer) {
 	}
 }
 
+// SetLevel sets the minimum level logged. Valid levels are trace, debug, info, warn and error. trace is logged as debug
+func SetLevel(name string) error {
+	lvl, err := parseLevel(name)
+	if err != nil {
+		return err
+	}
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	globalLevel = lvl
+	refreshLevel()
+	return nil
+}
+
+// SetComponentLevels overrides the level of messages prefixed with [component], e.g. telnet = debug
+func SetComponentLevels(levels map[string]string) error {
+	newLevels := map[string]zapcore.Level{}
+	for component, name := range levels {
+		lvl, err := parseLevel(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", component, err)
+		}
+		newLevels[strings.ToLower(component)] = lvl
+	}
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	componentLevels = newLevels
+	refreshLevel()
+	return nil
+}
+
+// refreshLevel lowers the core level to the lowest level in use. Must be called while holding levelMu
+func refreshLevel() {
+	lowest := globalLevel
+	for _, lvl := range componentLevels {
+		if lvl < lowest {
+			lowest = lvl
+		}
+	}
+	level.SetLevel(lowest)
+}
+
+func parseLevel(name string) (zapcore.Level, error) {
+	switch strings.ToLower(name) {
+	case "trace", "debug":
+		return zap.DebugLevel, nil
+	case "info":
+		return zap.InfoLevel, nil
+	case "warn":
+		return zap.WarnLevel, nil
+	case "error":
+		return zap.ErrorLevel, nil
+	}
+	return zap.DebugLevel, fmt.Errorf("unknown log level %s", name)
+}
+
+// isEnabled returns true if msg should be logged at lvl, based on its [component] prefix
+func isEnabled(lvl zapcore.Level, msg string) bool {
+	levelMu.RLock()
+	defer levelMu.RUnlock()
+	if len(componentLevels) == 0 {
+		return lvl >= globalLevel
+	}
+	componentLevel, ok := componentLevels[component(msg)]
+	if !ok {
+		componentLevel = globalLevel
+	}
+	return lvl >= componentLevel
+}
+
+// component returns the component of a message prefixed like [telnet] or [telnet->discord subscriber 1]
+func component(msg string) string {
+	if !strings.HasPrefix(msg, "[") {
+		return ""
+	}
+	end := strings.IndexAny(msg[1:], "] ->")
+	if end < 0 {
+		return ""
+	}
+	return strings.ToLower(msg[1 : end+1])
+}
+
 // Debug uses fmt.Sprint to construct and log a message.
 func Debug(args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.DebugLevel, fmt.Sprint(args...)) {
+		return
+	}
 	Sugar.Debug(args)
 	if SugarFile != nil {
 		SugarFile.Debug(args)
@@ -74,6 +167,9 @@ func Debug(args ...interface{}) {
 // Info uses fmt.Sprint to construct and log a message.
 func Info(args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.InfoLevel, fmt.Sprint(args...)) {
+		return
+	}
 	Sugar.Info(args)
 	if SugarFile != nil {
 		SugarFile.Info(args)
@@ -83,6 +179,9 @@ func Info(args ...interface{}) {
 // Warn uses fmt.Sprint to construct and log a message.
 func Warn(args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.WarnLevel, fmt.Sprint(args...)) {
+		return
+	}
 	Sugar.Warn(args)
 	if SugarFile != nil {
 		SugarFile.Warn(args)
@@ -92,6 +191,9 @@ func Warn(args ...interface{}) {
 // Error uses fmt.Sprint to construct and log a message.
 func Error(args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.ErrorLevel, fmt.Sprint(args...)) {
+		return
+	}
 	Sugar.Error(args)
 	if SugarFile != nil {
 		SugarFile.Error(args)
@@ -129,6 +231,9 @@ func Fatal(args ...interface{}) {
 // Debugf uses fmt.Sprintf to log a templated message.
 func Debugf(template string, args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.DebugLevel, template) {
+		return
+	}
 	Sugar.Debugf(template, args...)
 	if SugarFile != nil {
 		SugarFile.Debugf(template, args...)
@@ -138,6 +243,9 @@ func Debugf(template string, args ...interface{}) {
 // Infof uses fmt.Sprintf to log a templated message.
 func Infof(template string, args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.InfoLevel, template) {
+		return
+	}
 	Sugar.Infof(template, args...)
 	if SugarFile != nil {
 		SugarFile.Infof(template, args...)
@@ -147,6 +255,9 @@ func Infof(template string, args ...interface{}) {
 // Warnf uses fmt.Sprintf to log a templated message.
 func Warnf(template string, args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.WarnLevel, template) {
+		return
+	}
 	Sugar.Warnf(template, args...)
 	if SugarFile != nil {
 		SugarFile.Warnf(template, args...)
@@ -156,6 +267,9 @@ func Warnf(template string, args ...interface{}) {
 // Errorf uses fmt.Sprintf to log a templated message.
 func Errorf(template string, args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.ErrorLevel, template) {
+		return
+	}
 	Sugar.Errorf(template, args...)
 	if SugarFile != nil {
 		SugarFile.Errorf(template, args...)
@@ -198,6 +312,9 @@ func Fatalf(template string, args ...interface{}) {
 //	s.With(keysAndValues).Debug(msg)
 func Debugw(msg string, keysAndValues ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.DebugLevel, msg) {
+		return
+	}
 	Sugar.Debugw(msg, keysAndValues)
 	if SugarFile != nil {
 		SugarFile.Debugw(msg, keysAndValues)
@@ -208,6 +325,9 @@ func Debugw(msg string, keysAndValues ...interface{}) {
 // pairs are treated as they are in With.
 func Infow(msg string, keysAndValues ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.InfoLevel, msg) {
+		return
+	}
 	Sugar.Infow(msg, keysAndValues)
 	if SugarFile != nil {
 		SugarFile.Infow(msg, keysAndValues)
@@ -218,6 +338,9 @@ func Infow(msg string, keysAndValues ...interface{}) {
 // pairs are treated as they are in With.
 func Warnw(msg string, keysAndValues ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.WarnLevel, msg) {
+		return
+	}
 	Sugar.Warnw(msg, keysAndValues)
 	if SugarFile != nil {
 		SugarFile.Warnw(msg, keysAndValues)
@@ -228,6 +351,9 @@ func Warnw(msg string, keysAndValues ...interface{}) {
 // pairs are treated as they are in With.
 func Errorw(msg string, keysAndValues ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.ErrorLevel, msg) {
+		return
+	}
 	Sugar.Errorw(msg, keysAndValues)
 	if SugarFile != nil {
 		SugarFile.Errorw(msg, keysAndValues)
@@ -268,6 +394,9 @@ func Fatalw(msg string, keysAndValues ...interface{}) {
 // Debugln uses fmt.Sprintln to construct and log a message.
 func Debugln(args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.DebugLevel, fmt.Sprint(args...)) {
+		return
+	}
 	Sugar.Debugln(args)
 	if SugarFile != nil {
 		SugarFile.Debugln(args)
@@ -277,6 +406,9 @@ func Debugln(args ...interface{}) {
 // Infoln uses fmt.Sprintln to construct and log a message.
 func Infoln(args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.InfoLevel, fmt.Sprint(args...)) {
+		return
+	}
 	Sugar.Infoln(args)
 	if SugarFile != nil {
 		SugarFile.Infoln(args)
@@ -286,6 +418,9 @@ func Infoln(args ...interface{}) {
 // Warnln uses fmt.Sprintln to construct and log a message.
 func Warnln(args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.WarnLevel, fmt.Sprint(args...)) {
+		return
+	}
 	Sugar.Warnln(args)
 	if SugarFile != nil {
 		SugarFile.Warnln(args)
@@ -295,6 +430,9 @@ func Warnln(args ...interface{}) {
 // Errorln uses fmt.Sprintln to construct and log a message.
 func Errorln(args ...interface{}) {
 	Init(nil, nil)
+	if !isEnabled(zap.ErrorLevel, fmt.Sprint(args...)) {
+		return
+	}
 	Sugar.Errorln(args)
 	if SugarFile != nil {
 		SugarFile.Errorln(args)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -22,8 +23,9 @@ var (
 
 // UserEntry represents a record in the database
 type UserEntry struct {
-	CharacterName string
-	DiscordID     string
+	CharacterName   string
+	DiscordID       string
+	IsTellDMEnabled bool
 }
 
 // New initializes and creates the user database
@@ -138,20 +140,21 @@ func reload() error {
 			if line == "" || strings.HasPrefix(line, "#") {
 				continue
 			}
+			if strings.Contains(line, "#") {
+				line = strings.TrimSpace(line[:strings.Index(line, "#")])
+			}
 			parts := strings.Split(line, ":")
-			if len(parts) != 2 {
+			if len(parts) != 2 && len(parts) != 3 {
 				continue
 			}
 
 			discordID := strings.TrimSpace(parts[0])
 			characterName := strings.TrimSpace(parts[1])
-			if strings.Contains(characterName, "#") {
-				characterName = strings.TrimSpace(characterName[:strings.Index(characterName, "#")])
-			}
 
 			ue[discordID] = UserEntry{
-				DiscordID:     discordID,
-				CharacterName: characterName,
+				DiscordID:       discordID,
+				CharacterName:   characterName,
+				IsTellDMEnabled: len(parts) == 3 && strings.TrimSpace(parts[2]) == "tells",
 			}
 		}
 	}
@@ -163,6 +166,7 @@ func reload() error {
 // Set updates or adds an entry for a specified user id
 func Set(discordID string, characterName string) {
 	mu.Lock()
+	defer mu.Unlock()
 
 	ue, ok := users[discordID]
 	if ok {
@@ -194,7 +198,47 @@ func Name(discordID string) string {
 	return name
 }
 
+// DiscordID returns the discord ID of a user based on their character name
+func DiscordID(characterName string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, ue := range users {
+		if strings.EqualFold(ue.CharacterName, characterName) {
+			return ue.DiscordID
+		}
+	}
+	return ""
+}
+
+// IsTellDMEnabled returns true if a user opted in to receiving in game tells as discord DMs
+func IsTellDMEnabled(discordID string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return users[discordID].IsTellDMEnabled
+}
+
+// SetTellDM opts a user in or out of receiving in game tells as discord DMs
+func SetTellDM(discordID string, isEnabled bool) error {
+	mu.Lock()
+	defer mu.Unlock()
+	ue, ok := users[discordID]
+	if !ok {
+		return fmt.Errorf("user %s not found", discordID)
+	}
+	ue.IsTellDMEnabled = isEnabled
+	users[discordID] = ue
+	err := save()
+	if err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+	return nil
+}
+
+// save writes users to the database. Must be called while holding mu
 func save() error {
+	if filepath.Ext(usersDatabasePath) != ".toml" {
+		return saveTxt()
+	}
 	f, err := os.Create(usersDatabasePath)
 	if err != nil {
 		return fmt.Errorf("create: %w", err)
@@ -207,3 +251,67 @@ func save() error {
 	}
 	return nil
 }
+
+// saveTxt edits the txt database in place, so lines the operator wrote, comments included, are kept.
+// Users not in the file yet are appended. Must be called while holding mu
+func saveTxt() error {
+	data, err := os.ReadFile(usersDatabasePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read: %w", err)
+	}
+	lines := []string{}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		lines = strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	} else {
+		lines = append(lines, "#userid:username(:tells to receive in game tells as DMs)")
+	}
+
+	isWritten := make(map[string]bool)
+	for i, line := range lines {
+		entry := strings.TrimSpace(line)
+		comment := ""
+		if strings.Contains(entry, "#") {
+			comment = entry[strings.Index(entry, "#"):]
+			entry = strings.TrimSpace(entry[:strings.Index(entry, "#")])
+		}
+		parts := strings.Split(entry, ":")
+		if entry == "" || (len(parts) != 2 && len(parts) != 3) {
+			continue
+		}
+		ue, ok := users[strings.TrimSpace(parts[0])]
+		if !ok {
+			continue
+		}
+		isWritten[ue.DiscordID] = true
+		lines[i] = userLine(ue)
+		if comment != "" {
+			lines[i] += " " + comment
+		}
+	}
+
+	discordIDs := []string{}
+	for discordID := range users {
+		if !isWritten[discordID] {
+			discordIDs = append(discordIDs, discordID)
+		}
+	}
+	sort.Strings(discordIDs)
+	for _, discordID := range discordIDs {
+		lines = append(lines, userLine(users[discordID]))
+	}
+
+	err = os.WriteFile(usersDatabasePath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	return nil
+}
+
+// userLine returns the txt database line of a user
+func userLine(ue UserEntry) string {
+	line := fmt.Sprintf("%s:%s", ue.DiscordID, ue.CharacterName)
+	if ue.IsTellDMEnabled {
+		line += ":tells"
+	}
+	return line
+}
@@ -2,6 +2,8 @@ package userdb
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -27,3 +29,44 @@ func Test_reload(t *testing.T) {
 		})
 	}
 }
+
+func TestIsTellDMEnabled(t *testing.T) {
+	usersDatabasePath = "test/user_test.txt"
+	err := reload()
+	if err != nil {
+		t.Fatalf("reload: %s", err)
+	}
+	if DiscordID("shin") != "12345" {
+		t.Fatalf("DiscordID(shin) wanted 12345, got %s", DiscordID("shin"))
+	}
+	if !IsTellDMEnabled("12345") {
+		t.Fatalf("IsTellDMEnabled(12345) wanted true")
+	}
+	if IsTellDMEnabled("87784167131066368") {
+		t.Fatalf("IsTellDMEnabled(87784167131066368) wanted false")
+	}
+}
+
+func TestSetTellDM_keepsComments(t *testing.T) {
+	usersDatabasePath = filepath.Join(t.TempDir(), "users.txt")
+	err := os.WriteFile(usersDatabasePath, []byte("#userid:username\n# officers\n87784167131066368:Xackery #aka Xackery#3764\n12345:Shin\n"), 0644)
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+	err = reload()
+	if err != nil {
+		t.Fatalf("reload: %s", err)
+	}
+	err = SetTellDM("87784167131066368", true)
+	if err != nil {
+		t.Fatalf("SetTellDM: %s", err)
+	}
+	data, err := os.ReadFile(usersDatabasePath)
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+	want := "#userid:username\n# officers\n87784167131066368:Xackery:tells #aka Xackery#3764\n12345:Shin\n"
+	if string(data) != want {
+		t.Fatalf("users database = %q, want %q", string(data), want)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/xackery/talkeq/config"
+)
+
+// validateSections is the order sections are reported in
+var validateSections = []string{"talkeq", "api", "discord", "telnet", "eqlog", "peq_editor", "sql_report", "auction_parsing", "auction_digest"}
+
+// reportSections returns validateSections, followed by any other section problems were found in, so none are hidden
+func reportSections(problems config.ValidationErrors) []string {
+	sections := append([]string{}, validateSections...)
+	seen := map[string]bool{}
+	for _, section := range sections {
+		seen[section] = true
+	}
+	for _, problem := range problems {
+		if seen[problem.Section] {
+			continue
+		}
+		seen[problem.Section] = true
+		sections = append(sections, problem.Section)
+	}
+	return sections
+}
+
+// validateConfig loads the config at path without starting any services, and prints a report per section
+func validateConfig(path string, isConnectCheck bool) error {
+	cfg, err := config.Check(path)
+	if err != nil {
+		problems := config.ValidationErrors{}
+		if !errors.As(err, &problems) {
+			fmt.Printf("%s: FAIL\n  %s\n", path, err)
+			return fmt.Errorf("check: %w", err)
+		}
+		for _, section := range reportSections(problems) {
+			sectionProblems := []string{}
+			for _, problem := range problems {
+				if problem.Section == section {
+					sectionProblems = append(sectionProblems, problem.Message)
+				}
+			}
+			if len(sectionProblems) == 0 {
+				fmt.Printf("[%s] ok\n", section)
+				continue
+			}
+			fmt.Printf("[%s] %d problems\n", section, len(sectionProblems))
+			for _, problem := range sectionProblems {
+				fmt.Printf("  %s\n", problem)
+			}
+		}
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+
+	for _, section := range validateSections {
+		fmt.Printf("[%s] ok\n", section)
+	}
+
+	if !isConnectCheck {
+		return nil
+	}
+
+	hosts := map[string]string{}
+	if cfg.Telnet.IsEnabled {
+		hosts["telnet"] = cfg.Telnet.Host
+	}
+	if cfg.SQLReport.IsEnabled {
+		hosts["sql_report"] = cfg.SQLReport.Host
+	}
+	failCount := 0
+	for _, section := range validateSections {
+		host, ok := hosts[section]
+		if !ok {
+			continue
+		}
+		conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+		if err != nil {
+			fmt.Printf("[%s] connect to %s failed: %s\n", section, host, err)
+			failCount++
+			continue
+		}
+		conn.Close()
+		fmt.Printf("[%s] connect to %s ok\n", section, host)
+	}
+	if failCount > 0 {
+		return fmt.Errorf("%d connections failed", failCount)
+	}
+	return nil
+}